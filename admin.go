@@ -0,0 +1,283 @@
+package thorn
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/logger"
+	"github.com/andyzhou/thorn/pb"
+	"github.com/xtaci/kcp-go"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+/*
+ * admin api face
+ * - http json api for server management
+ * - auth by `Authorization: Bearer <key>` if auth keys configured
+ * - `/rooms` control api, GET list or one room by `id`, POST create, DELETE stop
+ * - `/metrics` prometheus text format, enabled by file conf
+ * - player rtt and jitter per room, kcp retransmit, loss and fec counters of all sessions
+ */
+
+//room info for admin api
+type adminRoomInfo struct {
+	RoomId  uint64                           `json:"roomId"`
+	IsOver  bool                             `json:"isOver"`
+	Lags    map[uint64]uint32                `json:"lags,omitempty"`    //playerId -> frames not confirmed
+	Inputs  map[uint64]*pb.S2C_InputStatsMsg `json:"inputs,omitempty"`  //playerId -> input lateness stats
+	Quality []*pb.PlayerQuality              `json:"quality,omitempty"` //connect quality of players
+}
+
+//kcp quality of all sessions, kcp-go keeps counters per process only
+type adminKcpQuality struct {
+	Loss         float64 `json:"loss"` //lost segments / sent segments
+	RetransSegs  uint64  `json:"retransSegs"`
+	LostSegs     uint64  `json:"lostSegs"`
+	FECRecovered uint64  `json:"fecRecovered"`
+	FECErrs      uint64  `json:"fecErrs"`
+}
+
+//start admin api
+func (f *Server) startAdmin() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", f.adminAuth(f.adminStatus))
+	mux.HandleFunc("/reload", f.adminAuth(f.adminReload))
+	mux.HandleFunc("/rooms", f.adminAuth(f.adminRooms))
+	mux.HandleFunc("/metrics", f.adminAuth(f.adminMetrics))
+	f.admin = &http.Server{
+		Addr:f.conf.AdminAddr,
+		Handler:mux,
+	}
+
+	//spawn process
+	go func() {
+		err := f.admin.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			logger.Errorf("Server:startAdmin failed, addr:%v, err:%v\n", f.conf.AdminAddr, err)
+		}
+	}()
+}
+
+//stop admin api
+func (f *Server) stopAdmin() {
+	if f.admin != nil {
+		f.admin.Close()
+		f.admin = nil
+	}
+}
+
+//////////////
+//private func
+//////////////
+
+//get kcp quality of all sessions
+func getKcpQuality() *adminKcpQuality {
+	snmp := kcp.DefaultSnmp.Copy()
+	quality := &adminKcpQuality{
+		RetransSegs:snmp.RetransSegs,
+		LostSegs:snmp.LostSegs,
+		FECRecovered:snmp.FECRecovered,
+		FECErrs:snmp.FECErrs,
+	}
+	if snmp.OutSegs > 0 {
+		quality.Loss = float64(snmp.LostSegs) / float64(snmp.OutSegs)
+	}
+	return quality
+}
+
+//server status
+func (f *Server) adminStatus(w http.ResponseWriter, r *http.Request) {
+	status := map[string]interface{}{
+		"reload":f.GetReloadStatus(),
+		"cpuTime":getCpuTime(),
+		"cpuNum":runtime.NumCPU(),
+		"goroutines":runtime.NumGoroutine(),
+	}
+	if f.kcp != nil {
+		status["rooms"] = f.kcp.GetManager().GetRooms()
+	}
+	status["kcp"] = getKcpQuality()
+	f.adminWrite(w, http.StatusOK, status)
+}
+
+//get reload status or do reload
+func (f *Server) adminReload(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		f.adminWrite(w, http.StatusOK, f.GetReloadStatus())
+	case http.MethodPost:
+		code := http.StatusOK
+		if err := f.Reload(); err != nil {
+			code = http.StatusBadRequest
+		}
+		f.adminWrite(w, code, f.GetReloadStatus())
+	default:
+		f.adminWrite(w, http.StatusMethodNotAllowed, nil)
+	}
+}
+
+//room control
+func (f *Server) adminRooms(w http.ResponseWriter, r *http.Request) {
+	if f.kcp == nil {
+		f.adminWrite(w, http.StatusServiceUnavailable, nil)
+		return
+	}
+	manager := f.kcp.GetManager()
+	switch r.Method {
+	case http.MethodGet:
+		idStr := r.URL.Query().Get("id")
+		if idStr == "" {
+			//list all rooms
+			rooms := make([]adminRoomInfo, 0)
+			for _, id := range manager.GetRoomIds() {
+				if room := manager.GetRoom(id); room != nil {
+					rooms = append(rooms, adminRoomInfo{RoomId:id, IsOver:room.IsOver()})
+				}
+			}
+			f.adminWrite(w, http.StatusOK, rooms)
+			return
+		}
+		id, _ := strconv.ParseUint(idStr, 10, 64)
+		room := f.GetRoom(id)
+		if room == nil {
+			f.adminWrite(w, http.StatusNotFound, nil)
+			return
+		}
+		f.adminWrite(w, http.StatusOK, adminRoomInfo{
+			RoomId:id,
+			IsOver:room.IsOver(),
+			Lags:room.GetPlayerLags(),
+			Inputs:room.GetInputStats(),
+			Quality:room.GetQuality(),
+		})
+	case http.MethodPost:
+		cfg := &conf.RoomConf{}
+		if err := json.NewDecoder(r.Body).Decode(cfg); err != nil {
+			f.adminWrite(w, http.StatusBadRequest, map[string]string{"error":err.Error()})
+			return
+		}
+		room, err := f.CreateRoom(cfg)
+		if err != nil {
+			f.adminWrite(w, http.StatusBadRequest, map[string]string{"error":err.Error()})
+			return
+		}
+		f.adminWrite(w, http.StatusOK, adminRoomInfo{RoomId:room.GetId(), IsOver:room.IsOver()})
+	case http.MethodDelete:
+		id, _ := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+		room := f.GetRoom(id)
+		if room == nil {
+			f.adminWrite(w, http.StatusNotFound, nil)
+			return
+		}
+		room.Stop()
+		manager.CloseRoom(id)
+		f.adminWrite(w, http.StatusOK, adminRoomInfo{RoomId:id, IsOver:true})
+	default:
+		f.adminWrite(w, http.StatusMethodNotAllowed, nil)
+	}
+}
+
+//metrics, prometheus text format
+func (f *Server) adminMetrics(w http.ResponseWriter, r *http.Request) {
+	fileConf := f.getFileConf()
+	if fileConf == nil || !fileConf.Metrics {
+		f.adminWrite(w, http.StatusNotFound, nil)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if f.kcp != nil {
+		fmt.Fprintf(w, "thorn_rooms %d\n", f.kcp.GetManager().GetRooms())
+		f.writePlayerMetrics(w)
+	}
+	fmt.Fprintf(w, "thorn_cpu_seconds %f\n", getCpuTime())
+	fmt.Fprintf(w, "thorn_goroutines %d\n", runtime.NumGoroutine())
+	reload := f.GetReloadStatus()
+	fmt.Fprintf(w, "thorn_reload_count %d\n", reload.Count)
+
+	//kcp snmp counters
+	fmt.Fprintf(w, "thorn_kcp_loss_ratio %f\n", getKcpQuality().Loss)
+	snmp := kcp.DefaultSnmp.Copy()
+	names := snmp.Header()
+	values := snmp.ToSlice()
+	for i, name := range names {
+		if i < len(values) {
+			fmt.Fprintf(w, "thorn_kcp_%s %s\n", strings.ToLower(name), values[i])
+		}
+	}
+}
+
+//player rtt and jitter metrics of all rooms
+func (f *Server) writePlayerMetrics(w http.ResponseWriter) {
+	var (
+		count     int64
+		rttSum    int64
+		rttMax    int64
+		jitterSum int64
+		lossSum   float32
+		lossMax   float32
+	)
+	manager := f.kcp.GetManager()
+	for _, id := range manager.GetRoomIds() {
+		room := manager.GetRoom(id)
+		if room == nil {
+			continue
+		}
+		for _, quality := range room.GetQuality() {
+			if !quality.GetOnline() || quality.GetRtt() <= 0 {
+				continue
+			}
+			count++
+			rttSum += quality.GetRtt()
+			jitterSum += quality.GetJitter()
+			if quality.GetRtt() > rttMax {
+				rttMax = quality.GetRtt()
+			}
+			lossSum += quality.GetLoss()
+			if quality.GetLoss() > lossMax {
+				lossMax = quality.GetLoss()
+			}
+		}
+	}
+	if count <= 0 {
+		count = 1
+	}
+	fmt.Fprintf(w, "thorn_player_rtt_ms_avg %d\n", rttSum / count)
+	fmt.Fprintf(w, "thorn_player_rtt_ms_max %d\n", rttMax)
+	fmt.Fprintf(w, "thorn_player_jitter_ms_avg %d\n", jitterSum / count)
+	fmt.Fprintf(w, "thorn_player_loss_percent_avg %f\n", lossSum / float32(count))
+	fmt.Fprintf(w, "thorn_player_loss_percent_max %f\n", lossMax)
+}
+
+//check auth key
+func (f *Server) adminAuth(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fileConf := f.getFileConf()
+		if fileConf != nil && len(fileConf.AuthKeys) > 0 {
+			key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			isOk := false
+			for _, v := range fileConf.AuthKeys {
+				if v != "" && v == key {
+					isOk = true
+					break
+				}
+			}
+			if !isOk {
+				f.adminWrite(w, http.StatusUnauthorized, nil)
+				return
+			}
+		}
+		handler(w, r)
+	}
+}
+
+//write json response
+func (f *Server) adminWrite(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if data != nil {
+		json.NewEncoder(w).Encode(data)
+	}
+}
@@ -1,11 +1,12 @@
 package thorn
 
 import (
-	"crypto/sha1"
 	"errors"
 	"fmt"
+	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/define"
+	"github.com/andyzhou/thorn/network"
 	"github.com/xtaci/kcp-go"
-	"golang.org/x/crypto/pbkdf2"
 	"log"
 	"runtime/debug"
 	"sync"
@@ -38,6 +39,7 @@ type Client struct {
 	salt string
 	readBuffSize int
 	block *kcp.BlockCrypt
+	impair *conf.ImpairConf //network impairment for test, option
 	cbForRead func(*kcp.UDPSession, []byte) bool
 	clients map[string]*clientInfo //tag -> clientInfo
 	sync.RWMutex
@@ -140,7 +142,15 @@ func (c *Client) DialServer(tag string) error {
 	}
 
 	//dial server
-	session, err := kcp.DialWithOptions(c.address, *c.block, 10, 3)
+	var (
+		session *kcp.UDPSession
+		err error
+	)
+	if c.impair != nil {
+		session, err = network.DialWithImpair(c.address, *c.block, c.impair)
+	}else{
+		session, err = kcp.DialWithOptions(c.address, *c.block, 10, 3)
+	}
 	if err != nil {
 		return err
 	}
@@ -165,6 +175,12 @@ func (c *Client) SetCBForRead(
 
 //set security, step-1
 func (c *Client) SetSecurity(password, salt string) error {
+	return c.SetSecurityWithCrypt(password, salt, define.CryptAES)
+}
+
+//set security with block crypt, step-1
+//crypt should be same as server, see define.CryptXXX
+func (c *Client) SetSecurityWithCrypt(password, salt, crypt string) error {
 	//check
 	if password == "" || salt == "" {
 		return errors.New("invalid parameter")
@@ -175,14 +191,7 @@ func (c *Client) SetSecurity(password, salt string) error {
 	c.salt = salt
 
 	//init kcp block
-	key := pbkdf2.Key(
-					[]byte(c.password),
-					[]byte(c.salt),
-					1024,
-					32,
-					sha1.New,
-				)
-	block, err := kcp.NewAESBlockCrypt(key)
+	block, err := network.NewBlockCrypt(crypt, c.password, c.salt)
 	if err != nil {
 		return err
 	}
@@ -192,6 +201,12 @@ func (c *Client) SetSecurity(password, salt string) error {
 	return nil
 }
 
+//set network impairment for new dialed session, option
+//for test bad network, nil means disabled.
+func (c *Client) SetImpair(cfg *conf.ImpairConf) {
+	c.impair = cfg
+}
+
 //set read buff size, option
 func (c *Client) SetReadBuffSize(size int) bool {
 	if size <= 0 {
@@ -265,8 +280,6 @@ func (c *Client) clientWriteProcess(client *clientInfo) bool {
 			return false
 		}
 	}
-
-	return true
 }
 
 //process for client reader
@@ -300,7 +313,7 @@ func (c *Client) clientReadProcess(client *clientInfo) bool {
 		default:
 			{
 				//try read origin data
-				_, err := client.session.Read(readBuff)
+				n, err := client.session.Read(readBuff)
 				if err != nil {
 					log.Println("Client:clientReadProcess failed, err:", err.Error())
 					return false
@@ -308,7 +321,7 @@ func (c *Client) clientReadProcess(client *clientInfo) bool {
 
 				//call cb
 				if c.cbForRead != nil {
-					c.cbForRead(client.session, readBuff)
+					c.cbForRead(client.session, readBuff[:n])
 				}
 			}
 		}
@@ -0,0 +1,203 @@
+package clock
+
+import (
+	"github.com/andyzhou/thorn/iface"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+ * fake clock face, implement of IClock
+ * - time moves only by Advance or Set, for test
+ * - timers and tickers fire in due order inside Advance
+ * - timer func called in Advance goroutine, ticker send drops if chan full
+ * - Reset re-arms timer from now, even if fired or stopped
+ */
+
+//face info
+type FakeClock struct {
+	now     time.Time
+	seq     uint64
+	waiters []*fakeWaiter //pending timers and tickers
+	sync.Mutex
+}
+
+//timer or ticker of fake clock
+type fakeWaiter struct {
+	clock  *FakeClock
+	seq    uint64 //create order, for same due time
+	due    time.Time
+	period time.Duration  //0 for timer
+	cb     func()         //timer func
+	ch     chan time.Time //ticker chan
+}
+
+//ticker of fake clock
+type fakeTicker struct {
+	*fakeWaiter
+}
+
+//construct
+func NewFakeClock(start time.Time) *FakeClock {
+	//self init
+	this := &FakeClock{
+		now:start,
+		waiters:make([]*fakeWaiter, 0),
+	}
+	return this
+}
+
+//get current time
+func (f *FakeClock) Now() time.Time {
+	f.Lock()
+	defer f.Unlock()
+	return f.now
+}
+
+//new ticker
+func (f *FakeClock) NewTicker(d time.Duration) iface.ITicker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	w := &fakeWaiter{
+		period:d,
+		ch:make(chan time.Time, 1),
+	}
+	f.addWaiter(w, d)
+	return &fakeTicker{w}
+}
+
+//call cb in Advance goroutine after d
+func (f *FakeClock) AfterFunc(d time.Duration, cb func()) iface.ITimer {
+	w := &fakeWaiter{
+		cb:cb,
+	}
+	f.addWaiter(w, d)
+	return w
+}
+
+//move time forward, fire all due timers and tickers in order
+func (f *FakeClock) Advance(d time.Duration) {
+	if d < 0 {
+		return
+	}
+	f.Lock()
+	target := f.now.Add(d)
+	f.Unlock()
+	for f.fireNext(target) {
+	}
+}
+
+//move time to t, do nothing if t before now
+func (f *FakeClock) Set(t time.Time) {
+	f.Advance(t.Sub(f.Now()))
+}
+
+//get pending timers and tickers count
+func (f *FakeClock) Pending() int {
+	f.Lock()
+	defer f.Unlock()
+	return len(f.waiters)
+}
+
+//////////////
+//private func
+//////////////
+
+//add waiter, sorted by due time and create order
+func (f *FakeClock) addWaiter(w *fakeWaiter, d time.Duration) {
+	f.Lock()
+	defer f.Unlock()
+	f.seq++
+	w.clock = f
+	w.seq = f.seq
+	w.due = f.now.Add(d)
+	f.insert(w)
+}
+
+//insert waiter, lock outside
+func (f *FakeClock) insert(w *fakeWaiter) {
+	idx := sort.Search(len(f.waiters), func(i int) bool {
+		v := f.waiters[i]
+		if v.due.Equal(w.due) {
+			return v.seq > w.seq
+		}
+		return v.due.After(w.due)
+	})
+	f.waiters = append(f.waiters, nil)
+	copy(f.waiters[idx + 1:], f.waiters[idx:])
+	f.waiters[idx] = w
+}
+
+//remove waiter, return false if not pending
+func (f *FakeClock) remove(w *fakeWaiter) bool {
+	f.Lock()
+	defer f.Unlock()
+	for i, v := range f.waiters {
+		if v == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i + 1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+//fire first waiter due before target
+//return false and set now to target if none.
+func (f *FakeClock) fireNext(target time.Time) bool {
+	f.Lock()
+	if len(f.waiters) <= 0 || f.waiters[0].due.After(target) {
+		if target.After(f.now) {
+			f.now = target
+		}
+		f.Unlock()
+		return false
+	}
+	w := f.waiters[0]
+	f.waiters = f.waiters[1:]
+	f.now = w.due
+	if w.period > 0 {
+		//ticker, schedule next
+		f.seq++
+		w.seq = f.seq
+		w.due = w.due.Add(w.period)
+		f.insert(w)
+	}
+	now := f.now
+	f.Unlock()
+
+	//fire out of lock
+	if w.cb != nil {
+		w.cb()
+	}
+	if w.ch != nil {
+		select {
+		case w.ch <- now:
+		default:
+		}
+	}
+	return true
+}
+
+//get ticker chan
+func (f *fakeTicker) C() <-chan time.Time {
+	return f.ch
+}
+
+//stop ticker
+func (f *fakeTicker) Stop() {
+	f.fakeWaiter.Stop()
+}
+
+//stop timer, return false if already fired or stopped
+func (f *fakeWaiter) Stop() bool {
+	return f.clock.remove(f)
+}
+
+//fire timer after d from now, return false if already fired or stopped
+func (f *fakeWaiter) Reset(d time.Duration) bool {
+	pending := f.clock.remove(f)
+	f.clock.addWaiter(f, d)
+	return pending
+}
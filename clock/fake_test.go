@@ -0,0 +1,191 @@
+package clock
+
+import (
+	"github.com/andyzhou/thorn/iface"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+//take tick of ticker, zero time if none
+func takeTick(ch <-chan time.Time) time.Time {
+	select {
+	case t := <- ch:
+		return t
+	default:
+		return time.Time{}
+	}
+}
+
+func TestFakeClockTimerOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		delays  []time.Duration //timer delays, in create order
+		advance time.Duration
+		fired   []int           //fired timer index in order
+	}{
+		{"due order", []time.Duration{3 * time.Second, time.Second, 2 * time.Second}, 3 * time.Second, []int{1, 2, 0}},
+		{"same due by create order", []time.Duration{time.Second, time.Second, 0}, time.Second, []int{2, 0, 1}},
+		{"not due", []time.Duration{time.Second, 2 * time.Second}, 1500 * time.Millisecond, []int{0}},
+		{"zero advance fire due now", []time.Duration{0, time.Second}, 0, []int{0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clk := NewFakeClock(testStart)
+			fired := make([]int, 0)
+			for i, d := range test.delays {
+				i, d := i, d
+				clk.AfterFunc(d, func() {
+					//now is due time inside timer func
+					if !clk.Now().Equal(testStart.Add(d)) {
+						t.Errorf("timer %d fired at %v, want %v", i, clk.Now(), testStart.Add(d))
+					}
+					fired = append(fired, i)
+				})
+			}
+			clk.Advance(test.advance)
+			if len(fired) != len(test.fired) {
+				t.Fatalf("fired %v, want %v", fired, test.fired)
+			}
+			for i := range fired {
+				if fired[i] != test.fired[i] {
+					t.Fatalf("fired %v, want %v", fired, test.fired)
+				}
+			}
+			if !clk.Now().Equal(testStart.Add(test.advance)) {
+				t.Fatalf("now %v after advance", clk.Now())
+			}
+			if clk.Pending() != len(test.delays) - len(test.fired) {
+				t.Fatalf("pending %d", clk.Pending())
+			}
+		})
+	}
+}
+
+func TestFakeClockTimerAndTickerOrder(t *testing.T) {
+	clk := NewFakeClock(testStart)
+	ticker := clk.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	//ticker due before timer fires first
+	var tick time.Time
+	clk.AfterFunc(1500 * time.Millisecond, func() {
+		tick = takeTick(ticker.C())
+	})
+	clk.Advance(2 * time.Second)
+	if !tick.Equal(testStart.Add(time.Second)) {
+		t.Fatalf("tick seen by timer %v, want %v", tick, testStart.Add(time.Second))
+	}
+	if tick = takeTick(ticker.C()); !tick.Equal(testStart.Add(2 * time.Second)) {
+		t.Fatalf("tick %v, want %v", tick, testStart.Add(2 * time.Second))
+	}
+}
+
+func TestFakeClockTimerAddedInFunc(t *testing.T) {
+	clk := NewFakeClock(testStart)
+	fired := 0
+	clk.AfterFunc(time.Second, func() {
+		//due inside same advance
+		clk.AfterFunc(time.Second, func() {
+			fired++
+		})
+	})
+	clk.Advance(2 * time.Second)
+	if fired != 1 {
+		t.Fatalf("timer added in timer func fired %d, want 1", fired)
+	}
+}
+
+func TestFakeClockTimerStopReset(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      func(timer iface.ITimer) bool
+		before  time.Duration //advance before op
+		ret     bool          //return of op
+		after   time.Duration //advance after op
+		fired   int
+	}{
+		{"stop pending", func(timer iface.ITimer) bool {
+			return timer.Stop()
+		}, 0, true, 2 * time.Second, 0},
+		{"stop fired", func(timer iface.ITimer) bool {
+			return timer.Stop()
+		}, time.Second, false, time.Second, 1},
+		{"reset pending delays", func(timer iface.ITimer) bool {
+			return timer.Reset(2 * time.Second)
+		}, 500 * time.Millisecond, true, 2 * time.Second - time.Millisecond, 0},
+		{"reset pending fires once", func(timer iface.ITimer) bool {
+			return timer.Reset(2 * time.Second)
+		}, 500 * time.Millisecond, true, 2 * time.Second, 1},
+		{"reset fired re-arms", func(timer iface.ITimer) bool {
+			return timer.Reset(time.Second)
+		}, time.Second, false, time.Second, 2},
+		{"reset stopped re-arms", func(timer iface.ITimer) bool {
+			timer.Stop()
+			return timer.Reset(time.Second)
+		}, 0, false, time.Second, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clk := NewFakeClock(testStart)
+			fired := 0
+			timer := clk.AfterFunc(time.Second, func() {
+				fired++
+			})
+			clk.Advance(test.before)
+			if ret := test.op(timer); ret != test.ret {
+				t.Fatalf("op return %v, want %v", ret, test.ret)
+			}
+			clk.Advance(test.after)
+			if fired != test.fired {
+				t.Fatalf("fired %d, want %d", fired, test.fired)
+			}
+		})
+	}
+}
+
+func TestFakeClockTickerRearm(t *testing.T) {
+	clk := NewFakeClock(testStart)
+	ticker := clk.NewTicker(time.Second)
+
+	//not due
+	clk.Advance(500 * time.Millisecond)
+	if tick := takeTick(ticker.C()); !tick.IsZero() {
+		t.Fatalf("tick %v before due", tick)
+	}
+
+	//due, re-armed for next period
+	clk.Advance(500 * time.Millisecond)
+	if tick := takeTick(ticker.C()); !tick.Equal(testStart.Add(time.Second)) {
+		t.Fatalf("tick %v, want %v", tick, testStart.Add(time.Second))
+	}
+	if clk.Pending() != 1 {
+		t.Fatalf("pending %d after tick, want 1", clk.Pending())
+	}
+
+	//ticks not taken are dropped, chan keeps first one
+	clk.Advance(3 * time.Second)
+	if tick := takeTick(ticker.C()); !tick.Equal(testStart.Add(2 * time.Second)) {
+		t.Fatalf("tick %v, want %v", tick, testStart.Add(2 * time.Second))
+	}
+	if tick := takeTick(ticker.C()); !tick.IsZero() {
+		t.Fatalf("dropped tick %v received", tick)
+	}
+
+	//period kept after drop
+	clk.Advance(time.Second)
+	if tick := takeTick(ticker.C()); !tick.Equal(testStart.Add(5 * time.Second)) {
+		t.Fatalf("tick %v, want %v", tick, testStart.Add(5 * time.Second))
+	}
+
+	//stopped ticker never ticks
+	ticker.Stop()
+	clk.Advance(5 * time.Second)
+	if tick := takeTick(ticker.C()); !tick.IsZero() {
+		t.Fatalf("tick %v after stop", tick)
+	}
+	if clk.Pending() != 0 {
+		t.Fatalf("pending %d after stop, want 0", clk.Pending())
+	}
+}
@@ -0,0 +1,61 @@
+package clock
+
+import (
+	"github.com/andyzhou/thorn/iface"
+	"time"
+)
+
+/*
+ * real clock face, implement of IClock
+ * - wrap time package
+ */
+
+//default real clock
+var Real iface.IClock = NewRealClock()
+
+//face info
+type RealClock struct {
+}
+
+//ticker of real clock
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+//construct
+func NewRealClock() *RealClock {
+	return &RealClock{}
+}
+
+//get current time
+func (f *RealClock) Now() time.Time {
+	return time.Now()
+}
+
+//new ticker
+func (f *RealClock) NewTicker(d time.Duration) iface.ITicker {
+	return &realTicker{ticker:time.NewTicker(d)}
+}
+
+//call f in own goroutine after d
+func (f *RealClock) AfterFunc(d time.Duration, cb func()) iface.ITimer {
+	return time.AfterFunc(d, cb)
+}
+
+//get ticker chan
+func (f *realTicker) C() <-chan time.Time {
+	return f.ticker.C
+}
+
+//stop ticker
+func (f *realTicker) Stop() {
+	f.ticker.Stop()
+}
+
+//get clock, real clock if nil
+func Or(clock iface.IClock) iface.IClock {
+	if clock == nil {
+		return Real
+	}
+	return clock
+}
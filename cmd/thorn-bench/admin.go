@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/andyzhou/thorn/conf"
+	"net/http"
+	"time"
+)
+
+/*
+ * admin api client, create rooms and get server status
+ */
+
+//face info
+type AdminClient struct {
+	addr   string //like http://127.0.0.1:6101
+	key    string //auth key, "" means no auth
+	client *http.Client
+}
+
+//server status
+type ServerStatus struct {
+	CpuTime    float64 `json:"cpuTime"`
+	CpuNum     int     `json:"cpuNum"`
+	Goroutines int     `json:"goroutines"`
+	Rooms      int32   `json:"rooms"`
+}
+
+//construct
+func NewAdminClient(addr, key string) *AdminClient {
+	this := &AdminClient{
+		addr:addr,
+		key:key,
+		client:&http.Client{Timeout:10 * time.Second},
+	}
+	return this
+}
+
+//create room
+func (f *AdminClient) CreateRoom(cfg *conf.RoomConf) error {
+	body, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return f.do(http.MethodPost, "/rooms", body, nil)
+}
+
+//stop room
+func (f *AdminClient) StopRoom(roomId uint64) error {
+	return f.do(http.MethodDelete, fmt.Sprintf("/rooms?id=%d", roomId), nil, nil)
+}
+
+//get server status
+func (f *AdminClient) GetStatus() (*ServerStatus, error) {
+	status := &ServerStatus{}
+	if err := f.do(http.MethodGet, "/status", nil, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+//////////////
+//private func
+//////////////
+
+//do request
+func (f *AdminClient) do(method, path string, body []byte, result interface{}) error {
+	req, err := http.NewRequest(method, f.addr + path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if f.key != "" {
+		req.Header.Set("Authorization", "Bearer " + f.key)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v %v failed, status:%v", method, path, resp.Status)
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/define"
+	"github.com/andyzhou/thorn/network"
+	"github.com/andyzhou/thorn/pb"
+	"github.com/andyzhou/thorn/protocol"
+	"github.com/xtaci/kcp-go"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * bot player, run full lockstep flow
+ * - connect, join, progress, ready, wait start
+ * - send input by rate, heartbeat per second
+ * - record latency from input sent to frame received
+ */
+
+//inter macro define
+const (
+	botStartTimeout = 30 * time.Second
+	botHeartbeatRate = time.Second
+)
+
+//face info
+type Bot struct {
+	id           uint64
+	roomId       uint64
+	token        string
+	session      *kcp.UDPSession
+	protocol     *protocol.Protocol
+	sent         map[int32]time.Time //input sid -> send time
+	seq          int32
+	latencies    []time.Duration
+	inputs       int64
+	frames       int64
+	ackFrame     uint32 //last frame id + 1, ack in heartbeat
+	messages     int64
+	bytes        int64
+	started      bool
+	disconnected bool
+	closed       int32
+	startChan    chan bool
+	sync.Mutex
+}
+
+//construct
+func NewBot(id, roomId uint64, token string) *Bot {
+	this := &Bot{
+		id:id,
+		roomId:roomId,
+		token:token,
+		protocol:protocol.NewProtocol(),
+		sent:map[int32]time.Time{},
+		latencies:make([]time.Duration, 0),
+		startChan:make(chan bool),
+	}
+	return this
+}
+
+//run until stop chan closed
+func (f *Bot) Run(
+		addr string,
+		block kcp.BlockCrypt,
+		impair *conf.ImpairConf,
+		rate int,
+		stopChan chan bool,
+	) error {
+	var (
+		session *kcp.UDPSession
+		err error
+	)
+	//dial server
+	if impair != nil {
+		session, err = network.DialWithImpair(addr, block, impair)
+	}else{
+		session, err = kcp.DialWithOptions(addr, block, 10, 3)
+	}
+	if err != nil {
+		f.disconnected = true
+		return err
+	}
+	session.SetStreamMode(true)
+	session.SetNoDelay(define.KcpNoDelay, define.KcpInterval, define.KcpResend, define.KcpNoCongestion)
+	session.SetWindowSize(define.KcpWindowSize, define.KcpWindowSize)
+	session.SetACKNoDelay(true)
+	f.session = session
+	defer f.close()
+	go f.readLoop()
+
+	//login
+	f.write(pb.ID_MSG_Connect, &pb.C2S_ConnectMsg{
+		PlayerID:f.id,
+		BattleID:f.roomId,
+		Token:f.token,
+	})
+	f.write(pb.ID_MSG_JoinRoom, nil)
+	f.write(pb.ID_MSG_Progress, &pb.C2S_ProgressMsg{Pro:100})
+	f.write(pb.ID_MSG_Ready, nil)
+
+	//wait game start
+	heartbeat := time.NewTicker(botHeartbeatRate)
+	defer heartbeat.Stop()
+	timeout := time.NewTimer(botStartTimeout)
+	defer timeout.Stop()
+	for waiting := true; waiting; {
+		select {
+		case <- f.startChan:
+			waiting = false
+		case <- heartbeat.C:
+			f.write(pb.ID_MSG_Heartbeat, nil)
+		case <- timeout.C:
+			return errors.New("wait game start timeout")
+		case <- stopChan:
+			return nil
+		}
+	}
+
+	//send input by rate
+	if rate <= 0 {
+		rate = 1
+	}
+	input := time.NewTicker(time.Second / time.Duration(rate))
+	defer input.Stop()
+	for {
+		select {
+		case <- input.C:
+			f.sendInput()
+		case <- heartbeat.C:
+			f.write(pb.ID_MSG_Heartbeat, &pb.C2S_HeartbeatMsg{
+				AckFrameCount:atomic.LoadUint32(&f.ackFrame),
+			})
+		case <- stopChan:
+			return nil
+		}
+	}
+}
+
+//////////////
+//private func
+//////////////
+
+//close session
+func (f *Bot) close() {
+	if atomic.CompareAndSwapInt32(&f.closed, 0, 1) && f.session != nil {
+		f.session.Close()
+	}
+}
+
+//send one input
+func (f *Bot) sendInput() {
+	f.Lock()
+	f.seq++
+	seq := f.seq
+	f.sent[seq] = time.Now()
+	f.Unlock()
+	input := &pb.C2S_InputMsg{
+		Sid:seq,
+		FrameID:atomic.LoadUint32(&f.ackFrame),
+	}
+	if f.write(pb.ID_MSG_Input, input) {
+		atomic.AddInt64(&f.inputs, 1)
+	}
+}
+
+//write packet
+func (f *Bot) write(id pb.ID, msg interface{}) bool {
+	packet := protocol.NewPacketWithPara(uint8(id), msg)
+	if packet == nil {
+		return false
+	}
+	_, err := f.session.Write(packet.Pack())
+	return err == nil
+}
+
+//read and process server messages
+func (f *Bot) readLoop() {
+	for {
+		packet, err := f.protocol.ReadPacket(f.session)
+		if err != nil {
+			if atomic.LoadInt32(&f.closed) == 0 {
+				f.Lock()
+				f.disconnected = true
+				f.Unlock()
+			}
+			return
+		}
+		now := time.Now()
+		atomic.AddInt64(&f.messages, 1)
+		atomic.AddInt64(&f.bytes, int64(protocol.MinPacketLen + len(packet.GetData())))
+
+		switch pb.ID(packet.GetMessageId()) {
+		case pb.ID_MSG_Start:
+			f.Lock()
+			if !f.started {
+				f.started = true
+				close(f.startChan)
+			}
+			f.Unlock()
+		case pb.ID_MSG_Frame:
+			msg := &pb.S2C_FrameMsg{}
+			if packet.UnmarshalPB(msg) != nil {
+				continue
+			}
+			f.Lock()
+			for _, frame := range msg.Frames {
+				f.frames++
+				if frame.FrameID + 1 > f.ackFrame {
+					atomic.StoreUint32(&f.ackFrame, frame.FrameID + 1)
+				}
+				for _, input := range frame.Input {
+					if input.GetId() != f.id {
+						continue
+					}
+					if sendTime, ok := f.sent[input.GetSid()]; ok {
+						f.latencies = append(f.latencies, now.Sub(sendTime))
+						delete(f.sent, input.GetSid())
+					}
+				}
+			}
+			f.Unlock()
+		case pb.ID_MSG_Close:
+			f.Lock()
+			f.disconnected = true
+			f.Unlock()
+		}
+	}
+}
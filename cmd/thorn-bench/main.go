@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/define"
+	"github.com/andyzhou/thorn/network"
+	"log"
+	"sync"
+	"time"
+)
+
+/*
+ * load generator
+ * - create rooms by admin api of server, like thornd
+ * - run bots of all rooms for duration
+ * - print latency percentiles, throughput, disconnects and server cpu
+ */
+
+func main() {
+	var (
+		addr, adminAddr, adminKey, password, salt, crypt string
+		rooms, players, rate int
+		roomBase uint64
+		duration, ramp time.Duration
+		impair conf.ImpairConf
+	)
+
+	//parse flags
+	flag.StringVar(&addr, "addr", "127.0.0.1:6100", "server address")
+	flag.StringVar(&adminAddr, "admin", "http://127.0.0.1:6101", "admin api address of server")
+	flag.StringVar(&adminKey, "key", "", "admin api auth key")
+	flag.StringVar(&password, "password", "test", "kcp password")
+	flag.StringVar(&salt, "salt", "abc", "kcp salt")
+	flag.StringVar(&crypt, "crypt", define.CryptAES, "kcp block crypt")
+	flag.IntVar(&rooms, "rooms", 10, "room count")
+	flag.IntVar(&players, "players", 2, "bot players per room")
+	flag.IntVar(&rate, "rate", 10, "inputs per second per bot")
+	flag.Uint64Var(&roomBase, "base", 10000, "first room id")
+	flag.DurationVar(&duration, "duration", 30 * time.Second, "bench duration after all bots dialed")
+	flag.DurationVar(&ramp, "ramp", time.Millisecond, "dial interval between bots")
+	flag.IntVar(&impair.Delay, "delay", 0, "impair delay of bots in ms")
+	flag.IntVar(&impair.Jitter, "jitter", 0, "impair jitter of bots in ms")
+	flag.Float64Var(&impair.Loss, "loss", 0, "impair loss rate of bots, 0~1")
+	flag.Float64Var(&impair.Reorder, "reorder", 0, "impair reorder rate of bots, 0~1")
+	flag.Parse()
+
+	//init
+	block, err := network.NewBlockCrypt(crypt, password, salt)
+	if err != nil {
+		log.Fatalln("init crypt failed, err:", err)
+	}
+	var impairConf *conf.ImpairConf
+	if impair.Delay > 0 || impair.Jitter > 0 || impair.Loss > 0 || impair.Reorder > 0 {
+		if err = impair.Check(); err != nil {
+			log.Fatalln("invalid impair, err:", err)
+		}
+		impairConf = &impair
+	}
+	admin := NewAdminClient(adminAddr, adminKey)
+	stats := NewStats()
+
+	//create rooms
+	roomIds := make([]uint64, 0, rooms)
+	for i := 0; i < rooms; i++ {
+		roomId := roomBase + uint64(i)
+		cfg := &conf.RoomConf{
+			RoomId:roomId,
+			SecretKey:fmt.Sprintf("bench-%d", roomId),
+		}
+		for j := 0; j < players; j++ {
+			cfg.Players = append(cfg.Players, roomId * 1000 + uint64(j) + 1)
+		}
+		if err = admin.CreateRoom(cfg); err != nil {
+			log.Fatalln("create room failed, err:", err)
+		}
+		roomIds = append(roomIds, roomId)
+	}
+	defer func() {
+		for _, roomId := range roomIds {
+			admin.StopRoom(roomId)
+		}
+	}()
+	log.Printf("created %d rooms\n", rooms)
+
+	//begin server status
+	beginStatus, err := admin.GetStatus()
+	if err != nil {
+		log.Println("get server status failed, err:", err)
+	}
+	beginTime := time.Now()
+
+	//run bots
+	stopChan := make(chan bool)
+	wg := new(sync.WaitGroup)
+	lock := new(sync.Mutex)
+	for _, roomId := range roomIds {
+		for j := 0; j < players; j++ {
+			bot := NewBot(roomId * 1000 + uint64(j) + 1, roomId, fmt.Sprintf("bench-%d", roomId))
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := bot.Run(addr, block, impairConf, rate, stopChan)
+				lock.Lock()
+				stats.AddBot(bot, err)
+				lock.Unlock()
+			}()
+			time.Sleep(ramp)
+		}
+	}
+	log.Printf("dialed %d bots, run %v\n", rooms * players, duration)
+
+	//wait and stop
+	time.Sleep(duration)
+	close(stopChan)
+	wg.Wait()
+	stats.duration = time.Since(beginTime)
+
+	//end server status
+	endStatus, err := admin.GetStatus()
+	if err == nil && beginStatus != nil && endStatus.CpuTime > 0 {
+		stats.cpuTime = endStatus.CpuTime - beginStatus.CpuTime
+		stats.cpuNum = endStatus.CpuNum
+	}
+
+	//report
+	stats.Print()
+}
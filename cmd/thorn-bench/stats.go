@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+/*
+ * bench stats, merged from all bots
+ */
+
+//face info
+type Stats struct {
+	bots         int
+	started      int
+	failed       int //dial or start failed
+	disconnected int
+	inputs       int64
+	echoed       int64
+	frames       int64
+	messages     int64
+	bytes        int64
+	latencies    []time.Duration
+	duration     time.Duration
+	cpuTime      float64 //server cpu seconds used, < 0 means unknown
+	cpuNum       int
+}
+
+//construct
+func NewStats() *Stats {
+	this := &Stats{
+		latencies:make([]time.Duration, 0),
+		cpuTime:-1,
+	}
+	return this
+}
+
+//merge one bot
+func (f *Stats) AddBot(bot *Bot, err error) {
+	bot.Lock()
+	defer bot.Unlock()
+	f.bots++
+	if err != nil {
+		f.failed++
+	}
+	if bot.started {
+		f.started++
+	}
+	if bot.disconnected {
+		f.disconnected++
+	}
+	f.inputs += bot.inputs
+	f.echoed += int64(len(bot.latencies))
+	f.frames += bot.frames
+	f.messages += bot.messages
+	f.bytes += bot.bytes
+	f.latencies = append(f.latencies, bot.latencies...)
+}
+
+//print report
+func (f *Stats) Print() {
+	seconds := f.duration.Seconds()
+	if seconds <= 0 {
+		seconds = 1
+	}
+	fmt.Printf("bots:%d, started:%d, failed:%d, disconnected:%d, duration:%v\n",
+		f.bots, f.started, f.failed, f.disconnected, f.duration)
+	fmt.Printf("inputs sent:%d (%.1f/s), echoed:%d\n",
+		f.inputs, float64(f.inputs) / seconds, f.echoed)
+	fmt.Printf("frames received:%d (%.1f/s), messages:%d (%.1f/s), bytes:%d (%.1f KB/s)\n",
+		f.frames, float64(f.frames) / seconds, f.messages, float64(f.messages) / seconds,
+		f.bytes, float64(f.bytes) / 1024 / seconds)
+
+	//latency percentiles
+	if len(f.latencies) > 0 {
+		sort.Slice(f.latencies, func(i, j int) bool {
+			return f.latencies[i] < f.latencies[j]
+		})
+		fmt.Printf("input->frame latency p50:%v, p90:%v, p99:%v, max:%v\n",
+			f.percentile(50), f.percentile(90), f.percentile(99),
+			f.latencies[len(f.latencies) - 1])
+	}else{
+		fmt.Println("input->frame latency: no samples")
+	}
+
+	//server cpu
+	if f.cpuTime >= 0 {
+		fmt.Printf("server cpu:%.2fs (%.1f%% of one core, %d cores)\n",
+			f.cpuTime, f.cpuTime * 100 / seconds, f.cpuNum)
+	}else{
+		fmt.Println("server cpu: unknown")
+	}
+}
+
+//get percentile of sorted latencies
+func (f *Stats) percentile(p int) time.Duration {
+	idx := len(f.latencies) * p / 100
+	if idx >= len(f.latencies) {
+		idx = len(f.latencies) - 1
+	}
+	return f.latencies[idx]
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"github.com/andyzhou/thorn/pb"
+	"sync"
+)
+
+/*
+ * clock sync by heartbeat
+ * - rtt = (t3 - t0) - (t2 - t1)
+ * - offset = ((t1 - t0) + (t2 - t3)) / 2
+ */
+
+//face info
+type clockSync struct {
+	lastServerTime int64 //server send time of last reply
+	lastRecvTime   int64 //receive time of last reply
+	offset         int64 //server clock - local clock, ms
+	rtt            int64 //ms
+	frameID        uint32
+	frameTime      int64 //server time of frameID tick
+	sync.Mutex
+}
+
+//update by heartbeat reply, now is local receive time
+func (f *clockSync) update(msg *pb.S2C_HeartbeatMsg, now int64) {
+	if msg.GetClientTime() <= 0 {
+		return
+	}
+	f.Lock()
+	defer f.Unlock()
+	f.lastServerTime = msg.GetServerSendTime()
+	f.lastRecvTime = now
+	f.rtt = (now - msg.GetClientTime()) - (msg.GetServerSendTime() - msg.GetServerRecvTime())
+	f.offset = ((msg.GetServerRecvTime() - msg.GetClientTime()) + (msg.GetServerSendTime() - now)) / 2
+	f.frameID = msg.GetFrameID()
+	f.frameTime = msg.GetFrameTime()
+}
+
+//get last reply times for next heartbeat
+func (f *clockSync) last() (int64, int64) {
+	f.Lock()
+	defer f.Unlock()
+	return f.lastServerTime, f.lastRecvTime
+}
+
+func (f *clockSync) String() string {
+	f.Lock()
+	defer f.Unlock()
+	if f.lastServerTime <= 0 {
+		return "no clock sync yet"
+	}
+	return fmt.Sprintf("offset:%dms rtt:%dms frame:%d frameTime:%d",
+				f.offset, f.rtt, f.frameID, f.frameTime)
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/andyzhou/thorn/pb"
+	"github.com/golang/protobuf/proto"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * cli commands, one command per line
+ * - empty line and line begin with `#` are ignored
+ */
+
+//inter macro define
+const (
+	defaultWaitTime = 10 * time.Second
+	commandHelp = `commands:
+  connect [roomId playerId token]  send connect message
+  join                             send join room message
+  progress <0~100>                 send loading progress
+  ready                            send ready message
+  input <sid> <x> <y> [frameId]    send input, frame id default last received + 1
+  hb                               send heartbeat
+  clock                            print clock offset, rtt and server frame by heartbeat
+  ack [frameCount]                 ack frames, default last received + 1
+  result <winnerId>                send game result
+  snapshot <frameId> <hash> [hex]  upload state snapshot, hash in hex
+  send <msgId> [hex data]          send raw packet
+  frames <on|off>                  print frame messages or not
+  wait <msg> [timeout]             wait message from server, like 'wait MSG_Start 5s'
+  sleep <duration>                 sleep, like 'sleep 500ms'
+  quit                             quit`
+)
+
+var (
+	errQuit = errors.New("quit")
+)
+
+//exec one command line
+func (f *Cli) Exec(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	args := strings.Fields(line)
+	cmd, args := strings.ToLower(args[0]), args[1:]
+
+	switch cmd {
+	case "help", "?":
+		fmt.Println(commandHelp)
+	case "connect":
+		if len(args) >= 2 {
+			roomId, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return err
+			}
+			playerId, err := strconv.ParseUint(args[1], 10, 64)
+			if err != nil {
+				return err
+			}
+			f.roomId, f.playerId = roomId, playerId
+			if len(args) >= 3 {
+				f.token = args[2]
+			}
+		}
+		return f.writePacket(pb.ID_MSG_Connect, &pb.C2S_ConnectMsg{
+			PlayerID:f.playerId,
+			BattleID:f.roomId,
+			Token:f.token,
+		})
+	case "join":
+		return f.writePacket(pb.ID_MSG_JoinRoom, nil)
+	case "progress":
+		values, err := parseInts(args, 1, 1)
+		if err != nil {
+			return err
+		}
+		return f.writePacket(pb.ID_MSG_Progress, &pb.C2S_ProgressMsg{Pro:int32(values[0])})
+	case "ready":
+		return f.writePacket(pb.ID_MSG_Ready, nil)
+	case "input":
+		values, err := parseInts(args, 3, 4)
+		if err != nil {
+			return err
+		}
+		frameID := atomic.LoadUint32(&f.frameID) + 1
+		if len(values) > 3 {
+			frameID = uint32(values[3])
+		}
+		return f.writePacket(pb.ID_MSG_Input, &pb.C2S_InputMsg{
+			Sid:int32(values[0]),
+			X:int32(values[1]),
+			Y:int32(values[2]),
+			FrameID:frameID,
+		})
+	case "ack":
+		values, err := parseInts(args, 0, 1)
+		if err != nil {
+			return err
+		}
+		ackFrameCount := atomic.LoadUint32(&f.frameID) + 1
+		if len(values) > 0 {
+			ackFrameCount = uint32(values[0])
+		}
+		return f.writePacket(pb.ID_MSG_Ack, &pb.C2S_AckMsg{AckFrameCount:ackFrameCount})
+	case "hb", "heartbeat":
+		return f.writePacket(pb.ID_MSG_Heartbeat, f.newHeartbeat())
+	case "clock":
+		fmt.Println(f.clock.String())
+	case "result":
+		if len(args) != 1 {
+			return errors.New("usage: result <winnerId>")
+		}
+		winnerId, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return err
+		}
+		return f.writePacket(pb.ID_MSG_Result, &pb.C2S_ResultMsg{WinnerID:winnerId})
+	case "snapshot":
+		if len(args) < 2 {
+			return errors.New("usage: snapshot <frameId> <hash> [hex data]")
+		}
+		frameID, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return err
+		}
+		hash, err := strconv.ParseUint(args[1], 16, 64)
+		if err != nil {
+			return err
+		}
+		data := []byte{}
+		if len(args) > 2 {
+			if _, err = fmt.Sscanf(args[2], "%x", &data); err != nil {
+				return err
+			}
+		}
+		return f.writePacket(pb.ID_MSG_Snapshot, &pb.C2S_SnapshotMsg{
+			FrameID:uint32(frameID),
+			Hash:hash,
+			Data:data,
+		})
+	case "send":
+		if len(args) < 1 {
+			return errors.New("usage: send <msgId> [hex data]")
+		}
+		id, err := parseMessageId(args[0])
+		if err != nil {
+			return err
+		}
+		data := []byte{}
+		if len(args) > 1 {
+			if _, err = fmt.Sscanf(args[1], "%x", &data); err != nil {
+				return err
+			}
+		}
+		return f.writePacket(id, data)
+	case "frames":
+		if len(args) != 1 {
+			return errors.New("usage: frames <on|off>")
+		}
+		val := int32(0)
+		if args[0] == "on" {
+			val = 1
+		}
+		atomic.StoreInt32(&f.showFrames, val)
+	case "wait":
+		if len(args) < 1 {
+			return errors.New("usage: wait <msg> [timeout]")
+		}
+		id, err := parseMessageId(args[0])
+		if err != nil {
+			return err
+		}
+		timeout := defaultWaitTime
+		if len(args) > 1 {
+			if timeout, err = time.ParseDuration(args[1]); err != nil {
+				return err
+			}
+		}
+		return f.waitMessage(id, timeout)
+	case "sleep":
+		if len(args) != 1 {
+			return errors.New("usage: sleep <duration>")
+		}
+		duration, err := time.ParseDuration(args[0])
+		if err != nil {
+			return err
+		}
+		time.Sleep(duration)
+	case "quit", "exit":
+		return errQuit
+	default:
+		return fmt.Errorf("unknown command %v, try help", cmd)
+	}
+	return nil
+}
+
+//////////////
+//private func
+//////////////
+
+//format sent message
+func formatSent(id pb.ID, msg interface{}) string {
+	switch v := msg.(type) {
+	case proto.Message:
+		return fmt.Sprintf("%v {%v}", id, proto.CompactTextString(v))
+	case []byte:
+		if len(v) > 0 {
+			return fmt.Sprintf("%v raw:%x", id, v)
+		}
+	}
+	return id.String()
+}
+
+//parse message id by number or name, like 13, MSG_Start or Start
+func parseMessageId(val string) (pb.ID, error) {
+	if id, err := strconv.ParseUint(val, 10, 8); err == nil {
+		return pb.ID(id), nil
+	}
+	if id, ok := pb.ID_value[val]; ok {
+		return pb.ID(id), nil
+	}
+	if id, ok := pb.ID_value["MSG_" + val]; ok {
+		return pb.ID(id), nil
+	}
+	return 0, fmt.Errorf("unknown message %v", val)
+}
+
+//parse int args
+func parseInts(args []string, min, max int) ([]int64, error) {
+	if len(args) < min || len(args) > max {
+		return nil, fmt.Errorf("need %d~%d args", min, max)
+	}
+	values := make([]int64, 0, len(args))
+	for _, v := range args {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
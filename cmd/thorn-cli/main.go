@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/define"
+	"github.com/andyzhou/thorn/network"
+	"github.com/andyzhou/thorn/pb"
+	"github.com/andyzhou/thorn/protocol"
+	"github.com/xtaci/kcp-go"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * debugging client
+ * - dial server, connect, join room and ready
+ * - print every decoded message from server
+ * - commands from stdin or script file, see `help`
+ */
+
+//face info
+type Cli struct {
+	session    *kcp.UDPSession
+	protocol   *protocol.Protocol
+	roomId     uint64
+	playerId   uint64
+	token      string
+	showFrames int32           //print frame messages or not
+	frameID    uint32          //last received frame id
+	clock      clockSync       //clock sync by heartbeat
+	received   map[pb.ID]int   //received but not waited message count
+	notifyChan chan bool       //notify for new message received
+	closeChan  chan bool
+	sync.Mutex
+}
+
+func main() {
+	var (
+		addr, password, salt, crypt, token, script string
+		roomId, playerId uint64
+		auto, interactive, frames bool
+		hbRate time.Duration
+		impair conf.ImpairConf
+	)
+
+	//parse flags
+	flag.StringVar(&addr, "addr", "127.0.0.1:6100", "server address")
+	flag.StringVar(&password, "password", "test", "kcp password")
+	flag.StringVar(&salt, "salt", "abc", "kcp salt")
+	flag.StringVar(&crypt, "crypt", define.CryptAES, "kcp block crypt")
+	flag.Uint64Var(&roomId, "room", 1, "room id")
+	flag.Uint64Var(&playerId, "player", 1, "player id")
+	flag.StringVar(&token, "token", "", "room secret key")
+	flag.BoolVar(&auto, "auto", true, "connect, join and ready on start")
+	flag.BoolVar(&frames, "frames", true, "print frame messages")
+	flag.DurationVar(&hbRate, "hb", time.Second, "heartbeat rate, 0 means disabled")
+	flag.StringVar(&script, "script", "", "run commands from file, then quit")
+	flag.BoolVar(&interactive, "i", false, "read commands from stdin after script")
+	flag.IntVar(&impair.Delay, "delay", 0, "impair delay in ms")
+	flag.IntVar(&impair.Jitter, "jitter", 0, "impair jitter in ms")
+	flag.Float64Var(&impair.Loss, "loss", 0, "impair loss rate, 0~1")
+	flag.Float64Var(&impair.Reorder, "reorder", 0, "impair reorder rate, 0~1")
+	flag.Parse()
+
+	//dial server
+	block, err := network.NewBlockCrypt(crypt, password, salt)
+	if err != nil {
+		log.Fatalln("init crypt failed, err:", err)
+	}
+	var session *kcp.UDPSession
+	if impair.Delay > 0 || impair.Jitter > 0 || impair.Loss > 0 || impair.Reorder > 0 {
+		if err = impair.Check(); err != nil {
+			log.Fatalln("invalid impair, err:", err)
+		}
+		session, err = network.DialWithImpair(addr, block, &impair)
+	}else{
+		session, err = kcp.DialWithOptions(addr, block, 10, 3)
+	}
+	if err != nil {
+		log.Fatalln("dial server failed, err:", err)
+	}
+	session.SetStreamMode(true)
+	session.SetNoDelay(define.KcpNoDelay, define.KcpInterval, define.KcpResend, define.KcpNoCongestion)
+	session.SetACKNoDelay(true)
+
+	//init cli
+	cli := &Cli{
+		session:session,
+		protocol:protocol.NewProtocol(),
+		roomId:roomId,
+		playerId:playerId,
+		token:token,
+		received:map[pb.ID]int{},
+		notifyChan:make(chan bool, 1),
+		closeChan:make(chan bool),
+	}
+	if frames {
+		cli.showFrames = 1
+	}
+	defer cli.Close()
+	log.Printf("dial %v success\n", addr)
+
+	//spawn process
+	go cli.readLoop()
+	if hbRate > 0 {
+		go cli.heartbeatLoop(hbRate)
+	}
+
+	//auto login
+	if auto {
+		for _, line := range []string{"connect", "join", "ready"} {
+			if err = cli.Exec(line); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+
+	//run script
+	if script != "" {
+		file, subErr := os.Open(script)
+		if subErr != nil {
+			log.Fatalln("open script failed, err:", subErr)
+		}
+		err = cli.Run(file, false)
+		file.Close()
+		if err != nil {
+			log.Println("script stopped, err:", err)
+			os.Exit(1)
+		}
+		if !interactive {
+			return
+		}
+	}
+
+	//interactive
+	cli.Run(os.Stdin, true)
+}
+
+//close
+func (f *Cli) Close() {
+	f.Lock()
+	defer f.Unlock()
+	select {
+	case <- f.closeChan:
+		return
+	default:
+		close(f.closeChan)
+		f.session.Close()
+	}
+}
+
+//run commands from reader
+//interactive mode print error and go on, or stop on error.
+func (f *Cli) Run(reader io.Reader, interactive bool) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		err := f.Exec(scanner.Text())
+		if err == errQuit {
+			return nil
+		}
+		if err != nil {
+			if !interactive {
+				return err
+			}
+			fmt.Println(err)
+		}
+	}
+	return scanner.Err()
+}
+
+//////////////
+//private func
+//////////////
+
+//write packet to server
+func (f *Cli) writePacket(id pb.ID, msg interface{}) error {
+	packet := protocol.NewPacketWithPara(uint8(id), msg)
+	if packet == nil {
+		return fmt.Errorf("pack %v failed", id)
+	}
+	if _, err := f.session.Write(packet.Pack()); err != nil {
+		return err
+	}
+	if id != pb.ID_MSG_Heartbeat {
+		log.Printf("-> %v\n", formatSent(id, msg))
+	}
+	return nil
+}
+
+//read and print server messages
+func (f *Cli) readLoop() {
+	defer f.Close()
+	for {
+		packet, err := f.protocol.ReadPacket(f.session)
+		if err != nil {
+			select {
+			case <- f.closeChan:
+			default:
+				log.Println("read failed, err:", err)
+			}
+			return
+		}
+		id := pb.ID(packet.GetMessageId())
+
+		//clock sync by heartbeat reply
+		if id == pb.ID_MSG_Heartbeat && len(packet.GetData()) > 0 {
+			msg := &pb.S2C_HeartbeatMsg{}
+			if packet.UnmarshalPB(msg) == nil {
+				f.clock.update(msg, time.Now().UnixMilli())
+			}
+		}
+
+		//track last frame id
+		if id == pb.ID_MSG_Frame {
+			msg := &pb.S2C_FrameMsg{}
+			if packet.UnmarshalPB(msg) == nil && len(msg.Frames) > 0 {
+				atomic.StoreUint32(&f.frameID, msg.Frames[len(msg.Frames) - 1].FrameID)
+			}
+		}
+
+		//print
+		if id != pb.ID_MSG_Heartbeat &&
+			(id != pb.ID_MSG_Frame || atomic.LoadInt32(&f.showFrames) == 1) {
+			log.Printf("<- %v\n", formatPacket(packet))
+		}
+
+		//notify waiter
+		f.Lock()
+		f.received[id]++
+		f.Unlock()
+		select {
+		case f.notifyChan <- true:
+		default:
+		}
+	}
+}
+
+//send heartbeat periodically
+func (f *Cli) heartbeatLoop(rate time.Duration) {
+	ticker := time.NewTicker(rate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <- f.closeChan:
+			return
+		case <- ticker.C:
+			if err := f.writePacket(pb.ID_MSG_Heartbeat, f.newHeartbeat()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+//new heartbeat with frame ack and clock sync
+func (f *Cli) newHeartbeat() *pb.C2S_HeartbeatMsg {
+	msg := &pb.C2S_HeartbeatMsg{
+		ClientTime:time.Now().UnixMilli(),
+	}
+	if frameID := atomic.LoadUint32(&f.frameID); frameID > 0 {
+		msg.AckFrameCount = frameID + 1
+	}
+	msg.LastServerTime, msg.LastRecvTime = f.clock.last()
+	return msg
+}
+
+//wait message received, consume one received
+func (f *Cli) waitMessage(id pb.ID, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		f.Lock()
+		if f.received[id] > 0 {
+			f.received[id]--
+			f.Unlock()
+			return nil
+		}
+		f.Unlock()
+		select {
+		case <- f.notifyChan:
+		case <- f.closeChan:
+			return fmt.Errorf("connect closed while wait %v", id)
+		case <- timer.C:
+			return fmt.Errorf("wait %v timeout", id)
+		}
+	}
+}
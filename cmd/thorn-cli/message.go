@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"github.com/andyzhou/thorn/iface"
+	"github.com/andyzhou/thorn/pb"
+	"github.com/golang/protobuf/proto"
+)
+
+/*
+ * decode and format server messages
+ */
+
+//new pb message of server side by message id, nil means no body
+func newServerMessage(id pb.ID) proto.Message {
+	switch id {
+	case pb.ID_MSG_Connect:
+		return &pb.S2C_ConnectMsg{}
+	case pb.ID_MSG_JoinRoom:
+		return &pb.S2C_JoinRoomMsg{}
+	case pb.ID_MSG_Progress:
+		return &pb.S2C_ProgressMsg{}
+	case pb.ID_MSG_Start:
+		return &pb.S2C_StartMsg{}
+	case pb.ID_MSG_Frame:
+		return &pb.S2C_FrameMsg{}
+	case pb.ID_MSG_Close:
+		return &pb.S2C_CloseMsg{}
+	case pb.ID_MSG_Snapshot:
+		return &pb.S2C_SnapshotMsg{}
+	case pb.ID_MSG_CatchUp:
+		return &pb.S2C_CatchUpMsg{}
+	case pb.ID_MSG_InputStats:
+		return &pb.S2C_InputStatsMsg{}
+	case pb.ID_MSG_InputAck:
+		return &pb.S2C_InputAckMsg{}
+	case pb.ID_MSG_Timing:
+		return &pb.S2C_TimingMsg{}
+	case pb.ID_MSG_Heartbeat:
+		return &pb.S2C_HeartbeatMsg{}
+	case pb.ID_MSG_Quality:
+		return &pb.S2C_QualityMsg{}
+	case pb.ID_MSG_PlayerStatus:
+		return &pb.S2C_PlayerStatusMsg{}
+	case pb.ID_MSG_Bot:
+		return &pb.S2C_BotMsg{}
+	default:
+		return nil
+	}
+}
+
+//format packet into readable string
+func formatPacket(packet iface.IPacket) string {
+	id := pb.ID(packet.GetMessageId())
+	msg := newServerMessage(id)
+	if msg == nil {
+		if len(packet.GetData()) > 0 {
+			return fmt.Sprintf("%v raw:%x", id, packet.GetData())
+		}
+		return id.String()
+	}
+	if err := proto.Unmarshal(packet.GetData(), msg); err != nil {
+		return fmt.Sprintf("%v decode failed, err:%v, raw:%x", id, err, packet.GetData())
+	}
+	return fmt.Sprintf("%v {%v}", id, proto.CompactTextString(msg))
+}
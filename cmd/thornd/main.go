@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/andyzhou/thorn"
+	"github.com/andyzhou/thorn/conf"
+	"log"
+	"os"
+	"time"
+)
+
+/*
+ * standalone lockstep server
+ * - conf from yaml or json file, override by THORN_xxx env
+ * - rooms created by admin api, `POST /rooms`
+ * - SIGHUP reload conf file, SIGINT/SIGTERM graceful shutdown
+ */
+
+//inter macro define
+const (
+	DefaultHost = "0.0.0.0"
+	DefaultPort = 6100
+)
+
+func main() {
+	var (
+		confFile string
+		shutdownTime time.Duration
+		cfg *conf.FileConf
+		err error
+	)
+
+	//parse flags
+	flag.StringVar(&confFile, "c", "", "conf file path, yaml or json")
+	flag.DurationVar(&shutdownTime, "t", time.Minute, "max wait time for running games on shutdown")
+	flag.Parse()
+
+	//load conf
+	if confFile != "" {
+		cfg, err = conf.LoadFileConf(confFile)
+	}else{
+		cfg = conf.NewFileConf()
+		if err = cfg.ApplyEnv(conf.EnvPrefix); err == nil {
+			err = cfg.Check()
+		}
+	}
+	if err != nil {
+		log.Println("load conf failed, err:", err)
+		os.Exit(1)
+	}
+
+	//apply net tunables before server created
+	if cfg.Net != nil {
+		cfg.Net.Apply()
+	}
+
+	//setup server conf
+	serverConf := &thorn.ServerConf{
+		Host:DefaultHost,
+		Port:DefaultPort,
+		ConfFile:confFile,
+	}
+	if cfg.Server != nil {
+		if cfg.Server.Host != "" {
+			serverConf.Host = cfg.Server.Host
+		}
+		if cfg.Server.Port > 0 {
+			serverConf.Port = cfg.Server.Port
+		}
+		serverConf.Password = cfg.Server.Password
+		serverConf.Salt = cfg.Server.Salt
+		serverConf.Crypt = cfg.Server.Crypt
+		serverConf.UpgradeSock = cfg.Server.UpgradeSock
+		serverConf.SessionStats = cfg.Server.SessionStats
+		serverConf.AdminAddr = cfg.Server.AdminAddr
+	}
+	serverConf.Impair = cfg.Impair
+	if serverConf.AdminAddr == "" {
+		log.Println("admin api disabled, no way to create rooms")
+	}
+
+	//init server
+	server := thorn.NewServer(serverConf)
+	if confFile == "" {
+		server.ApplyFileConf(cfg)
+	}
+
+	//start, return on signal
+	server.Start()
+
+	//graceful shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTime)
+	defer cancel()
+	if err = server.Shutdown(ctx); err != nil {
+		log.Println("shutdown failed, err:", err)
+	}
+}
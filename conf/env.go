@@ -0,0 +1,117 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/*
+ * env overrides for file conf
+ * - env name is prefix and upper json keys joined by `_`
+ * - example: THORN_LOGLEVEL, THORN_SERVER_PORT, THORN_KCP_INTERVAL
+ * - slice value split by `,`
+ */
+
+//apply env overrides
+func (f *FileConf) ApplyEnv(prefix string) error {
+	_, err := applyEnv(reflect.ValueOf(f).Elem(), strings.ToUpper(prefix))
+	return err
+}
+
+//////////////
+//private func
+//////////////
+
+//apply env for all fields of struct, return true if any field set
+func applyEnv(v reflect.Value, prefix string) (bool, error) {
+	var (
+		isSet bool
+	)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := strings.Split(field.Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(key)
+		fv := v.Field(i)
+
+		//sub conf, alloc only if any env set
+		if fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct {
+			sub := reflect.New(fv.Type().Elem())
+			if !fv.IsNil() {
+				sub.Elem().Set(fv.Elem())
+			}
+			subSet, err := applyEnv(sub.Elem(), name)
+			if err != nil {
+				return false, err
+			}
+			if subSet {
+				fv.Set(sub)
+				isSet = true
+			}
+			continue
+		}
+
+		//value field
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setEnvValue(fv, val); err != nil {
+			return false, fmt.Errorf("invalid env %v, err:%v", name, err)
+		}
+		isSet = true
+	}
+	return isSet, nil
+}
+
+//set field by env value
+func setEnvValue(fv reflect.Value, val string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %v", fv.Type())
+		}
+		items := make([]string, 0)
+		for _, v := range strings.Split(val, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				items = append(items, v)
+			}
+		}
+		fv.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %v", fv.Type())
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package conf
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/andyzhou/thorn/define"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+ * conf for server file, yaml or json format
+ * - format by file ext, `.yaml` or `.yml` for yaml, others for json
+ * - values can be overridden by env, see ApplyEnv
+ * - reloaded on SIGHUP, except server and net part
+ */
+
+//env prefix for overrides
+const (
+	EnvPrefix = "THORN"
+)
+
+//kcp tuning, apply for new sessions
+type KcpTuneConf struct {
+	NoDelay      int  `json:"noDelay" yaml:"noDelay"`
+	Interval     int  `json:"interval" yaml:"interval"`         //internal update timer interval in millisecond
+	Resend       int  `json:"resend" yaml:"resend"`             //fast resend
+	NoCongestion int  `json:"noCongestion" yaml:"noCongestion"` //1 means turn off congestion control
+	SndWnd       int  `json:"sndWnd" yaml:"sndWnd"`
+	RcvWnd       int  `json:"rcvWnd" yaml:"rcvWnd"`
+	ReadBuffer   int  `json:"readBuffer" yaml:"readBuffer"`
+	WriteBuffer  int  `json:"writeBuffer" yaml:"writeBuffer"`
+	AckNoDelay   bool `json:"ackNoDelay" yaml:"ackNoDelay"`
+}
+
+//default kcp tuning
+func NewKcpTuneConf() *KcpTuneConf {
+	return &KcpTuneConf{
+		NoDelay:define.KcpNoDelay,
+		Interval:define.KcpInterval,
+		Resend:define.KcpResend,
+		NoCongestion:define.KcpNoCongestion,
+		SndWnd:define.KcpWindowSize,
+		RcvWnd:define.KcpWindowSize,
+		ReadBuffer:define.KcpBufferSize,
+		WriteBuffer:define.KcpBufferSize,
+		AckNoDelay:true,
+	}
+}
+
+//server listen and admin, apply at startup only
+type ServerFileConf struct {
+	Host         string `json:"host" yaml:"host"`
+	Port         int    `json:"port" yaml:"port"`
+	Password     string `json:"password" yaml:"password"`
+	Salt         string `json:"salt" yaml:"salt"`
+	Crypt        string `json:"crypt" yaml:"crypt"`               //block crypt, "" means aes
+	UpgradeSock  string `json:"upgradeSock" yaml:"upgradeSock"`   //unix socket path for zero downtime upgrade
+	SessionStats bool   `json:"sessionStats" yaml:"sessionStats"` //count kcp segments and fec per player
+	AdminAddr    string `json:"adminAddr" yaml:"adminAddr"`       //host:port of admin api, "" means disabled
+}
+
+//network tunables, apply at startup only
+//0 means keep default value in define.
+type NetConf struct {
+	MaxReadyTime          int64  `json:"maxReadyTime" yaml:"maxReadyTime"`                   //seconds
+	MaxGameFrame          uint32 `json:"maxGameFrame" yaml:"maxGameFrame"`
+	BroadcastOffsetFrames uint32 `json:"broadcastOffsetFrames" yaml:"broadcastOffsetFrames"`
+	MaxFrameDataPerMsg    int    `json:"maxFrameDataPerMsg" yaml:"maxFrameDataPerMsg"`
+	BadNetworkThreshold   int64  `json:"badNetworkThreshold" yaml:"badNetworkThreshold"`     //seconds
+	RoomCheckRate         int    `json:"roomCheckRate" yaml:"roomCheckRate"`                 //seconds
+	DefaultChanSize       int    `json:"defaultChanSize" yaml:"defaultChanSize"`
+	ConnPacketChanSize    int    `json:"connPacketChanSize" yaml:"connPacketChanSize"`
+	RoomInOutChanSize     int    `json:"roomInOutChanSize" yaml:"roomInOutChanSize"`
+	RoomMessageChanSize   int    `json:"roomMessageChanSize" yaml:"roomMessageChanSize"`
+}
+
+//room policy defaults, apply when room conf value is 0
+type RoomPolicyConf struct {
+	MaxPlayers       int    `json:"maxPlayers" yaml:"maxPlayers"`
+	Frequency        int    `json:"frequency" yaml:"frequency"`
+	TimeLimit        int    `json:"timeLimit" yaml:"timeLimit"`
+	NotifyTime       int    `json:"notifyTime" yaml:"notifyTime"`
+	MaxFrameMemory   int    `json:"maxFrameMemory" yaml:"maxFrameMemory"`
+	FrameRetention   string `json:"frameRetention" yaml:"frameRetention"`
+	CatchUpRate      int    `json:"catchUpRate" yaml:"catchUpRate"`
+	InputRateLimit   int    `json:"inputRateLimit" yaml:"inputRateLimit"`
+	InputPolicy      string `json:"inputPolicy" yaml:"inputPolicy"`
+	InputsPerFrame   int    `json:"inputsPerFrame" yaml:"inputsPerFrame"`
+	InputDelay       int    `json:"inputDelay" yaml:"inputDelay"`
+	AdaptiveTiming   bool   `json:"adaptiveTiming" yaml:"adaptiveTiming"`
+	MaxInputHold     int    `json:"maxInputHold" yaml:"maxInputHold"`
+	HeartbeatTimeout int    `json:"heartbeatTimeout" yaml:"heartbeatTimeout"`
+	TimeoutAction    string `json:"timeoutAction" yaml:"timeoutAction"`
+	AfkTimeout       int    `json:"afkTimeout" yaml:"afkTimeout"`
+	AfkAction        string `json:"afkAction" yaml:"afkAction"`
+	ReconnectGrace   int    `json:"reconnectGrace" yaml:"reconnectGrace"`
+	GraceOutcome     string `json:"graceOutcome" yaml:"graceOutcome"`
+	DuplicateLogin   string `json:"duplicateLogin" yaml:"duplicateLogin"`
+}
+
+//file conf
+type FileConf struct {
+	LogLevel  string          `json:"logLevel" yaml:"logLevel"`   //debug, info, warn, error
+	RateLimit int             `json:"rateLimit" yaml:"rateLimit"` //max packets per second per connect, 0 means no limit
+	AuthKeys  []string        `json:"authKeys" yaml:"authKeys"`   //keys for admin api, empty means no auth
+	Metrics   bool            `json:"metrics" yaml:"metrics"`     //enable /metrics of admin api
+	Server    *ServerFileConf `json:"server" yaml:"server"`
+	Net       *NetConf        `json:"net" yaml:"net"`
+	Kcp       *KcpTuneConf    `json:"kcp" yaml:"kcp"`
+	Room      *RoomPolicyConf `json:"room" yaml:"room"`
+	Impair    *ImpairConf     `json:"impair" yaml:"impair"` //network impairment for test, apply at startup only
+}
+
+//default file conf, for run without file
+func NewFileConf() *FileConf {
+	return &FileConf{
+		Kcp:NewKcpTuneConf(),
+	}
+}
+
+//load file conf
+func LoadFileConf(path string) (*FileConf, error) {
+	//check
+	if path == "" {
+		return nil, errors.New("invalid parameter")
+	}
+
+	//read and decode file
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	//missing kcp values use default
+	cfg := NewFileConf()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	default:
+		err = json.Unmarshal(data, cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	//env overrides
+	if err = cfg.ApplyEnv(EnvPrefix); err != nil {
+		return nil, err
+	}
+	if err = cfg.Check(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+//check values
+func (f *FileConf) Check() error {
+	if f.RateLimit < 0 {
+		return errors.New("rate limit can't be negative")
+	}
+	if f.Kcp != nil {
+		if f.Kcp.Interval < 0 || f.Kcp.SndWnd < 0 || f.Kcp.RcvWnd < 0 ||
+			f.Kcp.ReadBuffer < 0 || f.Kcp.WriteBuffer < 0 {
+			return errors.New("kcp values can't be negative")
+		}
+	}
+	if f.Server != nil {
+		if f.Server.Port < 0 || f.Server.Port > 65535 {
+			return errors.New("invalid server port")
+		}
+	}
+	if f.Net != nil {
+		if f.Net.MaxReadyTime < 0 || f.Net.MaxFrameDataPerMsg < 0 ||
+			f.Net.BadNetworkThreshold < 0 || f.Net.RoomCheckRate < 0 ||
+			f.Net.DefaultChanSize < 0 || f.Net.ConnPacketChanSize < 0 ||
+			f.Net.RoomInOutChanSize < 0 || f.Net.RoomMessageChanSize < 0 {
+			return errors.New("net values can't be negative")
+		}
+	}
+	if f.Impair != nil {
+		if err := f.Impair.Check(); err != nil {
+			return err
+		}
+	}
+	if f.Room != nil {
+		if f.Room.MaxPlayers < 0 || f.Room.Frequency < 0 ||
+			f.Room.TimeLimit < 0 || f.Room.NotifyTime < 0 ||
+			f.Room.MaxFrameMemory < 0 || f.Room.CatchUpRate < 0 ||
+			f.Room.InputRateLimit < 0 || f.Room.InputsPerFrame < 0 ||
+			f.Room.InputDelay < 0 || f.Room.MaxInputHold < 0 ||
+			f.Room.HeartbeatTimeout < 0 || f.Room.AfkTimeout < 0 ||
+			f.Room.ReconnectGrace < 0 {
+			return errors.New("room values can't be negative")
+		}
+		if err := CheckFrameRetention(f.Room.FrameRetention); err != nil {
+			return err
+		}
+		if err := CheckInputPolicy(f.Room.InputPolicy); err != nil {
+			return err
+		}
+		if err := CheckPlayerAction(f.Room.TimeoutAction); err != nil {
+			return err
+		}
+		if err := CheckPlayerAction(f.Room.AfkAction); err != nil {
+			return err
+		}
+		if err := CheckGraceOutcome(f.Room.GraceOutcome); err != nil {
+			return err
+		}
+		if err := CheckDuplicateLogin(f.Room.DuplicateLogin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//apply net tunables into define, call before server created
+func (f *NetConf) Apply() {
+	if f.MaxReadyTime > 0 {
+		define.MaxReadyTime = f.MaxReadyTime
+	}
+	if f.MaxGameFrame > 0 {
+		define.MaxGameFrame = f.MaxGameFrame
+	}
+	if f.BroadcastOffsetFrames > 0 {
+		define.BroadcastOffsetFrames = f.BroadcastOffsetFrames
+	}
+	if f.MaxFrameDataPerMsg > 0 {
+		define.KMaxFrameDataPerMsg = f.MaxFrameDataPerMsg
+	}
+	if f.BadNetworkThreshold > 0 {
+		define.KBadNetworkThreshold = f.BadNetworkThreshold
+	}
+	if f.RoomCheckRate > 0 {
+		define.RoomCheckRate = f.RoomCheckRate
+	}
+	if f.DefaultChanSize > 0 {
+		define.DefaultChanSize = f.DefaultChanSize
+	}
+	if f.ConnPacketChanSize > 0 {
+		define.ConnPacketChanSize = f.ConnPacketChanSize
+	}
+	if f.RoomInOutChanSize > 0 {
+		define.RoomInOutChanSize = f.RoomInOutChanSize
+	}
+	if f.RoomMessageChanSize > 0 {
+		define.RoomMessageChanSize = f.RoomMessageChanSize
+	}
+}
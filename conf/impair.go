@@ -0,0 +1,28 @@
+package conf
+
+import "errors"
+
+/*
+ * conf for network impairment, for test only
+ * - apply on both send and receive of packet conn
+ */
+
+type ImpairConf struct {
+	Delay        int     `json:"delay" yaml:"delay"`               //fixed delay in millisecond
+	Jitter       int     `json:"jitter" yaml:"jitter"`             //random delay in millisecond, +/- jitter
+	Loss         float64 `json:"loss" yaml:"loss"`                 //drop rate, 0~1
+	Reorder      float64 `json:"reorder" yaml:"reorder"`           //reorder rate, 0~1
+	ReorderDelay int     `json:"reorderDelay" yaml:"reorderDelay"` //extra delay of reordered packet in millisecond, 0 means 20ms
+	Seed         int64   `json:"seed" yaml:"seed"`                 //random seed, 0 means by time
+}
+
+//check values
+func (f *ImpairConf) Check() error {
+	if f.Delay < 0 || f.Jitter < 0 || f.ReorderDelay < 0 {
+		return errors.New("impair delay can't be negative")
+	}
+	if f.Loss < 0 || f.Loss > 1 || f.Reorder < 0 || f.Reorder > 1 {
+		return errors.New("impair rate should be 0~1")
+	}
+	return nil
+}
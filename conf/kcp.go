@@ -0,0 +1,15 @@
+package conf
+
+/*
+ * conf for kcp server
+ */
+
+type KcpConf struct {
+	Address      string //host:port
+	Password     string
+	Salt         string
+	Crypt        string      //block crypt, "" means aes
+	UpgradeSock  string      //unix socket path for upgrade handoff, linux only, "" means disabled
+	Impair       *ImpairConf //network impairment for test, nil means disabled
+	SessionStats bool        //count kcp segments and fec recovery per session, decrypt packets again
+}
@@ -1,16 +1,83 @@
 package conf
 
+import (
+	"fmt"
+	"github.com/andyzhou/thorn/define"
+)
+
 /*
  * conf for room
  */
 
 type RoomConf struct {
-	RoomId     uint64
-	Players    []uint64
-	RandomSeed int32
-	SecretKey  string
-	MaxPlayers int //0 means no limit
-	Frequency  int //frame frame, default 30 frames
-	TimeLimit  int //seconds value, 0 means no limit
-	NotifyTime int //seconds value, notify before end
-}
\ No newline at end of file
+	RoomId           uint64   `json:"roomId"`
+	Players          []uint64 `json:"players"`
+	RandomSeed       int32    `json:"randomSeed"`
+	SecretKey        string   `json:"secretKey"`
+	MaxPlayers       int      `json:"maxPlayers"`       //0 means no limit
+	Frequency        int      `json:"frequency"`        //frame frame, default 30 frames
+	TimeLimit        int      `json:"timeLimit"`        //seconds value, 0 means no limit
+	NotifyTime       int      `json:"notifyTime"`       //seconds value, notify before end
+	MaxFrameMemory   int      `json:"maxFrameMemory"`   //max frame data bytes, 0 means default
+	FrameRetention   string   `json:"frameRetention"`   //policy for frames over memory cap, spill or drop, "" means spill
+	CatchUpRate      int      `json:"catchUpRate"`      //backlog frames per second for lagging player, 0 means default
+	InputRateLimit   int      `json:"inputRateLimit"`   //max inputs per second per player, 0 means no limit
+	InputPolicy      string   `json:"inputPolicy"`      //policy for more inputs of player in frame, keep, last or merge, "" means keep
+	InputsPerFrame   int      `json:"inputsPerFrame"`   //max inputs per player per frame for keep policy, 0 means default
+	InputDelay       int      `json:"inputDelay"`       //frames between current frame and input frame, 0 means no delay
+	AdaptiveTiming   bool     `json:"adaptiveTiming"`   //adapt frequency and input delay by rtt and lateness
+	MaxInputHold     int      `json:"maxInputHold"`     //max ms to hold back inputs of faster players, 0 means no latency equalization
+	HeartbeatTimeout int      `json:"heartbeatTimeout"` //seconds of no heart beat, 0 means default
+	TimeoutAction    string   `json:"timeoutAction"`    //warn, kick or forfeit, "" means kick
+	AfkTimeout       int      `json:"afkTimeout"`       //seconds of heart beat but no input, 0 means no afk check
+	AfkAction        string   `json:"afkAction"`        //warn, kick or forfeit, "" means warn
+	ReconnectGrace   int      `json:"reconnectGrace"`   //seconds seat reserved for disconnected player, 0 means no grace
+	GraceOutcome     string   `json:"graceOutcome"`     //outcome when grace expired, continue, forfeit or abort, "" means continue
+	DuplicateLogin   string   `json:"duplicateLogin"`   //policy for player connecting twice, kick, reject or observe, "" means kick
+	ManualTick       bool     `json:"-"`                //tick by Room.Advance instead of timer, for test
+}
+
+//check frame retention policy, "" means default
+func CheckFrameRetention(policy string) error {
+	switch policy {
+	case "", define.FrameRetentionSpill, define.FrameRetentionDrop:
+		return nil
+	}
+	return fmt.Errorf("invalid frame retention %v", policy)
+}
+
+//check input policy, "" means default
+func CheckInputPolicy(policy string) error {
+	switch policy {
+	case "", define.InputPolicyKeep, define.InputPolicyLast, define.InputPolicyMerge:
+		return nil
+	}
+	return fmt.Errorf("invalid input policy %v", policy)
+}
+
+//check action for timeout or afk player, "" means default
+func CheckPlayerAction(action string) error {
+	switch action {
+	case "", define.PlayerActionWarn, define.PlayerActionKick, define.PlayerActionForfeit:
+		return nil
+	}
+	return fmt.Errorf("invalid player action %v", action)
+}
+
+//check outcome of reconnect grace, "" means default
+func CheckGraceOutcome(outcome string) error {
+	switch outcome {
+	case "", define.GraceOutcomeContinue, define.GraceOutcomeForfeit, define.GraceOutcomeAbort:
+		return nil
+	}
+	return fmt.Errorf("invalid grace outcome %v", outcome)
+}
+
+//check duplicate login policy, "" means default
+func CheckDuplicateLogin(policy string) error {
+	switch policy {
+	case "", define.DuplicateLoginKick, define.DuplicateLoginReject, define.DuplicateLoginObserve:
+		return nil
+	}
+	return fmt.Errorf("invalid duplicate login policy %v", policy)
+}
@@ -0,0 +1,14 @@
+//go:build linux
+
+package thorn
+
+import "syscall"
+
+//get cpu time of process in seconds, user and system
+func getCpuTime() float64 {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	return float64(usage.Utime.Nano() + usage.Stime.Nano()) / 1e9
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package thorn
+
+//get cpu time of process in seconds, not supported
+func getCpuTime() float64 {
+	return 0
+}
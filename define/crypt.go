@@ -0,0 +1,18 @@
+package define
+
+//kcp block crypt
+const (
+	CryptAES      = "aes" //default, aes-256
+	CryptAES128   = "aes-128"
+	CryptAES192   = "aes-192"
+	CryptSalsa20  = "salsa20"
+	CryptBlowfish = "blowfish"
+	CryptTwofish  = "twofish"
+	CryptCast5    = "cast5"
+	Crypt3DES     = "3des"
+	CryptTEA      = "tea"
+	CryptXTEA     = "xtea"
+	CryptSM4      = "sm4"
+	CryptXOR      = "xor"
+	CryptNone     = "none"
+)
@@ -4,6 +4,14 @@ import "errors"
 
 var (
 	ErrorOfInvalidPara = errors.New("invalid input parameter")
+	ErrServerClosing   = errors.New("server is shutting down")
+	//for room
+	ErrRoomNotRunning = errors.New("room is not running")
+	ErrFrameClosed    = errors.New("frame is closed")
+	ErrFrameTooFar    = errors.New("frame is too far ahead")
+	ErrFrameEvicted   = errors.New("frame is evicted")
+	ErrInputDuplicate = errors.New("inputs of player in frame are full")
+	ErrInputDropped   = errors.New("input dropped by merger")
 	//for network
 	ErrConnClosing   = errors.New("use of closed network connection")
 	ErrWriteBlocking = errors.New("write packet was blocking")
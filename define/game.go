@@ -1,13 +1,36 @@
 package define
 
-//global
-const (
+//global, tunable by conf file at startup
+var (
 	MaxReadyTime          int64  = 120           //max prepare time, force close if no any login
 	MaxGameFrame          uint32 = 30*60*3 + 100 //max frames per game round
-	BroadcastOffsetFrames        = 3             //cast per frames
+	BroadcastOffsetFrames uint32 = 3             //cast per frames
 	KMaxFrameDataPerMsg          = 60            //max message packet per frame
-	KBadNetworkThreshold         = 2             //max time for no heart beat
-	PlayerSendChanSize           = 1024
+	KBadNetworkThreshold  int64  = 2             //max time for no heart beat
+	HeartbeatTimeout      int64  = 10            //default seconds of no heart beat, player marked disconnected
+	MaxSnapshotSize              = 1000          //max bytes of one snapshot data, message must fit in protocol.MaxPacketLen
+	MaxPendingSnapshots          = 8             //max frames of not agreed snapshots kept
+	CatchUpRate                  = 600           //default backlog frames per second
+	CatchUpThreshold      uint32 = 90            //lag frames over it move into backlog
+	AckResendTimeout      int64  = 1000          //ms without ack progress, un-acked frames sent again (at least 2 rtt)
+	MaxInputAheadFrames   uint32 = 30            //max frames input can target ahead of current
+	MaxInputLateFrames    uint32 = 30            //input later than it rejected, 0 means no limit
+	InputStatsTicks       uint32 = 30            //report input stats per xx ticks
+	MaxInputDelay         uint32 = 10            //max input delay frames of adaptive timing
+	MinFrequency                 = 10            //min tick frequency of adaptive timing
+	FrequencyStep                = 5             //tick frequency change per adapt
+	AdaptiveTimingTicks   uint32 = 150           //adapt timing per xx ticks
+	AdaptiveLateRatio            = 0.05          //late inputs ratio over it raise input delay
+	EqualizeLatencyTicks  uint32 = 30            //update input holds of latency equalization per xx ticks
+	QualityTicks          uint32 = 60            //send connect quality of players per xx ticks
+	BotHistoryFrames      uint32 = 30            //recent frames given to bot provider
+	MaxSystemAheadFrames  uint32 = 30 * 60       //max frames system input can target ahead of current
+	MaxObservers                 = 4             //max observer sessions per player
+)
+
+//general
+const (
+	PlayerSendChanSize = 1024
 )
 
 //game state
@@ -1,12 +1,30 @@
 package define
 
+import "time"
+
 //general
 const (
-	DefaultChanSize = 1024
-	DefaultTimeOut  = 10
+	DefaultTimeOut = 10
 )
 
-//chan
-const (
+//chan, tunable by conf file at startup
+var (
+	DefaultChanSize    = 1024
 	ConnPacketChanSize = 1024
+)
+
+//kcp tuning default
+const (
+	KcpNoDelay      = 1
+	KcpInterval     = 10
+	KcpResend       = 2
+	KcpNoCongestion = 1
+	KcpWindowSize   = 4096
+	KcpBufferSize   = 4 * 1024 * 1024
+)
+
+//shutdown
+const (
+	ShutdownCheckRate = 100 * time.Millisecond //drain check rate
+	ConnFlushTimeout  = time.Second            //max wait for send chan flush
 )
\ No newline at end of file
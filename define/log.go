@@ -0,0 +1,9 @@
+package define
+
+//log level
+const (
+	LogLevelDebug = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
@@ -2,8 +2,52 @@ package define
 
 //general
 const (
-	RoomFrequency       = 30 //default frame frequency
+	RoomFrequency    = 30  //default frame frequency
+	FrameSegmentSize = 256 //frames per storage segment
+	InputsPerFrame   = 1   //default max inputs per player per frame, for keep policy
+)
+
+//frame retention policy, for frames over memory cap
+const (
+	FrameRetentionSpill = "spill" //push old frames into replay sink, drop if no sink
+	FrameRetentionDrop  = "drop"  //drop old frames
+)
+
+//input policy, for more inputs of one player in frame
+const (
+	InputPolicyKeep  = "keep"  //keep inputs up to inputsPerFrame per player
+	InputPolicyLast  = "last"  //last input of player wins
+	InputPolicyMerge = "merge" //merge by IInputMerger of server
+)
+
+//action for heart beat timeout or afk player
+const (
+	PlayerActionWarn    = "warn"    //notify only, timed out player marked disconnected
+	PlayerActionKick    = "kick"    //close connect, can reconnect
+	PlayerActionForfeit = "forfeit" //close connect and forfeit, can't reconnect
+)
+
+//reserved seat of server injected system inputs, player seats start from 1
+const SystemSeatId int32 = 0
+
+//policy for player connecting while old session online
+const (
+	DuplicateLoginKick    = "kick"    //close old session with reason, new one takes seat
+	DuplicateLoginReject  = "reject"  //reject new session
+	DuplicateLoginObserve = "observe" //new session joins as observer, can't input
+)
+
+//outcome when reconnect grace expired
+const (
+	GraceOutcomeContinue = "continue" //game goes on without the player
+	GraceOutcomeForfeit  = "forfeit"  //player forfeited, can't reconnect
+	GraceOutcomeAbort    = "abort"    //game ended with no result
+)
+
+//tunable by conf file at startup
+var (
 	RoomInOutChanSize   = 1024
 	RoomMessageChanSize = 1024
-	RoomCheckRate       = 60 //xx seconds
+	RoomCheckRate       = 60       //xx seconds
+	RoomMaxFrameMemory  = 32 << 20 //max frame data bytes per room
 )
@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/andyzhou/thorn/iface"
+	"github.com/andyzhou/thorn/pb"
 	"log"
 )
 
@@ -38,6 +39,16 @@ func (f *RoomCallBack)  OneGameOver(roomId uint64) {
 	log.Println("RoomCallBack:OneGameOver")
 }
 
+//optional, implement of IPlayerLagListener
+func (f *RoomCallBack)  OnPlayerLag(roomId, playerId uint64, lag uint32) {
+	log.Println("RoomCallBack:OnPlayerLag")
+}
+
+//optional, implement of IPlayerForfeitListener
+func (f *RoomCallBack)  OnPlayerForfeit(roomId, playerId uint64, reason pb.LEAVE_REASON) {
+	log.Println("RoomCallBack:OnPlayerForfeit, reason:", reason)
+}
+
 
 //implement of IGameListener
 //cb for connected
@@ -0,0 +1,165 @@
+package harness
+
+import (
+	"errors"
+	"github.com/andyzhou/thorn/clock"
+	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/network"
+	"github.com/andyzhou/thorn/room"
+	"sync"
+	"time"
+)
+
+/*
+ * room test harness, run rooms without sockets
+ * - drive Router, Manager and Room directly with in-memory connects
+ * - rooms tick only by Advance, messages processed in send order
+ * - all rooms and connects share fake clock, time moves only by AdvanceTime
+ *
+ * example:
+ *   h := harness.New()
+ *   defer h.Close()
+ *   h.CreateRoom(&conf.RoomConf{RoomId:1, Players:[]uint64{1, 2}, SecretKey:"key"})
+ *   p1, _ := h.Connect(1, 1, "key")
+ *   p1.Join(); p1.Ready()
+ *   h.Advance(1)
+ *   p1.Expect(pb.ID_MSG_Start, &pb.S2C_StartMsg{})
+ */
+
+//face info
+type Harness struct {
+	clock   *clock.FakeClock
+	manager *network.Manager
+	router  *network.Router
+	rooms   map[uint64]*room.Room
+	players []*Player
+	sync.Mutex
+}
+
+//construct
+func New() *Harness {
+	//init manager
+	clk := clock.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	manager := network.NewManagerWithClock(clk)
+
+	//self init
+	this := &Harness{
+		clock:clk,
+		manager:manager,
+		router:network.NewRouter(manager),
+		rooms:map[uint64]*room.Room{},
+		players:make([]*Player, 0),
+	}
+	return this
+}
+
+//close all rooms and connects
+func (f *Harness) Close() {
+	f.Lock()
+	defer f.Unlock()
+	for _, p := range f.players {
+		p.conn.Close()
+	}
+	for _, r := range f.rooms {
+		r.Stop()
+		r.Wait()
+	}
+	f.manager.Close()
+	f.rooms = map[uint64]*room.Room{}
+	f.players = make([]*Player, 0)
+}
+
+//get manager
+func (f *Harness) GetManager() *network.Manager {
+	return f.manager
+}
+
+//get fake clock
+func (f *Harness) GetClock() *clock.FakeClock {
+	return f.clock
+}
+
+//get router
+func (f *Harness) GetRouter() *network.Router {
+	return f.router
+}
+
+//create room with manual tick
+func (f *Harness) CreateRoom(cfg *conf.RoomConf) (*room.Room, error) {
+	//check
+	if cfg == nil || cfg.RoomId <= 0 {
+		return nil, errors.New("invalid parameter")
+	}
+
+	//init room
+	cfg.ManualTick = true
+	r := room.NewRoomWithClock(cfg, f.clock)
+	if !f.manager.AddRoom(r) {
+		r.Stop()
+		return nil, errors.New("add room failed")
+	}
+	f.Lock()
+	f.rooms[cfg.RoomId] = r
+	f.Unlock()
+	return r, nil
+}
+
+//get room
+func (f *Harness) GetRoom(roomId uint64) *room.Room {
+	f.Lock()
+	defer f.Unlock()
+	return f.rooms[roomId]
+}
+
+//connect virtual player and send connect message
+func (f *Harness) Connect(roomId, playerId uint64, token string) (*Player, error) {
+	p := f.NewPlayer(roomId, playerId)
+	if err := p.Connect(token); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+//new virtual player without connect
+func (f *Harness) NewPlayer(roomId, playerId uint64) *Player {
+	p := newPlayer(f, roomId, playerId)
+	f.Lock()
+	f.players = append(f.players, p)
+	f.Unlock()
+	return p
+}
+
+//process pending messages of all rooms, then tick n times
+func (f *Harness) Advance(ticks int) {
+	for _, r := range f.getRooms() {
+		r.Advance(ticks)
+	}
+}
+
+//process pending messages of all rooms
+func (f *Harness) Sync() {
+	f.Advance(0)
+}
+
+//process pending messages, then move clock forward
+//fire due timers, such as room time limit, then process again.
+func (f *Harness) AdvanceTime(d time.Duration) {
+	f.Sync()
+	f.clock.Advance(d)
+	f.Sync()
+}
+
+//////////////
+//private func
+//////////////
+
+//get all rooms
+func (f *Harness) getRooms() []*room.Room {
+	f.Lock()
+	defer f.Unlock()
+	rooms := make([]*room.Room, 0, len(f.rooms))
+	for _, r := range f.rooms {
+		rooms = append(rooms, r)
+	}
+	return rooms
+}
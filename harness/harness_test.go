@@ -0,0 +1,166 @@
+package harness
+
+import (
+	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/pb"
+	"testing"
+)
+
+//new harness with one room of two players
+func newTestHarness(t *testing.T) *Harness {
+	h := New()
+	t.Cleanup(h.Close)
+	_, err := h.CreateRoom(&conf.RoomConf{
+		RoomId:1,
+		Players:[]uint64{1, 2},
+		SecretKey:"key",
+		InputsPerFrame:4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+//connect, join and ready player, check replies
+func joinReady(t *testing.T, h *Harness, playerId uint64) *Player {
+	p, err := h.Connect(1, playerId, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Sync()
+	connectMsg := &pb.S2C_ConnectMsg{}
+	if err = p.Expect(pb.ID_MSG_Connect, connectMsg); err != nil {
+		t.Fatal(err)
+	}
+	if connectMsg.ErrorCode != pb.ERROR_CODE_ERR_Ok {
+		t.Fatalf("player %d connect error %v", playerId, connectMsg.ErrorCode)
+	}
+	p.Join()
+	h.Sync()
+	joinMsg := &pb.S2C_JoinRoomMsg{}
+	if err = p.Expect(pb.ID_MSG_JoinRoom, joinMsg); err != nil {
+		t.Fatal(err)
+	}
+	if joinMsg.RoomSeatId <= 0 {
+		t.Fatalf("player %d seat %d", playerId, joinMsg.RoomSeatId)
+	}
+	p.Ready()
+	return p
+}
+
+func TestConnectWrongToken(t *testing.T) {
+	h := newTestHarness(t)
+	p, err := h.Connect(1, 1, "bad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Sync()
+	msg := &pb.S2C_ConnectMsg{}
+	if err = p.Expect(pb.ID_MSG_Connect, msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.ErrorCode == pb.ERROR_CODE_ERR_Ok {
+		t.Fatal("connect with wrong token accepted")
+	}
+}
+
+func TestStartOnTick(t *testing.T) {
+	h := newTestHarness(t)
+	p1 := joinReady(t, h, 1)
+	p2 := joinReady(t, h, 2)
+	h.Sync()
+	if err := p1.Expect(pb.ID_MSG_Start, nil); err == nil {
+		t.Fatal("started before tick")
+	}
+
+	//ready checked by tick
+	h.Advance(1)
+	for _, p := range []*Player{p1, p2} {
+		msg := &pb.S2C_StartMsg{}
+		if err := p.Expect(pb.ID_MSG_Start, msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Frequency == 0 {
+			t.Fatalf("player %d start without frequency", p.GetId())
+		}
+	}
+}
+
+func TestInputDelivered(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs map[uint64][]int32 //player id -> input sids
+	}{
+		{"one player", map[uint64][]int32{1:{7}}},
+		{"both players", map[uint64][]int32{1:{7}, 2:{8}}},
+		{"inputs in order", map[uint64][]int32{1:{1, 2, 3}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := newTestHarness(t)
+			players := map[uint64]*Player{
+				1:joinReady(t, h, 1),
+				2:joinReady(t, h, 2),
+			}
+			h.Advance(1)
+			for _, p := range players {
+				p.Frames()
+			}
+
+			//send inputs, tick delivers them in one frame
+			for id, sids := range test.inputs {
+				for _, sid := range sids {
+					players[id].Input(&pb.C2S_InputMsg{Sid:sid})
+				}
+			}
+			h.Advance(1)
+			for _, p := range players {
+				got := map[uint64][]int32{}
+				frameIds := map[uint32]bool{}
+				for _, frame := range p.Frames() {
+					for _, input := range frame.Input {
+						got[input.Id] = append(got[input.Id], input.Sid)
+						frameIds[frame.FrameID] = true
+					}
+				}
+				if len(frameIds) != 1 {
+					t.Fatalf("player %d inputs in frames %v, want one frame", p.GetId(), frameIds)
+				}
+				for id, sids := range test.inputs {
+					if len(got[id]) != len(sids) {
+						t.Fatalf("player %d got inputs %v, want %v", p.GetId(), got, test.inputs)
+					}
+					for i, sid := range sids {
+						if got[id][i] != sid {
+							t.Fatalf("player %d got inputs %v, want %v", p.GetId(), got, test.inputs)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFramesContinuous(t *testing.T) {
+	h := newTestHarness(t)
+	p1 := joinReady(t, h, 1)
+	joinReady(t, h, 2)
+	h.Advance(1)
+	p1.Frames()
+
+	//input every tick, frame ids increase one by one
+	lastFrameId := uint32(0)
+	for i := 0; i < 5; i++ {
+		p1.Input(&pb.C2S_InputMsg{Sid:int32(i)})
+		h.Advance(1)
+		frames := p1.Frames()
+		if len(frames) != 1 {
+			t.Fatalf("tick %d got %d frames, want 1", i, len(frames))
+		}
+		if i > 0 && frames[0].FrameID != lastFrameId + 1 {
+			t.Fatalf("frame %d after %d", frames[0].FrameID, lastFrameId)
+		}
+		lastFrameId = frames[0].FrameID
+	}
+}
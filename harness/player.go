@@ -0,0 +1,209 @@
+package harness
+
+import (
+	"fmt"
+	"github.com/andyzhou/thorn/iface"
+	"github.com/andyzhou/thorn/network"
+	"github.com/andyzhou/thorn/pb"
+	"github.com/andyzhou/thorn/protocol"
+	"github.com/golang/protobuf/proto"
+	"sync"
+)
+
+/*
+ * virtual player, client side of in-memory connect
+ * - send pb messages, collect and assert received messages
+ */
+
+//face info
+type Player struct {
+	h        *Harness
+	roomId   uint64
+	id       uint64
+	conn     *network.MemConn
+	received []iface.IPacket //received but not taken
+	sync.Mutex
+}
+
+//construct
+func newPlayer(h *Harness, roomId, playerId uint64) *Player {
+	this := &Player{
+		h:h,
+		roomId:roomId,
+		id:playerId,
+		received:make([]iface.IPacket, 0),
+	}
+	return this
+}
+
+//get player id
+func (f *Player) GetId() uint64 {
+	return f.id
+}
+
+//get connect
+func (f *Player) GetConn() *network.MemConn {
+	return f.conn
+}
+
+//dial new connect and send connect message
+//old connect is closed if exists.
+func (f *Player) Connect(token string) error {
+	if f.conn != nil {
+		f.conn.Close()
+	}
+	f.conn = network.NewMemConn(f.h.router, f.h.clock)
+	f.conn.Do()
+	return f.Send(pb.ID_MSG_Connect, &pb.C2S_ConnectMsg{
+		PlayerID:f.id,
+		BattleID:f.roomId,
+		Token:token,
+	})
+}
+
+//close connect
+func (f *Player) Disconnect() {
+	if f.conn != nil {
+		f.conn.Close()
+	}
+}
+
+//send message, msg maybe nil, proto message or raw bytes
+func (f *Player) Send(id pb.ID, msg interface{}) error {
+	if f.conn == nil {
+		return fmt.Errorf("player %d not connected", f.id)
+	}
+	packet := protocol.NewPacketWithPara(uint8(id), msg)
+	if packet == nil {
+		return fmt.Errorf("pack %v failed", id)
+	}
+	return f.conn.Deliver(packet)
+}
+
+//send join room
+func (f *Player) Join() error {
+	return f.Send(pb.ID_MSG_JoinRoom, nil)
+}
+
+//send loading progress
+func (f *Player) Progress(pro int32) error {
+	return f.Send(pb.ID_MSG_Progress, &pb.C2S_ProgressMsg{Pro:pro})
+}
+
+//send ready
+func (f *Player) Ready() error {
+	return f.Send(pb.ID_MSG_Ready, nil)
+}
+
+//send heartbeat
+func (f *Player) Heartbeat() error {
+	return f.Send(pb.ID_MSG_Heartbeat, nil)
+}
+
+//send heartbeat with frame ack or clock sync
+func (f *Player) HeartbeatWith(msg *pb.C2S_HeartbeatMsg) error {
+	return f.Send(pb.ID_MSG_Heartbeat, msg)
+}
+
+//send frame ack, frames before ackFrameCount applied
+func (f *Player) Ack(ackFrameCount uint32) error {
+	return f.Send(pb.ID_MSG_Ack, &pb.C2S_AckMsg{AckFrameCount:ackFrameCount})
+}
+
+//send input
+func (f *Player) Input(msg *pb.C2S_InputMsg) error {
+	return f.Send(pb.ID_MSG_Input, msg)
+}
+
+//send result
+func (f *Player) Result(winnerId uint64) error {
+	return f.Send(pb.ID_MSG_Result, &pb.C2S_ResultMsg{WinnerID:winnerId})
+}
+
+//send state snapshot
+func (f *Player) Snapshot(frameId uint32, hash uint64, data []byte) error {
+	return f.Send(pb.ID_MSG_Snapshot, &pb.C2S_SnapshotMsg{
+		FrameID:frameId,
+		Hash:hash,
+		Data:data,
+	})
+}
+
+//take all received messages
+func (f *Player) Received() []iface.IPacket {
+	f.collect()
+	f.Lock()
+	defer f.Unlock()
+	received := f.received
+	f.received = make([]iface.IPacket, 0)
+	return received
+}
+
+//get received messages of id, keep them
+func (f *Player) Messages(id pb.ID) []iface.IPacket {
+	f.collect()
+	f.Lock()
+	defer f.Unlock()
+	packets := make([]iface.IPacket, 0)
+	for _, v := range f.received {
+		if pb.ID(v.GetMessageId()) == id {
+			packets = append(packets, v)
+		}
+	}
+	return packets
+}
+
+//take first received message of id, decode into msg if not nil
+//messages before it are dropped.
+func (f *Player) Expect(id pb.ID, msg proto.Message) error {
+	f.collect()
+	f.Lock()
+	defer f.Unlock()
+	for i, v := range f.received {
+		if pb.ID(v.GetMessageId()) != id {
+			continue
+		}
+		f.received = f.received[i + 1:]
+		if msg != nil {
+			return v.UnmarshalPB(msg)
+		}
+		return nil
+	}
+	return fmt.Errorf("player %d no message %v received", f.id, id)
+}
+
+//take all received frames
+func (f *Player) Frames() []*pb.FrameData {
+	frames := make([]*pb.FrameData, 0)
+	f.collect()
+	f.Lock()
+	defer f.Unlock()
+	left := make([]iface.IPacket, 0)
+	for _, v := range f.received {
+		if pb.ID(v.GetMessageId()) != pb.ID_MSG_Frame {
+			left = append(left, v)
+			continue
+		}
+		msg := &pb.S2C_FrameMsg{}
+		if v.UnmarshalPB(msg) == nil {
+			frames = append(frames, msg.Frames...)
+		}
+	}
+	f.received = left
+	return frames
+}
+
+//////////////
+//private func
+//////////////
+
+//collect packets written to connect
+func (f *Player) collect() {
+	if f.conn == nil {
+		return
+	}
+	packets := f.conn.TakePackets()
+	f.Lock()
+	defer f.Unlock()
+	f.received = append(f.received, packets...)
+}
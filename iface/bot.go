@@ -0,0 +1,14 @@
+package iface
+
+import "github.com/andyzhou/thorn/pb"
+
+/*
+ * interface of bot provider
+ */
+
+//bot for seats of offline players
+//api client should implement this
+//history is recent closed frames, read only, return inputs of seat for frame, nil means no input.
+type IBotProvider interface {
+	GetBotInputs(roomId uint64, roomSeatId int32, frameId uint32, history []*pb.FrameData) []*pb.InputData
+}
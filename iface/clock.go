@@ -0,0 +1,24 @@
+package iface
+
+import "time"
+
+/*
+ * interface of clock
+ * - real clock by default, fake clock for test
+ */
+
+type IClock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) ITicker
+	AfterFunc(d time.Duration, f func()) ITimer
+}
+
+type ITicker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type ITimer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
@@ -1,6 +1,9 @@
 package iface
 
-import "time"
+import (
+	"github.com/andyzhou/thorn/conf"
+	"time"
+)
 
 /*
  * interface of config
@@ -11,4 +14,6 @@ type IConfig interface {
 	GetPacketReceiveChanLimit() uint32
 	GetConnReadTimeout() time.Duration
 	GetConnWriteTimeout() time.Duration
+	GetRateLimit() int
+	GetKcpTune() *conf.KcpTuneConf
 }
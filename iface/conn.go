@@ -16,12 +16,25 @@ type IConnCallBack interface {
 	OnClose(conn IConn) //cb for closed conn
 }
 
+//packet stats of connect
+type ConnStats struct {
+	PacketsIn   uint64 //packets received
+	PacketsOut  uint64 //packets written
+	Pending     int32  //packets queued not written
+	Segments    uint64 //kcp push segments sent, 0 if unknown or session stats off
+	Retransmits uint64 //kcp push segments sent again
+	Parity      uint64 //fec parity packets sent
+	Recovered   uint64 //data shards received recovered by fec
+}
+
 type IConn interface {
 	Close()
 	IsClosed() bool
 	Do()
 	AsyncWritePacket(packet IPacket, duration time.Duration) error
+	Flush(timeout time.Duration) bool
 	GetActiveTime() int64
+	GetStats() ConnStats
 	GetRawConn() net.Conn
 	GetExtraData() interface{}
 	SetExtraData(data interface{}) bool
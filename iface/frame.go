@@ -11,5 +11,6 @@ import (
 type IFrame interface {
 	GetData() []*pb.InputData
 	AddData(data *pb.InputData) bool
+	SetData(pos int, data *pb.InputData) bool
 	GetIdx() uint32
 }
@@ -1,5 +1,7 @@
 package iface
 
+import "github.com/andyzhou/thorn/pb"
+
 /*
  * interface of game
  */
@@ -11,11 +13,48 @@ type IGameListener interface {
 	OneGameOver(roomId uint64)
 }
 
+//optional for game listener, detected by type assertion
+//called instead of OnLeaveGame with leave reason.
+type ILeaveReasonListener interface {
+	OnLeaveGameWithReason(roomId, playerId uint64, reason pb.LEAVE_REASON)
+}
+
+//optional for game listener, lag frames of player
+type IPlayerLagListener interface {
+	OnPlayerLag(roomId, playerId uint64, lag uint32)
+}
+
+//optional for game listener, player forfeited
+type IPlayerForfeitListener interface {
+	OnPlayerForfeit(roomId, playerId uint64, reason pb.LEAVE_REASON)
+}
+
 type IGame interface {
 	Close()
+	CloseWithReason(reason pb.CLOSE_REASON)
+	SetReplaySink(sink IReplaySink)
+	SetFrameRetention(maxMemory int, policy string)
+	SetCatchUpRate(framesPerTick int)
+	SetInputRateLimit(limit int)
+	SetInputPolicy(policy string, inputsPerFrame int)
+	SetTiming(frequency, inputDelay int, adaptive bool)
+	SetLatencyEqualization(maxHold int)
+	SetSupervision(heartbeatTimeout int, timeoutAction string, afkTimeout int, afkAction string)
+	SetReconnectGrace(grace int, outcome string)
+	SetDuplicateLogin(policy string)
+	GetFrequency() int
+	SetInputMerger(merger IInputMerger)
+	SetBotProvider(provider IBotProvider)
+	PushSystemInput(frameId uint32, input *pb.InputData) (uint32, error)
 	GetResult() map[uint64]uint64
+	GetOverReason() pb.CLOSE_REASON
+	GetPlayerLags() map[uint64]uint32
+	GetInputStats() map[uint64]*pb.S2C_InputStatsMsg
+	GetQuality() []*pb.PlayerQuality
 	Tick(now int64) bool
 	ProcessMessage(playerId uint64, packet IPacket) bool
+	ProcessMessageByConn(playerId uint64, conn IConn, packet IPacket) bool
 	JoinGame(playerId uint64, conn IConn) bool
 	LeaveGame(playerId uint64) bool
+	LeaveGameByConn(playerId uint64, conn IConn) bool
 }
@@ -1,15 +1,24 @@
 package iface
 
+import (
+	"context"
+	"github.com/andyzhou/thorn/conf"
+)
+
 /*
  * interface of kcp server
  */
 
 type IKcpServer interface {
 	Quit()
+	Shutdown(ctx context.Context) error
+	Upgraded() <-chan bool
 	GetManager() IManager
 	GetRouter() IConnCallBack
 	GetProtocol() IProtocol
 	GetConfig() IConfig
+	GetClock() IClock
 	SetCallback(cb IConnCallBack) bool
 	SetConfig(config IConfig) bool
+	SetImpair(cfg *conf.ImpairConf) bool
 }
@@ -11,6 +11,11 @@ type ILockStep interface {
 	GetRangeFrames(from, to uint32) []IFrame
 	GetFrame(idx uint32) IFrame
 	GetFrameCount() uint32
+	GetFirstFrame() uint32
+	GetMemSize() int
+	SetMaxMemory(maxMemory int)
+	SetInputPolicy(policy string, inputsPerFrame int, merge InputMergeFunc)
 	PushCommand(data *pb.InputData) bool
+	PushCommandAt(frameId uint32, data *pb.InputData) error
 	Tick() uint32
 }
\ No newline at end of file
@@ -1,13 +1,18 @@
 package iface
 
+import "context"
+
 /*
  * interface of manager
  */
 
 type IManager interface {
 	Close()
+	Shutdown(ctx context.Context) error
 	GetRooms() int32
+	GetRoomIds() []uint64
 	CloseRoom(id uint64) bool
 	GetRoom(id uint64) IRoom
 	AddRoom(room IRoom) bool
+	GetClock() IClock
 }
\ No newline at end of file
@@ -0,0 +1,17 @@
+package iface
+
+import "github.com/andyzhou/thorn/pb"
+
+/*
+ * interface of input merger
+ */
+
+//merger for more inputs of one player in frame
+//api client should implement this
+//return merged input of old and new one, nil means keep old.
+type IInputMerger interface {
+	MergeInput(roomId uint64, frameId uint32, old, input *pb.InputData) *pb.InputData
+}
+
+//merge func of lock step, by frame
+type InputMergeFunc func(frameId uint32, old, input *pb.InputData) *pb.InputData
@@ -22,9 +22,11 @@ type IPacket interface {
 type IPlayerPacket interface {
 	//get
 	GetId() uint64
+	GetConn() IConn
 	GetPacket() IPacket
 
 	//set
 	SetId(id uint64)
+	SetConn(conn IConn)
 	SetPacket(packet IPacket)
 }
\ No newline at end of file
@@ -1,5 +1,7 @@
 package iface
 
+import "github.com/andyzhou/thorn/pb"
+
 /*
  * interface of player
  */
@@ -17,7 +19,41 @@ type IPlayer interface {
 	SetProgress(int32)
 	RefreshHeartbeatTime()
 	GetLastHeartbeatTime() int64
+	RefreshInputTime()
+	GetLastInputTime() int64
+	SetAfkWarned()
+	IsAfkWarned() bool
+	SetTimedOut(timedOut bool)
+	IsTimedOut() bool
+	SetForfeit()
+	IsForfeit() bool
+	SetDisconnectTime(t int64)
+	GetDisconnectTime() int64
+	SetBot(bot bool)
+	IsBot() bool
+	AddObserver(conn IConn) bool
 	SetSendFrameCount(c uint32)
 	GetSendFrameCount() uint32
+	SetAckFrameCount(c uint32)
+	GetAckFrameCount() uint32
+	SetAckTime(t int64)
+	GetAckTime() int64
+	AllowInput(now int64, limit int) bool
+	RecordInput(lateFrames uint32)
+	GetInputStats(resetRecent bool) *pb.S2C_InputStatsMsg
+	StartRTTProbe(frameId uint32, now int64)
+	EndRTTProbe(ackFrameCount uint32, now int64)
+	RecordRTT(sample int64)
+	GetRTT() int64
+	GetJitter() int64
+	GetQuality() *pb.PlayerQuality
+	SetClockSync(clientTime, recvTime, sendTime int64)
+	EndClockSync(sendTime, clientRecvTime int64) bool
+	GetClockOffset() int64
+	SetInputHold(frames uint32)
+	GetInputHold() uint32
+	SetCatchUp(from, end uint32)
+	GetCatchUp() (uint32, uint32)
 	SendMessage(packet IPacket)
+	TrySendMessage(packet IPacket) error
 }
\ No newline at end of file
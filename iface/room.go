@@ -1,16 +1,28 @@
 package iface
 
+import "github.com/andyzhou/thorn/pb"
+
 /*
  * interface of room
  */
 
 type IRoom interface {
 	Stop()
+	StopWithReason(reason pb.CLOSE_REASON)
+	Wait()
 	GetId() uint64
 	GetSecretKey() string
 	IsOver() bool
 	HasPlayer(id uint64) bool
 	VerifyToken(string) bool
+	SetResultSink(sink IResultSink)
+	SetReplaySink(sink IReplaySink)
+	SetInputMerger(merger IInputMerger)
+	SetBotProvider(provider IBotProvider)
+	PushSystemInput(frameId uint32, input *pb.InputData) (uint32, error)
+	GetPlayerLags() map[uint64]uint32
+	GetInputStats() map[uint64]*pb.S2C_InputStatsMsg
+	GetQuality() []*pb.PlayerQuality
 	IGameListener
 	IConnCallBack
 }
@@ -0,0 +1,25 @@
+package iface
+
+import "github.com/andyzhou/thorn/pb"
+
+/*
+ * interface of result and replay sink
+ */
+
+//sink for game result
+//api client should implement this
+//forfeit of player pushed when happened, before result.
+//reason CLOSE_Shutdown means partial result of game stopped by server shutdown.
+type IResultSink interface {
+	OnResult(roomId uint64, result map[uint64]uint64, reason pb.CLOSE_REASON) error
+	OnForfeit(roomId, playerId uint64, reason pb.LEAVE_REASON) error
+	Flush() error
+}
+
+//sink for frame replay
+//api client should implement this
+//frames over room memory cap pushed before game over, in order.
+type IReplaySink interface {
+	OnFrames(roomId uint64, frames []*pb.FrameData) error
+	Flush() error
+}
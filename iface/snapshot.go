@@ -0,0 +1,13 @@
+package iface
+
+import "github.com/andyzhou/thorn/pb"
+
+/*
+ * interface of snapshot store
+ */
+
+type ISnapshotStore interface {
+	Reset()
+	Push(playerId uint64, msg *pb.C2S_SnapshotMsg, voters int) bool
+	GetAgreed() *pb.S2C_SnapshotMsg
+}
@@ -0,0 +1,308 @@
+package thorn
+
+import (
+	"fmt"
+	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/define"
+	"github.com/andyzhou/thorn/iface"
+	"github.com/andyzhou/thorn/network"
+	"github.com/andyzhou/thorn/pb"
+	"github.com/andyzhou/thorn/protocol"
+	"github.com/golang/protobuf/proto"
+	"github.com/xtaci/kcp-go"
+	"net"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/*
+ * integration test over impaired udp
+ * - real kcp server, 20% loss and jitter on server socket, both directions
+ */
+
+const (
+	impairTestKey     = "key"
+	impairTestTimeout = 10 * time.Second
+)
+
+//kcp client of test
+type impairClient struct {
+	t        *testing.T
+	id       uint64
+	session  *kcp.UDPSession
+	packets  chan iface.IPacket
+	received []iface.IPacket  //read from packets, not taken
+	frames   []*pb.FrameData //frames of all taken messages
+	silent   int32           //stop heart beat if 1
+	closed   chan bool
+}
+
+//start server with impaired socket and one room of two players
+func newImpairServer(t *testing.T, cfg *conf.RoomConf) (*Server, string) {
+	if testing.Short() {
+		t.Skip("skip impair test in short mode")
+	}
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP:net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := udpConn.LocalAddr().(*net.UDPAddr).Port
+	udpConn.Close()
+
+	server := NewServer(&ServerConf{
+		Host:"127.0.0.1",
+		Port:port,
+		Password:"pass",
+		Salt:"salt",
+		Impair:&conf.ImpairConf{Loss:0.2, Delay:10, Jitter:5, Seed:1},
+		NoSignal:true,
+	})
+	t.Cleanup(server.Stop)
+	cfg.RoomId = 1
+	cfg.Players = []uint64{1, 2}
+	cfg.SecretKey = impairTestKey
+	//inputs held by kcp retransmit arrive in burst
+	cfg.InputsPerFrame = 64
+	if _, err = server.CreateRoom(cfg); err != nil {
+		t.Fatal(err)
+	}
+	return server, fmt.Sprintf("127.0.0.1:%d", port)
+}
+
+//dial server and send connect
+func dialImpair(t *testing.T, addr string, playerId uint64) *impairClient {
+	block, err := network.NewBlockCrypt("", "pass", "salt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := kcp.DialWithOptions(addr, block, 10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.SetStreamMode(true)
+	session.SetNoDelay(define.KcpNoDelay, define.KcpInterval, define.KcpResend, define.KcpNoCongestion)
+	session.SetWindowSize(define.KcpWindowSize, define.KcpWindowSize)
+	session.SetACKNoDelay(true)
+	c := &impairClient{
+		t:t,
+		id:playerId,
+		session:session,
+		packets:make(chan iface.IPacket, 4096),
+		closed:make(chan bool),
+	}
+	t.Cleanup(c.close)
+	go c.readLoop()
+	go c.heartbeatLoop()
+	c.send(pb.ID_MSG_Connect, &pb.C2S_ConnectMsg{
+		PlayerID:playerId,
+		BattleID:1,
+		Token:impairTestKey,
+	})
+	return c
+}
+
+//dial, join and ready, wait game start
+func joinImpair(t *testing.T, addr string, playerId uint64) *impairClient {
+	c := dialImpair(t, addr, playerId)
+	c.send(pb.ID_MSG_JoinRoom, nil)
+	c.send(pb.ID_MSG_Ready, nil)
+	c.expect(pb.ID_MSG_Start, nil, nil)
+	return c
+}
+
+func (f *impairClient) close() {
+	select {
+	case <- f.closed:
+	default:
+		close(f.closed)
+		f.session.Close()
+	}
+}
+
+func (f *impairClient) send(id pb.ID, msg interface{}) {
+	if _, err := f.session.Write(protocol.NewPacketWithPara(uint8(id), msg).Pack()); err != nil {
+		f.t.Errorf("player %d send %v failed, err:%v", f.id, id, err)
+	}
+}
+
+//send heart beat until closed, frames only sent to player with heart beat
+func (f *impairClient) heartbeatLoop() {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <- f.closed:
+			return
+		case <- ticker.C:
+			if atomic.LoadInt32(&f.silent) == 0 {
+				f.session.Write(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Heartbeat), nil).Pack())
+			}
+		}
+	}
+}
+
+func (f *impairClient) readLoop() {
+	reader := protocol.NewProtocol()
+	for {
+		packet, err := reader.ReadPacket(f.session)
+		if err != nil {
+			close(f.packets)
+			return
+		}
+		f.packets <- packet
+	}
+}
+
+//take first message of id matched by check, decode into msg
+//messages before it are dropped, fail if not received in time.
+func (f *impairClient) expect(id pb.ID, msg proto.Message, check func() bool) {
+	deadline := time.After(impairTestTimeout)
+	for {
+		for len(f.received) > 0 {
+			packet := f.received[0]
+			f.received = f.received[1:]
+			f.record(packet)
+			if pb.ID(packet.GetMessageId()) != id {
+				continue
+			}
+			if msg != nil && packet.UnmarshalPB(msg) != nil {
+				continue
+			}
+			if check == nil || check() {
+				return
+			}
+		}
+		select {
+		case packet, ok := <- f.packets:
+			if !ok {
+				f.t.Fatalf("player %d closed while waiting %v", f.id, id)
+			}
+			f.received = append(f.received, packet)
+		case <- deadline:
+			f.t.Fatalf("player %d wait %v timeout", f.id, id)
+		}
+	}
+}
+
+//keep frames of taken message
+func (f *impairClient) record(packet iface.IPacket) {
+	if pb.ID(packet.GetMessageId()) != pb.ID_MSG_Frame {
+		return
+	}
+	msg := &pb.S2C_FrameMsg{}
+	if packet.UnmarshalPB(msg) == nil {
+		f.frames = append(f.frames, msg.Frames...)
+	}
+}
+
+//get sids of player inputs in frames until all wanted received
+//frames ordered by id, catch up frames may arrive after live ones.
+func (f *impairClient) expectInputs(playerId uint64, want int) []int32 {
+	for {
+		frames := map[uint32]*pb.FrameData{}
+		frameIds := make([]int, 0)
+		for _, frame := range f.frames {
+			if _, ok := frames[frame.FrameID]; !ok {
+				frameIds = append(frameIds, int(frame.FrameID))
+			}
+			frames[frame.FrameID] = frame
+		}
+		sort.Ints(frameIds)
+		sids := make([]int32, 0)
+		for _, frameId := range frameIds {
+			for _, input := range frames[uint32(frameId)].Input {
+				if input.Id == playerId {
+					sids = append(sids, input.Sid)
+				}
+			}
+		}
+		if len(sids) >= want {
+			return sids
+		}
+		f.expect(pb.ID_MSG_Frame, nil, nil)
+	}
+}
+
+//send inputs of sid 1~count by interval
+func sendInputs(c *impairClient, count int, interval time.Duration) {
+	for i := 1; i <= count; i++ {
+		c.send(pb.ID_MSG_Input, &pb.C2S_InputMsg{Sid:int32(i)})
+		time.Sleep(interval)
+	}
+}
+
+//check sids are 1~count in order
+func checkSids(t *testing.T, sids []int32, count int) {
+	if len(sids) != count {
+		t.Fatalf("got %d inputs, want %d", len(sids), count)
+	}
+	for i, sid := range sids {
+		if sid != int32(i + 1) {
+			t.Fatalf("input %d sid %d, want %d", i, sid, i + 1)
+		}
+	}
+}
+
+func TestImpairInputDelivered(t *testing.T) {
+	_, addr := newImpairServer(t, &conf.RoomConf{})
+	p1 := joinImpair(t, addr, 1)
+	p2 := joinImpair(t, addr, 2)
+
+	//all inputs arrive once and in order over lossy link
+	sendInputs(p1, 30, 30 * time.Millisecond)
+	checkSids(t, p1.expectInputs(1, 30), 30)
+	checkSids(t, p2.expectInputs(1, 30), 30)
+}
+
+func TestImpairReconnectCatchUp(t *testing.T) {
+	_, addr := newImpairServer(t, &conf.RoomConf{})
+	p1 := joinImpair(t, addr, 1)
+	p2 := joinImpair(t, addr, 2)
+
+	//p1 gone, game goes on over catch up threshold
+	p1.close()
+	count := int(define.CatchUpThreshold) / define.RoomFrequency + 1
+	sendInputs(p2, count * 10, 100 * time.Millisecond)
+
+	//new session of p1 replaces old one, backlog streamed by catch up
+	p1 = dialImpair(t, addr, 1)
+	p1.send(pb.ID_MSG_JoinRoom, nil)
+	p1.send(pb.ID_MSG_Ready, nil)
+	p1.expect(pb.ID_MSG_Start, nil, nil)
+	catchUp := &pb.S2C_CatchUpMsg{}
+	p1.expect(pb.ID_MSG_CatchUp, catchUp, func() bool {
+		return catchUp.Done
+	})
+	if catchUp.EndFrameID <= define.CatchUpThreshold {
+		t.Fatalf("catch up end frame %d, want over %d", catchUp.EndFrameID, define.CatchUpThreshold)
+	}
+
+	//frames of backlog hold all inputs of p2
+	checkSids(t, p1.expectInputs(2, count * 10), count * 10)
+}
+
+func TestImpairHeartbeatTimeout(t *testing.T) {
+	_, addr := newImpairServer(t, &conf.RoomConf{
+		HeartbeatTimeout:2,
+		TimeoutAction:define.PlayerActionKick,
+	})
+	p1 := joinImpair(t, addr, 1)
+	p2 := joinImpair(t, addr, 2)
+
+	//p1 silent, p2 keeps heartbeat
+	atomic.StoreInt32(&p1.silent, 1)
+	status := &pb.S2C_PlayerStatusMsg{}
+	p2.expect(pb.ID_MSG_PlayerStatus, status, func() bool {
+		return status.Id == 1 && status.Status == pb.PLAYER_STATUS_PLAYER_Kicked
+	})
+	if status.Reason != pb.LEAVE_REASON_LEAVE_Timeout {
+		t.Fatalf("kick reason %v, want timeout", status.Reason)
+	}
+
+	//kicked player can reconnect and play
+	p1 = joinImpair(t, addr, 1)
+	sendInputs(p1, 3, 30 * time.Millisecond)
+	checkSids(t, p2.expectInputs(1, 3), 3)
+}
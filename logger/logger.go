@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"errors"
+	"github.com/andyzhou/thorn/define"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+/*
+ * leveled logger, base on std log
+ */
+
+//inter variable
+var (
+	level int32 = define.LogLevelInfo
+	levelNames = map[string]int32{
+		"debug":define.LogLevelDebug,
+		"info":define.LogLevelInfo,
+		"warn":define.LogLevelWarn,
+		"error":define.LogLevelError,
+	}
+)
+
+//set log level
+func SetLevel(value int32) {
+	atomic.StoreInt32(&level, value)
+}
+
+//get log level
+func GetLevel() int32 {
+	return atomic.LoadInt32(&level)
+}
+
+//parse level name, like `debug`, `info`, `warn`, `error`
+func ParseLevel(name string) (int32, error) {
+	value, ok := levelNames[strings.ToLower(name)]
+	if !ok {
+		return 0, errors.New("invalid log level")
+	}
+	return value, nil
+}
+
+func Debugf(format string, args ...interface{}) {
+	output(define.LogLevelDebug, "[debug] ", format, args...)
+}
+
+func Infof(format string, args ...interface{}) {
+	output(define.LogLevelInfo, "[info] ", format, args...)
+}
+
+func Warnf(format string, args ...interface{}) {
+	output(define.LogLevelWarn, "[warn] ", format, args...)
+}
+
+func Errorf(format string, args ...interface{}) {
+	output(define.LogLevelError, "[error] ", format, args...)
+}
+
+//output with level check
+func output(value int32, prefix, format string, args ...interface{}) {
+	if value < atomic.LoadInt32(&level) {
+		return
+	}
+	log.Printf(prefix + format, args...)
+}
@@ -3,6 +3,7 @@ package network
 import (
 	"github.com/andyzhou/thorn/define"
 	"github.com/andyzhou/thorn/iface"
+	"github.com/andyzhou/thorn/logger"
 	"github.com/xtaci/kcp-go"
 	"log"
 	"net"
@@ -24,9 +25,16 @@ type Conn struct {
 	conn              *kcp.UDPSession     //raw connection
 	callback          iface.IConnCallBack //connect cb interface from outside
 	extraData         interface{}
+	extraLock         sync.RWMutex
 	activeTime        int64              //last active timestamp
 	packetSendChan    chan iface.IPacket //send chan
 	packetReceiveChan chan iface.IPacket //receive chan
+	pendingPackets    int32              //packets queued but not written
+	packetsIn         uint64             //packets received
+	packetsOut        uint64             //packets written
+	segmentStats      *sessionStats      //kcp segment stats, nil if not tracked
+	rateTime          int64              //current rate limit second
+	rateCount         int                //packets received in current second
 	closeFlag         int32
 	closeChan         chan bool
 	closeOnce         sync.Once
@@ -42,7 +50,7 @@ func NewConn(
 	this := &Conn{
 		conn:sess,
 		server:server,
-		activeTime:time.Now().Unix(),
+		activeTime:server.GetClock().Now().Unix(),
 		packetSendChan:make(chan iface.IPacket, define.ConnPacketChanSize),
 		packetReceiveChan:make(chan iface.IPacket, define.ConnPacketChanSize),
 		closeChan:make(chan bool, 1),
@@ -64,13 +72,14 @@ func (f *Conn) Close() {
 	}()
 
 	//do some cleanup
+	//packet chans not closed, send on them may race with close
 	f.closeOnce.Do(func() {
 		atomic.StoreInt32(&f.closeFlag, 1)
-		close(f.packetSendChan)
-		close(f.packetReceiveChan)
 		close(f.closeChan)
 		f.conn.Close()
-		f.callback.OnClose(f)
+		if f.callback != nil {
+			f.callback.OnClose(f)
+		}
 	})
 }
 
@@ -104,21 +113,38 @@ func (f *Conn) Do() {
 
 //get extra data
 func (f *Conn) GetExtraData() interface{} {
+	f.extraLock.RLock()
+	defer f.extraLock.RUnlock()
 	return f.extraData
 }
 
-//set extra data
+//set extra data, nil means clear
 func (f *Conn) SetExtraData(data interface{}) bool {
-	if data == nil {
-		return false
-	}
+	f.extraLock.Lock()
+	defer f.extraLock.Unlock()
 	f.extraData = data
 	return true
 }
 
 //get last active time
 func (f *Conn) GetActiveTime() int64 {
-	return f.activeTime
+	return atomic.LoadInt64(&f.activeTime)
+}
+
+//get packet stats
+func (f *Conn) GetStats() iface.ConnStats {
+	stats := iface.ConnStats{
+		PacketsIn:atomic.LoadUint64(&f.packetsIn),
+		PacketsOut:atomic.LoadUint64(&f.packetsOut),
+		Pending:atomic.LoadInt32(&f.pendingPackets),
+	}
+	if segmentStats := f.segmentStats; segmentStats != nil {
+		stats.Segments = atomic.LoadUint64(&segmentStats.segments)
+		stats.Retransmits = atomic.LoadUint64(&segmentStats.retransmits)
+		stats.Parity = atomic.LoadUint64(&segmentStats.parity)
+		stats.Recovered = atomic.LoadUint64(&segmentStats.recovered)
+	}
+	return stats
 }
 
 //get raw connect
@@ -154,6 +180,7 @@ func (f *Conn) AsyncWritePacket(packet iface.IPacket, timeout time.Duration) err
 	if timeout == 0 {
 		select {
 		case f.packetSendChan <- packet:
+			atomic.AddInt32(&f.pendingPackets, 1)
 			return nil
 		default:
 			return define.ErrWriteBlocking
@@ -161,6 +188,7 @@ func (f *Conn) AsyncWritePacket(packet iface.IPacket, timeout time.Duration) err
 	}else{
 		select {
 		case f.packetSendChan <- packet:
+			atomic.AddInt32(&f.pendingPackets, 1)
 			return nil
 		case <- f.closeChan:
 			return define.ErrConnClosing
@@ -168,8 +196,23 @@ func (f *Conn) AsyncWritePacket(packet iface.IPacket, timeout time.Duration) err
 			return define.ErrWriteBlocking
 		}
 	}
+}
+
+//wait read, write and handle process exit
+func (f *Conn) Wait() {
+	f.wg.Wait()
+}
 
-	return nil
+//wait queued packets written or timeout
+func (f *Conn) Flush(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for !f.IsClosed() && atomic.LoadInt32(&f.pendingPackets) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return true
 }
 
 ///////////////
@@ -204,15 +247,22 @@ func (f *Conn) writeLoop() {
 				if f.IsClosed() {
 					return
 				}
-				//write packet
+				//write packet, skip oversize one
 				//f.conn.SetWriteDeadline(time.Now().Add(writeTimeOut))
-				_, err := f.conn.Write(p.Pack())
+				data := p.Pack()
+				if data == nil {
+					atomic.AddInt32(&f.pendingPackets, -1)
+					continue
+				}
+				_, err := f.conn.Write(data)
+				atomic.AddInt32(&f.pendingPackets, -1)
 				if err != nil {
 					log.Println("Conn:writeLoop, err:", err)
 					return
 				}
+				atomic.AddUint64(&f.packetsOut, 1)
 				//update active time
-				f.activeTime = time.Now().Unix()
+				atomic.StoreInt64(&f.activeTime, f.server.GetClock().Now().Unix())
 			}
 		}
 	}
@@ -246,10 +296,20 @@ func (f *Conn) readLoop() {
 		message, err := f.server.GetProtocol().ReadPacket(f.conn)
 		if err != nil {
 			log.Println("Conn:readLoop, err:", err)
+			return
+		}
+		atomic.AddUint64(&f.packetsIn, 1)
+		//check rate limit
+		if f.isRateLimited() {
+			logger.Debugf("Conn:readLoop, packet dropped by rate limit, id:%d\n", message.GetMessageId())
 			continue
 		}
 		//send to receive chan
-		f.packetReceiveChan <- message
+		select {
+		case f.packetReceiveChan <- message:
+		case <- f.closeChan:
+			return
+		}
 	}
 }
 
@@ -284,12 +344,27 @@ func (f *Conn) handleLoop() {
 				if f.callback != nil {
 					f.callback.OnMessage(f, p)
 				}
-				f.activeTime = time.Now().Unix()
+				atomic.StoreInt64(&f.activeTime, f.server.GetClock().Now().Unix())
 			}
 		}
 	}
 }
 
+//check received packet over rate limit or not
+func (f *Conn) isRateLimited() bool {
+	config := f.server.GetConfig()
+	if config == nil || config.GetRateLimit() <= 0 {
+		return false
+	}
+	now := f.server.GetClock().Now().Unix()
+	if now != f.rateTime {
+		f.rateTime = now
+		f.rateCount = 0
+	}
+	f.rateCount++
+	return f.rateCount > config.GetRateLimit()
+}
+
 //async do some func
 func (f *Conn) asyncDo(fun func(), wg *sync.WaitGroup) {
 	wg.Add(1)
@@ -0,0 +1,17 @@
+package network
+
+import "testing"
+
+func TestConnSetExtraDataNil(t *testing.T) {
+	conn := &Conn{}
+	conn.SetExtraData(uint64(1))
+	if playerId, ok := conn.GetExtraData().(uint64); !ok || playerId != 1 {
+		t.Fatalf("extra data %v, want 1", conn.GetExtraData())
+	}
+
+	//nil clears, conn detached from player
+	conn.SetExtraData(nil)
+	if conn.GetExtraData() != nil {
+		t.Fatalf("extra data %v, want nil", conn.GetExtraData())
+	}
+}
@@ -0,0 +1,58 @@
+package network
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"github.com/andyzhou/thorn/define"
+	"github.com/xtaci/kcp-go"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+/*
+ * kcp block crypt, shared by server and client
+ * - key derived from password and salt by pbkdf2
+ */
+
+//create block crypt by name, "" means aes
+func NewBlockCrypt(crypt, password, salt string) (kcp.BlockCrypt, error) {
+	//init key
+	key := pbkdf2.Key(
+					[]byte(password),
+					[]byte(salt),
+					1024,
+					32,
+					sha1.New,
+				)
+
+	//init block
+	switch crypt {
+	case "", define.CryptAES:
+		return kcp.NewAESBlockCrypt(key)
+	case define.CryptAES128:
+		return kcp.NewAESBlockCrypt(key[:16])
+	case define.CryptAES192:
+		return kcp.NewAESBlockCrypt(key[:24])
+	case define.CryptSalsa20:
+		return kcp.NewSalsa20BlockCrypt(key)
+	case define.CryptBlowfish:
+		return kcp.NewBlowfishBlockCrypt(key)
+	case define.CryptTwofish:
+		return kcp.NewTwofishBlockCrypt(key)
+	case define.CryptCast5:
+		return kcp.NewCast5BlockCrypt(key[:16])
+	case define.Crypt3DES:
+		return kcp.NewTripleDESBlockCrypt(key[:24])
+	case define.CryptTEA:
+		return kcp.NewTEABlockCrypt(key[:16])
+	case define.CryptXTEA:
+		return kcp.NewXTEABlockCrypt(key[:16])
+	case define.CryptSM4:
+		return kcp.NewSM4BlockCrypt(key[:16])
+	case define.CryptXOR:
+		return kcp.NewSimpleXORBlockCrypt(key)
+	case define.CryptNone:
+		return kcp.NewNoneBlockCrypt(key)
+	default:
+		return nil, fmt.Errorf("unsupported crypt %v", crypt)
+	}
+}
@@ -0,0 +1,392 @@
+package network
+
+import (
+	"container/heap"
+	"github.com/andyzhou/thorn/conf"
+	"github.com/xtaci/kcp-go"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * network impairment face, implement of net.PacketConn
+ * - inject delay, jitter, loss and reorder into packet path
+ * - apply on both send and receive, for test only
+ */
+
+//inter macro define
+const (
+	impairReadChanSize = 1024
+	impairReorderDelay = 20 //default extra delay of reordered packet, ms
+	impairReadBuffSize = 2048
+)
+
+//impair stats
+type ImpairStats struct {
+	Sent      uint64 `json:"sent"`
+	Received  uint64 `json:"received"`
+	Dropped   uint64 `json:"dropped"`
+	Reordered uint64 `json:"reordered"`
+}
+
+//face info
+type ImpairConn struct {
+	net.PacketConn                    //raw conn
+	conf           atomic.Value       //*conf.ImpairConf, nil means pass through
+	rand           *rand.Rand
+	randLock       sync.Mutex
+	sendQueue      *impairQueue
+	recvQueue      *impairQueue
+	readChan       chan *impairPacket
+	readDeadline   atomic.Value       //time.Time
+	deadlineChan   chan bool          //wake blocked read when deadline changed
+	closeChan      chan bool
+	closeOnce      sync.Once
+	stats          ImpairStats
+}
+
+//construct
+func NewImpairConn(conn net.PacketConn, cfg *conf.ImpairConf) *ImpairConn {
+	//self init
+	this := &ImpairConn{
+		PacketConn:conn,
+		readChan:make(chan *impairPacket, impairReadChanSize),
+		deadlineChan:make(chan bool, 1),
+		closeChan:make(chan bool),
+	}
+	seed := time.Now().UnixNano()
+	if cfg != nil && cfg.Seed != 0 {
+		seed = cfg.Seed
+	}
+	this.rand = rand.New(rand.NewSource(seed))
+	this.readDeadline.Store(time.Time{})
+	this.SetImpair(cfg)
+
+	//init queues
+	this.sendQueue = newImpairQueue(this.closeChan, func(p *impairPacket) {
+		this.PacketConn.WriteTo(p.data, p.addr)
+	})
+	this.recvQueue = newImpairQueue(this.closeChan, func(p *impairPacket) {
+		select {
+		case this.readChan <- p:
+		case <- this.closeChan:
+		}
+	})
+
+	//spawn process
+	go this.sendQueue.run()
+	go this.recvQueue.run()
+	go this.readLoop()
+	return this
+}
+
+//dial kcp server with impaired udp conn, for client side
+func DialWithImpair(
+		addr string,
+		block kcp.BlockCrypt,
+		cfg *conf.ImpairConf,
+	) (*kcp.UDPSession, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	network := "udp4"
+	if udpAddr.IP.To4() == nil {
+		network = "udp"
+	}
+	udpConn, err := net.ListenUDP(network, nil)
+	if err != nil {
+		return nil, err
+	}
+	return kcp.NewConn2(udpAddr, block, 10, 3, NewImpairConn(udpConn, cfg))
+}
+
+//set impair conf, nil means pass through
+func (f *ImpairConn) SetImpair(cfg *conf.ImpairConf) {
+	f.conf.Store(cfg)
+}
+
+//get stats
+func (f *ImpairConn) GetStats() ImpairStats {
+	return ImpairStats{
+		Sent:atomic.LoadUint64(&f.stats.Sent),
+		Received:atomic.LoadUint64(&f.stats.Received),
+		Dropped:atomic.LoadUint64(&f.stats.Dropped),
+		Reordered:atomic.LoadUint64(&f.stats.Reordered),
+	}
+}
+
+//get raw conn
+func (f *ImpairConn) GetRawConn() net.PacketConn {
+	return f.PacketConn
+}
+
+//close
+func (f *ImpairConn) Close() error {
+	var (
+		err error
+	)
+	f.closeOnce.Do(func() {
+		close(f.closeChan)
+		err = f.PacketConn.Close()
+	})
+	return err
+}
+
+//read packet
+func (f *ImpairConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		//check deadline
+		deadline, _ := f.readDeadline.Load().(time.Time)
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return 0, nil, os.ErrDeadlineExceeded
+		}
+		p, changed := f.waitPacket(deadline)
+		if changed {
+			//deadline changed or reached, check again
+			continue
+		}
+		if p == nil {
+			return 0, nil, net.ErrClosed
+		}
+		if p.err != nil {
+			return 0, nil, p.err
+		}
+		return copy(b, p.data), p.addr, nil
+	}
+}
+
+//write packet
+func (f *ImpairConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	cfg, _ := f.conf.Load().(*conf.ImpairConf)
+	if cfg == nil {
+		return f.PacketConn.WriteTo(b, addr)
+	}
+	atomic.AddUint64(&f.stats.Sent, 1)
+	delay, drop := f.impair(cfg)
+	if drop {
+		return len(b), nil
+	}
+	data := make([]byte, len(b))
+	copy(data, b)
+	f.sendQueue.push(&impairPacket{data:data, addr:addr, due:time.Now().Add(delay)})
+	return len(b), nil
+}
+
+//set deadline, read deadline only apply on impaired read
+func (f *ImpairConn) SetDeadline(t time.Time) error {
+	f.SetReadDeadline(t)
+	return f.PacketConn.SetWriteDeadline(t)
+}
+
+func (f *ImpairConn) SetReadDeadline(t time.Time) error {
+	f.readDeadline.Store(t)
+	select {
+	case f.deadlineChan <- true:
+	default:
+	}
+	return nil
+}
+
+//////////////
+//private func
+//////////////
+
+//wait packet until deadline
+//return nil packet if closed, or true if deadline changed or reached.
+func (f *ImpairConn) waitPacket(deadline time.Time) (*impairPacket, bool) {
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case p := <- f.readChan:
+		return p, false
+	case <- timeout:
+		return nil, true
+	case <- f.deadlineChan:
+		return nil, true
+	case <- f.closeChan:
+		return nil, false
+	}
+}
+
+//read raw packets and impair them
+func (f *ImpairConn) readLoop() {
+	for {
+		buff := make([]byte, impairReadBuffSize)
+		n, addr, err := f.PacketConn.ReadFrom(buff)
+		if err != nil {
+			select {
+			case f.readChan <- &impairPacket{err:err}:
+			case <- f.closeChan:
+			}
+			return
+		}
+
+		//pass through
+		cfg, _ := f.conf.Load().(*conf.ImpairConf)
+		if cfg == nil {
+			f.recvQueue.push(&impairPacket{data:buff[:n], addr:addr, due:time.Now()})
+			continue
+		}
+
+		//impair
+		atomic.AddUint64(&f.stats.Received, 1)
+		delay, drop := f.impair(cfg)
+		if drop {
+			continue
+		}
+		f.recvQueue.push(&impairPacket{data:buff[:n], addr:addr, due:time.Now().Add(delay)})
+	}
+}
+
+//get delay of packet, or drop it
+func (f *ImpairConn) impair(cfg *conf.ImpairConf) (time.Duration, bool) {
+	f.randLock.Lock()
+	defer f.randLock.Unlock()
+
+	//loss
+	if cfg.Loss > 0 && f.rand.Float64() < cfg.Loss {
+		atomic.AddUint64(&f.stats.Dropped, 1)
+		return 0, true
+	}
+
+	//delay and jitter
+	delay := cfg.Delay
+	if cfg.Jitter > 0 {
+		delay += f.rand.Intn(cfg.Jitter * 2 + 1) - cfg.Jitter
+	}
+	if delay < 0 {
+		delay = 0
+	}
+
+	//reorder, hold packet longer than followers
+	if cfg.Reorder > 0 && f.rand.Float64() < cfg.Reorder {
+		atomic.AddUint64(&f.stats.Reordered, 1)
+		if cfg.ReorderDelay > 0 {
+			delay += cfg.ReorderDelay
+		}else{
+			delay += impairReorderDelay
+		}
+	}
+	return time.Duration(delay) * time.Millisecond, false
+}
+
+////////////////////
+//delay queue
+////////////////////
+
+//delayed packet
+type impairPacket struct {
+	data []byte
+	addr net.Addr
+	err  error
+	due  time.Time
+	seq  uint64
+}
+
+//packet heap, order by due time and seq
+type impairHeap []*impairPacket
+
+func (h impairHeap) Len() int { return len(h) }
+func (h impairHeap) Less(i, j int) bool {
+	if h[i].due.Equal(h[j].due) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].due.Before(h[j].due)
+}
+func (h impairHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *impairHeap) Push(x interface{}) { *h = append(*h, x.(*impairPacket)) }
+func (h *impairHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	p := old[n - 1]
+	old[n - 1] = nil
+	*h = old[:n - 1]
+	return p
+}
+
+//queue release packet when due
+type impairQueue struct {
+	packets   impairHeap
+	seq       uint64
+	out       func(*impairPacket)
+	wakeChan  chan bool
+	closeChan chan bool
+	sync.Mutex
+}
+
+func newImpairQueue(closeChan chan bool, out func(*impairPacket)) *impairQueue {
+	this := &impairQueue{
+		packets:make(impairHeap, 0),
+		out:out,
+		wakeChan:make(chan bool, 1),
+		closeChan:closeChan,
+	}
+	return this
+}
+
+//push packet
+func (f *impairQueue) push(p *impairPacket) {
+	f.Lock()
+	f.seq++
+	p.seq = f.seq
+	heap.Push(&f.packets, p)
+	f.Unlock()
+	select {
+	case f.wakeChan <- true:
+	default:
+	}
+}
+
+//release due packets
+func (f *impairQueue) run() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		//pop due packets
+		var wait time.Duration = -1
+		for {
+			f.Lock()
+			if f.packets.Len() <= 0 {
+				f.Unlock()
+				break
+			}
+			p := f.packets[0]
+			if wait = time.Until(p.due); wait > 0 {
+				f.Unlock()
+				break
+			}
+			heap.Pop(&f.packets)
+			f.Unlock()
+			f.out(p)
+			wait = -1
+		}
+
+		//wait next due or new packet
+		var timeout <-chan time.Time
+		if wait > 0 {
+			if !timer.Stop() {
+				select {
+				case <- timer.C:
+				default:
+				}
+			}
+			timer.Reset(wait)
+			timeout = timer.C
+		}
+		select {
+		case <- f.wakeChan:
+		case <- timeout:
+		case <- f.closeChan:
+			return
+		}
+	}
+}
@@ -1,14 +1,16 @@
 package network
 
 import (
-	"crypto/sha1"
+	"context"
+	"github.com/andyzhou/thorn/conf"
 	"github.com/andyzhou/thorn/define"
 	"github.com/andyzhou/thorn/iface"
 	"github.com/andyzhou/thorn/protocol"
 	"github.com/xtaci/kcp-go"
-	"golang.org/x/crypto/pbkdf2"
 	"log"
+	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,16 +20,21 @@ import (
 
 //face info
 type KcpServer struct {
-	address  string //like ':10086'
-	password string
-	salt     string
-	cb       iface.IConnCallBack
-	router   iface.IConnCallBack
-	protocol iface.IProtocol
-	config   iface.IConfig
-	listener *kcp.Listener
-	manager  iface.IManager
-	needQuit bool
+	conf            *conf.KcpConf
+	cb              iface.IConnCallBack
+	router          iface.IConnCallBack
+	protocol        iface.IProtocol
+	config          atomic.Value     //iface.IConfig, swap on reload
+	listener        *kcp.Listener
+	packetConn      *PacketConn      //wrap udp conn if upgrade or session stats enabled
+	impairConn      *ImpairConn      //wrap udp conn if impair enabled
+	upgradeListener *net.UnixListener
+	upgradeChan     chan bool        //closed after udp socket passed to new process
+	manager         iface.IManager
+	conns           sync.Map         //*Conn -> bool
+	connWg          sync.WaitGroup   //wait all conn process exit
+	needQuit        bool             //refuse new connect
+	closing         bool             //quit or shutdown called
 	sync.RWMutex
 }
 
@@ -37,16 +44,29 @@ func NewKcpServer(
 		password,
 		salt string,
 	) *KcpServer {
+	return NewKcpServerWithConf(&conf.KcpConf{
+		Address:address,
+		Password:password,
+		Salt:salt,
+	})
+}
+
+//construct with conf
+func NewKcpServerWithConf(cfg *conf.KcpConf) *KcpServer {
+	return NewKcpServerWithClock(cfg, nil)
+}
+
+//construct with clock, real clock if nil
+func NewKcpServerWithClock(cfg *conf.KcpConf, clk iface.IClock) *KcpServer {
 	//init manager
-	manager := NewManager()
+	manager := NewManagerWithClock(clk)
 
 	//self init
 	this := &KcpServer{
-		address:address,
-		password:password,
-		salt:salt,
+		conf:cfg,
 		protocol:protocol.NewProtocol(),
 		listener:new(kcp.Listener),
+		upgradeChan:make(chan bool),
 		manager:manager,
 		router:NewRouter(manager),
 	}
@@ -60,14 +80,69 @@ func NewKcpServer(
 	return this
 }
 
-//stop
+//stop, safe to call more times or after Shutdown
 func (f *KcpServer) Quit() {
 	f.manager.Close()
 	f.Lock()
 	defer f.Unlock()
 	f.needQuit = true
-	f.listener.Close()
-	f.listener = nil
+	f.closing = true
+	if f.listener != nil {
+		f.listener.Close()
+		f.listener = nil
+	}
+	if f.upgradeListener != nil {
+		f.upgradeListener.Close()
+		f.upgradeListener = nil
+	}
+}
+
+//get chan closed after upgrade handoff
+//old process should shutdown after all rooms finished
+func (f *KcpServer) Upgraded() <-chan bool {
+	return f.upgradeChan
+}
+
+//graceful shutdown
+//refuse new connect, drain rooms, then flush and close
+//all connects, wait their process exit and close listener.
+func (f *KcpServer) Shutdown(ctx context.Context) error {
+	//refuse new connect
+	//keep listener until all connects closed
+	f.Lock()
+	f.needQuit = true
+	f.closing = true
+	f.Unlock()
+
+	//drain rooms, close message sent by rooms
+	err := f.manager.Shutdown(ctx)
+
+	//flush and close all connects
+	sf := func(k, v interface{}) bool {
+		conn, ok := k.(*Conn)
+		if ok && conn != nil {
+			conn.Flush(define.ConnFlushTimeout)
+			conn.Close()
+		}
+		return true
+	}
+	f.conns.Range(sf)
+
+	//wait all connect process exit
+	f.connWg.Wait()
+
+	//close listener
+	f.Lock()
+	if f.listener != nil {
+		f.listener.Close()
+		f.listener = nil
+	}
+	if f.upgradeListener != nil {
+		f.upgradeListener.Close()
+		f.upgradeListener = nil
+	}
+	f.Unlock()
+	return err
 }
 
 //get router
@@ -85,9 +160,15 @@ func (f *KcpServer) GetProtocol() iface.IProtocol {
 	return f.protocol
 }
 
+//get clock
+func (f *KcpServer) GetClock() iface.IClock {
+	return f.manager.GetClock()
+}
+
 //get config
 func (f *KcpServer) GetConfig() iface.IConfig {
-	return f.config
+	config, _ := f.config.Load().(iface.IConfig)
+	return config
 }
 
 //set room callback
@@ -104,7 +185,17 @@ func (f *KcpServer) SetConfig(config iface.IConfig) bool {
 	if config == nil {
 		return false
 	}
-	f.config = config
+	f.config.Store(config)
+	return true
+}
+
+//set network impairment, nil means pass through
+//only work if impair enabled by conf at startup.
+func (f *KcpServer) SetImpair(cfg *conf.ImpairConf) bool {
+	if f.impairConn == nil {
+		return false
+	}
+	f.impairConn.SetImpair(cfg)
 	return true
 }
 
@@ -117,9 +208,6 @@ func (f *KcpServer) runMainProcess() {
 	var (
 		m any = nil
 	)
-	if f.listener == nil {
-		return
-	}
 	//defer
 	defer func() {
 		if err := recover(); err != m {
@@ -127,72 +215,166 @@ func (f *KcpServer) runMainProcess() {
 		}
 	}()
 
-	//loop
+	//loop, listener cleared by Quit or Shutdown
 	for {
-		if f.needQuit {
+		f.RLock()
+		listener := f.listener
+		f.RUnlock()
+		if listener == nil {
 			break
 		}
 		//accept new connect
-		sess, err := f.listener.AcceptKCP()
+		sess, err := listener.AcceptKCP()
 		if err != nil {
 			log.Println("Server accept failed, err:", err)
 			continue
 		}
 
+		//refuse new connect if shutting down
+		f.RLock()
+		needQuit := f.needQuit
+		f.RUnlock()
+		if needQuit {
+			sess.Close()
+			continue
+		}
+
 		//set upd mode
 		f.setUdpMode(sess)
 
 		//new udp connect
 		conn := NewConn(sess, f)
+		if f.packetConn != nil {
+			conn.segmentStats = f.packetConn.trackSession(sess.GetConv())
+		}
 		if f.cb != nil {
 			conn.SetCallBack(f.cb)
 		}
 		conn.Do()
+		f.trackConn(conn)
 	}
 }
 
+//track connect until its process exit
+func (f *KcpServer) trackConn(conn *Conn) {
+	f.conns.Store(conn, true)
+	f.connWg.Add(1)
+	go func() {
+		conn.Wait()
+		if f.packetConn != nil {
+			f.packetConn.untrackSession(conn.conn.GetConv(), conn.segmentStats)
+		}
+		f.conns.Delete(conn)
+		f.connWg.Done()
+	}()
+}
+
+//get conversation ids of all sessions
+func (f *KcpServer) getConvs() []uint32 {
+	convs := make([]uint32, 0)
+	sf := func(k, v interface{}) bool {
+		conn, ok := k.(*Conn)
+		if ok && conn != nil && !conn.IsClosed() {
+			convs = append(convs, conn.conn.GetConv())
+		}
+		return true
+	}
+	f.conns.Range(sf)
+	return convs
+}
+
 //set udp mode
 func (f *KcpServer) setUdpMode(session *kcp.UDPSession) bool {
 	if session == nil {
 		return false
 	}
-	session.SetNoDelay(1, 10, 2, 1)
+	tune := f.GetConfig().GetKcpTune()
+	session.SetNoDelay(tune.NoDelay, tune.Interval, tune.Resend, tune.NoCongestion)
 	session.SetStreamMode(true)
-	session.SetWindowSize(4096, 4096)
-	session.SetReadBuffer(4 * 1024 * 1024)
-	session.SetWriteBuffer(4 * 1024 * 1024)
-	session.SetACKNoDelay(true)
+	session.SetWindowSize(tune.SndWnd, tune.RcvWnd)
+	session.SetReadBuffer(tune.ReadBuffer)
+	session.SetWriteBuffer(tune.WriteBuffer)
+	session.SetACKNoDelay(tune.AckNoDelay)
 	return true
 }
 
 //inter init
 func (f *KcpServer) interInit() {
-	//init AES key
-	key := pbkdf2.Key(
-					[]byte(f.password),
-					[]byte(f.salt),
-					1024,
-					32,
-					sha1.New,
-				)
-	block, err := kcp.NewAESBlockCrypt(key)
+	var (
+		udpConn net.PacketConn
+		relayConn net.Conn
+		oldConvs []uint32
+		oldRooms []uint64
+	)
+
+	//init block crypt
+	block, err := NewBlockCrypt(f.conf.Crypt, f.conf.Password, f.conf.Salt)
 	if err != nil {
-		log.Println("kcpServer.interInit, init AES failed, err:", err.Error())
+		log.Println("kcpServer.interInit, init crypt failed, err:", err.Error())
 		panic(any(err))
-		return
+	}
+
+	//try inherit udp socket from old process
+	if f.conf.UpgradeSock != "" {
+		udpConn, relayConn, oldConvs, oldRooms, err = inheritPacketConn(f.conf.UpgradeSock)
+		if err != nil {
+			log.Println("kcpServer.interInit, inherit udp socket failed, err:", err.Error())
+		}
+		if udpConn != nil {
+			log.Printf("kcpServer.interInit, inherit udp socket, old sessions:%d, old rooms:%d\n",
+						len(oldConvs), len(oldRooms))
+		}
+	}
+
+	//init udp socket
+	if udpConn == nil {
+		udpAddr, subErr := net.ResolveUDPAddr("udp", f.conf.Address)
+		if subErr != nil {
+			log.Println("kcpServer.interInit, resolve address failed, err:", subErr.Error())
+			panic(any(subErr))
+		}
+		udpConn, err = net.ListenUDP("udp", udpAddr)
+		if err != nil {
+			log.Println("kcpServer.interInit, init udp failed, err:", err.Error())
+			panic(any(err))
+		}
+	}
+	//wrap network impairment, not work with upgrade
+	if f.conf.Impair != nil {
+		if f.conf.UpgradeSock != "" {
+			log.Println("kcpServer.interInit, impair disabled as upgrade enabled")
+		}else{
+			f.impairConn = NewImpairConn(udpConn, f.conf.Impair)
+			udpConn = f.impairConn
+		}
+	}
+	//wrap udp conn only if needed, raw udp conn keeps batch io of kcp-go
+	if f.conf.UpgradeSock != "" || f.conf.SessionStats {
+		f.packetConn = NewPacketConn(udpConn, block, true)
+		f.packetConn.SetSessionStats(f.conf.SessionStats)
+		if relayConn != nil {
+			f.packetConn.SetRelayOut(relayConn, oldConvs, oldRooms)
+		}
+		udpConn = f.packetConn
 	}
 
 	//init kcp listener, udp protocol
-	f.listener, err = kcp.ListenWithOptions(
-							f.address,
+	f.listener, err = kcp.ServeConn(
 							block,
-							10,
-							3,
+							kcpDataShards,
+							kcpParityShards,
+							udpConn,
 						)
 	if err != nil {
 		log.Println("kcpServer.interInit, init kcp failed, err:", err.Error())
 		panic(any(err))
-		return
+	}
+
+	//wait new process for upgrade
+	if f.conf.UpgradeSock != "" {
+		if err = f.serveUpgrade(); err != nil {
+			log.Println("kcpServer.interInit, serve upgrade failed, err:", err.Error())
+		}
 	}
 
 	//init chan limit
@@ -200,10 +382,10 @@ func (f *KcpServer) interInit() {
 	timeOut := time.Second * define.DefaultTimeOut
 
 	//init default config
-	f.config = protocol.NewConfig(
+	f.config.Store(protocol.NewConfig(
 			packetChanLimit,
 			packetChanLimit,
 			timeOut,
 			timeOut,
-		)
+		))
 }
\ No newline at end of file
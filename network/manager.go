@@ -1,8 +1,11 @@
 package network
 
 import (
+	"context"
+	"github.com/andyzhou/thorn/clock"
 	"github.com/andyzhou/thorn/define"
 	"github.com/andyzhou/thorn/iface"
+	"github.com/andyzhou/thorn/pb"
 	"log"
 	"sync"
 	"sync/atomic"
@@ -18,14 +21,23 @@ import (
 type Manager struct {
 	roomCount int32
 	rooms     sync.Map //roomId -> IRoom
+	closing   int32    //refuse new rooms if 1
+	clock     iface.IClock
 	closeChan chan bool
+	closeOnce sync.Once
 }
 
 //construct
 func NewManager() *Manager {
+	return NewManagerWithClock(nil)
+}
+
+//construct with clock, real clock if nil
+func NewManagerWithClock(clk iface.IClock) *Manager {
 	//self init
 	this := &Manager{
 		rooms:sync.Map{},
+		clock:clock.Or(clk),
 		roomCount:0,
 		closeChan:make(chan bool, 1),
 	}
@@ -46,8 +58,12 @@ func (f *Manager) Close() {
 		}
 	}()
 
+	//refuse new rooms
+	atomic.StoreInt32(&f.closing, 1)
 	if f.closeChan != nil {
-		f.closeChan <- true
+		f.closeOnce.Do(func() {
+			f.closeChan <- true
+		})
 	}
 	if f.roomCount <= 0 {
 		return
@@ -63,6 +79,55 @@ func (f *Manager) Close() {
 	f.rooms.Range(sf)
 }
 
+//graceful shutdown
+//refuse new rooms, wait running games finish or ctx done,
+//then stop all rooms and wait their main process exit.
+//safe to call more times, later calls return nil.
+func (f *Manager) Shutdown(ctx context.Context) error {
+	var (
+		err error
+	)
+	//refuse new rooms
+	if !atomic.CompareAndSwapInt32(&f.closing, 0, 1) {
+		return nil
+	}
+
+	//wait running games finish
+	ticker := time.NewTicker(define.ShutdownCheckRate)
+	defer ticker.Stop()
+	for f.getActiveRooms() > 0 {
+		select {
+		case <- ctx.Done():
+			err = ctx.Err()
+		case <- ticker.C:
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	//stop all rooms with shutdown reason
+	rooms := make([]iface.IRoom, 0)
+	sf := func(k, v interface{}) bool {
+		room, ok := v.(iface.IRoom)
+		if ok && room != nil {
+			room.StopWithReason(pb.CLOSE_REASON_CLOSE_Shutdown)
+			rooms = append(rooms, room)
+		}
+		return true
+	}
+	f.rooms.Range(sf)
+
+	//wait rooms main process exit
+	for _, room := range rooms {
+		room.Wait()
+	}
+
+	//stop main process
+	f.Close()
+	return err
+}
+
 //get rooms
 func (f *Manager) GetRooms() int32 {
 	return f.roomCount
@@ -81,6 +146,19 @@ func (f *Manager) CloseRoom(id uint64) bool {
 	return true
 }
 
+//get ids of all rooms
+func (f *Manager) GetRoomIds() []uint64 {
+	ids := make([]uint64, 0)
+	sf := func(k, v interface{}) bool {
+		if id, ok := k.(uint64); ok {
+			ids = append(ids, id)
+		}
+		return true
+	}
+	f.rooms.Range(sf)
+	return ids
+}
+
 //get room
 func (f *Manager) GetRoom(id uint64) iface.IRoom {
 	//basic check
@@ -105,12 +183,21 @@ func (f *Manager) AddRoom(room iface.IRoom) bool {
 	if room == nil || room.GetId() <= 0 {
 		return false
 	}
+	//check closing
+	if atomic.LoadInt32(&f.closing) == 1 {
+		return false
+	}
 	//sync into map
 	f.rooms.Store(room.GetId(), room)
 	atomic.AddInt32(&f.roomCount, 1)
 	return true
 }
 
+//get clock
+func (f *Manager) GetClock() iface.IClock {
+	return f.clock
+}
+
 //////////////
 //private func
 //////////////
@@ -118,7 +205,7 @@ func (f *Manager) AddRoom(room iface.IRoom) bool {
 //run main process
 func (f *Manager) runMainProcess() {
 	var (
-		timer = time.NewTicker(time.Second * define.RoomCheckRate)
+		timer = f.clock.NewTicker(time.Second * time.Duration(define.RoomCheckRate))
 		m any = nil
 	)
 
@@ -135,7 +222,7 @@ func (f *Manager) runMainProcess() {
 	//loop
 	for {
 		select {
-		case <- timer.C:
+		case <- timer.C():
 			{
 				//clean up rooms
 				f.cleanUpRooms()
@@ -156,6 +243,7 @@ func (f *Manager) cleanUpRooms() {
 		if ok && room != nil {
 			if room.IsOver() {
 				//clean up
+				room.Stop()
 				f.rooms.Delete(k)
 				if f.roomCount > 0 {
 					atomic.AddInt32(&f.roomCount, -1)
@@ -166,3 +254,17 @@ func (f *Manager) cleanUpRooms() {
 	}
 	f.rooms.Range(sf)
 }
+
+//get count of rooms which game not over
+func (f *Manager) getActiveRooms() int {
+	num := 0
+	sf := func(k, v interface{}) bool {
+		room, ok := v.(iface.IRoom)
+		if ok && room != nil && !room.IsOver() {
+			num++
+		}
+		return true
+	}
+	f.rooms.Range(sf)
+	return num
+}
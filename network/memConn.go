@@ -0,0 +1,161 @@
+package network
+
+import (
+	"github.com/andyzhou/thorn/clock"
+	"github.com/andyzhou/thorn/define"
+	"github.com/andyzhou/thorn/iface"
+	"net"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * in-memory connect face, implement of IConn
+ * - no socket, packets from client deliver to router and callback directly
+ * - packets to client are kept in memory, for test
+ */
+
+//face info
+type MemConn struct {
+	router     iface.IConnCallBack //room router
+	callback   iface.IConnCallBack //connect cb interface, room after connected
+	extraData  interface{}
+	activeTime int64
+	clock      iface.IClock
+	closeFlag  int32
+	closeOnce  sync.Once
+	packets    []iface.IPacket     //packets written to client
+	packetsIn  uint64              //packets from client
+	packetsOut uint64              //packets to client
+	sync.Mutex
+}
+
+//construct, real clock if clk is nil
+func NewMemConn(router iface.IConnCallBack, clk iface.IClock) *MemConn {
+	//self init
+	clk = clock.Or(clk)
+	this := &MemConn{
+		router:router,
+		clock:clk,
+		activeTime:clk.Now().Unix(),
+		packets:make([]iface.IPacket, 0),
+	}
+	return this
+}
+
+//close
+func (f *MemConn) Close() {
+	f.closeOnce.Do(func() {
+		atomic.StoreInt32(&f.closeFlag, 1)
+		f.Lock()
+		callback := f.callback
+		f.Unlock()
+		if callback != nil {
+			callback.OnClose(f)
+		}
+	})
+}
+
+//check is closed
+func (f *MemConn) IsClosed() bool {
+	return atomic.LoadInt32(&f.closeFlag) == 1
+}
+
+//do it, notify router
+func (f *MemConn) Do() {
+	if f.router != nil && !reflect.ValueOf(f.router).IsNil() {
+		if !f.router.OnConnect(f) {
+			f.Close()
+		}
+	}
+}
+
+//write packet to client side, keep in memory
+func (f *MemConn) AsyncWritePacket(packet iface.IPacket, duration time.Duration) error {
+	if f.IsClosed() {
+		return define.ErrConnClosing
+	}
+	f.Lock()
+	defer f.Unlock()
+	f.packets = append(f.packets, packet)
+	atomic.AddUint64(&f.packetsOut, 1)
+	return nil
+}
+
+//packets kept in memory, no wait
+func (f *MemConn) Flush(timeout time.Duration) bool {
+	return true
+}
+
+//deliver packet from client side to router and callback
+func (f *MemConn) Deliver(packet iface.IPacket) error {
+	if f.IsClosed() {
+		return define.ErrConnClosing
+	}
+	atomic.AddUint64(&f.packetsIn, 1)
+	if f.router != nil {
+		f.router.OnMessage(f, packet)
+	}
+	f.Lock()
+	callback := f.callback
+	f.Unlock()
+	if callback != nil {
+		callback.OnMessage(f, packet)
+	}
+	atomic.StoreInt64(&f.activeTime, f.clock.Now().Unix())
+	return nil
+}
+
+//take all packets written to client side
+func (f *MemConn) TakePackets() []iface.IPacket {
+	f.Lock()
+	defer f.Unlock()
+	packets := f.packets
+	f.packets = make([]iface.IPacket, 0)
+	return packets
+}
+
+//get extra data
+func (f *MemConn) GetExtraData() interface{} {
+	f.Lock()
+	defer f.Unlock()
+	return f.extraData
+}
+
+//set extra data
+func (f *MemConn) SetExtraData(data interface{}) bool {
+	f.Lock()
+	defer f.Unlock()
+	f.extraData = data
+	return true
+}
+
+//get last active time
+func (f *MemConn) GetActiveTime() int64 {
+	return atomic.LoadInt64(&f.activeTime)
+}
+
+//get packet stats, no pending as written in memory
+func (f *MemConn) GetStats() iface.ConnStats {
+	return iface.ConnStats{
+		PacketsIn:atomic.LoadUint64(&f.packetsIn),
+		PacketsOut:atomic.LoadUint64(&f.packetsOut),
+	}
+}
+
+//get raw connect, always nil
+func (f *MemConn) GetRawConn() net.Conn {
+	return nil
+}
+
+//set connect call back
+func (f *MemConn) SetCallBack(cb iface.IConnCallBack) {
+	if cb == nil {
+		return
+	}
+	f.Lock()
+	defer f.Unlock()
+	f.callback = cb
+}
@@ -0,0 +1,501 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/andyzhou/thorn/pb"
+	"github.com/andyzhou/thorn/protocol"
+	"github.com/xtaci/kcp-go"
+	"hash/crc32"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+ * udp packet conn face, implement of net.PacketConn
+ * - wrap raw udp conn for kcp listener
+ * - relay packets of old sessions between processes on upgrade,
+ *   new session connect to old room relayed too
+ * - count segments and fec recovery per session, opt-in as packets decrypted again
+ */
+
+//inter macro define, same as kcp-go
+const (
+	kcpNonceSize      = 16
+	kcpCrcSize        = 4
+	kcpFecHeaderSize  = 6
+	kcpFecTypeData    = 0xf1
+	kcpMaxPacketSize  = 1500
+	kcpOverhead       = 24
+	kcpCmdPush        = 81
+	kcpDataShards     = 10 //fec shards of server sessions
+	kcpParityShards   = 3
+	relayReadBuffSize = 2048
+)
+
+//segment and fec stats of one session
+type sessionStats struct {
+	segments    uint64 //push segments sent
+	retransmits uint64 //push segments sent again
+	parity      uint64 //fec parity packets sent
+	recovered   uint64 //data shards received recovered by fec
+	nextSn      uint32 //next sn never sent
+	fecGroup    uint32 //current fec group received
+	fecData     int    //data shards received of group
+	fecParity   int    //parity shards received of group
+	fecDone     bool   //group recovered or complete
+	sync.Mutex         //for fec group
+}
+
+//face info
+type PacketConn struct {
+	net.PacketConn                 //raw udp conn
+	block          kcp.BlockCrypt  //for decode conversation id
+	fec            bool            //fec enabled or not
+	relayIn        atomic.Value    //net.Conn, read packets from relay if set
+	relayOut       net.Conn        //forward packets of old sessions
+	oldConvs       map[uint32]bool //conversation ids of old process
+	oldRooms       map[uint64]bool //room ids of old process
+	oldAddrs       sync.Map        //remote addr -> bool, learned from old convs
+	countStats     bool            //count session stats or not
+	stats          sync.Map        //conversation id -> *sessionStats
+	statsAddrs     sync.Map        //remote addr -> *sessionStats, for fec parity
+	statsBuffPool  sync.Pool       //decode buff of counted packets
+	decodeBuff     []byte
+	relayBuff      []byte
+	sync.RWMutex
+}
+
+//construct
+func NewPacketConn(
+		conn net.PacketConn,
+		block kcp.BlockCrypt,
+		fec bool,
+	) *PacketConn {
+	//self init
+	this := &PacketConn{
+		PacketConn:conn,
+		block:block,
+		fec:fec,
+		decodeBuff:make([]byte, kcpMaxPacketSize),
+		relayBuff:make([]byte, relayReadBuffSize),
+	}
+	this.statsBuffPool.New = func() interface{} {
+		return make([]byte, kcpMaxPacketSize)
+	}
+	return this
+}
+
+//close
+func (f *PacketConn) Close() error {
+	if relay, ok := f.relayIn.Load().(net.Conn); ok && relay != nil {
+		relay.Close()
+	}
+	f.Lock()
+	if f.relayOut != nil {
+		f.relayOut.Close()
+		f.relayOut = nil
+	}
+	f.Unlock()
+	return f.PacketConn.Close()
+}
+
+//read packet, skip packets belong to old process
+func (f *PacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		//read from relay
+		if relay, ok := f.relayIn.Load().(net.Conn); ok && relay != nil {
+			return f.readRelay(relay, b)
+		}
+
+		//read from raw udp conn
+		n, addr, err := f.PacketConn.ReadFrom(b)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() && f.isRelayIn() {
+				//switched to relay
+				continue
+			}
+			return n, addr, err
+		}
+
+		//check old session
+		if f.isOldSession(b[:n], addr) {
+			f.forward(b[:n], addr)
+			continue
+		}
+		if f.countStats {
+			f.countRead(b[:n], addr)
+		}
+		return n, addr, nil
+	}
+}
+
+//write packet, count segments of tracked sessions
+func (f *PacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	n, err := f.PacketConn.WriteTo(b, addr)
+	if err == nil && f.countStats {
+		f.countWritten(b, addr)
+	}
+	return n, err
+}
+
+//count segments and fec recovery of sessions, call before serve
+//every packet decrypted again, so it costs crypto of all packets.
+func (f *PacketConn) SetSessionStats(enable bool) {
+	f.countStats = enable
+}
+
+//switch read source to relay, for old process after handoff
+func (f *PacketConn) SwitchToRelay(relay net.Conn) {
+	if relay == nil {
+		return
+	}
+	f.relayIn.Store(relay)
+	//wake up blocked read
+	f.PacketConn.SetReadDeadline(time.Now())
+}
+
+//set relay for old sessions and rooms, for new process after handoff
+func (f *PacketConn) SetRelayOut(relay net.Conn, convs []uint32, rooms []uint64) {
+	if relay == nil || (len(convs) <= 0 && len(rooms) <= 0) {
+		return
+	}
+	oldConvs := make(map[uint32]bool, len(convs))
+	for _, v := range convs {
+		oldConvs[v] = true
+	}
+	oldRooms := make(map[uint64]bool, len(rooms))
+	for _, v := range rooms {
+		oldRooms[v] = true
+	}
+	f.Lock()
+	defer f.Unlock()
+	f.relayOut = relay
+	f.oldConvs = oldConvs
+	f.oldRooms = oldRooms
+}
+
+//get raw udp conn
+func (f *PacketConn) GetRawConn() net.PacketConn {
+	return f.PacketConn
+}
+
+//////////////
+//private func
+//////////////
+
+//start count segments of session, nil if stats off
+func (f *PacketConn) trackSession(conv uint32) *sessionStats {
+	if !f.countStats {
+		return nil
+	}
+	stats := &sessionStats{}
+	f.stats.Store(conv, stats)
+	return stats
+}
+
+//stop count segments of session
+func (f *PacketConn) untrackSession(conv uint32, stats *sessionStats) {
+	if stats == nil {
+		return
+	}
+	if v, ok := f.stats.Load(conv); ok && v == stats {
+		f.stats.Delete(conv)
+	}
+	sf := func(k, v interface{}) bool {
+		if v == stats {
+			f.statsAddrs.Delete(k)
+		}
+		return true
+	}
+	f.statsAddrs.Range(sf)
+}
+
+//check is relay in mode
+func (f *PacketConn) isRelayIn() bool {
+	relay, ok := f.relayIn.Load().(net.Conn)
+	return ok && relay != nil
+}
+
+//read one relayed packet
+//format: |--addrLen(uint8)--|--addr--|--packet--|
+func (f *PacketConn) readRelay(relay net.Conn, b []byte) (int, net.Addr, error) {
+	for {
+		n, err := relay.Read(f.relayBuff)
+		if err != nil {
+			return 0, nil, err
+		}
+		if n < 1 || n < 1 + int(f.relayBuff[0]) {
+			continue
+		}
+		addrLen := int(f.relayBuff[0])
+		addr, err := net.ResolveUDPAddr("udp", string(f.relayBuff[1:1 + addrLen]))
+		if err != nil {
+			continue
+		}
+		return copy(b, f.relayBuff[1 + addrLen:n]), addr, nil
+	}
+}
+
+//forward packet to old process
+func (f *PacketConn) forward(data []byte, addr net.Addr) {
+	f.RLock()
+	relay := f.relayOut
+	f.RUnlock()
+	if relay == nil {
+		return
+	}
+	addrStr := addr.String()
+	buff := make([]byte, 0, 1 + len(addrStr) + len(data))
+	buff = append(buff, byte(len(addrStr)))
+	buff = append(buff, addrStr...)
+	buff = append(buff, data...)
+	if _, err := relay.Write(buff); err != nil {
+		//old process gone, stop relay
+		log.Println("PacketConn:forward failed, stop relay, err:", err)
+		f.Lock()
+		if f.relayOut != nil {
+			f.relayOut.Close()
+			f.relayOut = nil
+		}
+		f.oldConvs = nil
+		f.oldRooms = nil
+		f.Unlock()
+	}
+}
+
+//check packet belong to old process or not
+//route by kcp conversation id, fec parity packets route by remote addr.
+//new session connect to old room becomes old session, its connect packet must be seen first.
+func (f *PacketConn) isOldSession(data []byte, addr net.Addr) bool {
+	f.RLock()
+	oldConvs := f.oldConvs
+	oldRooms := f.oldRooms
+	f.RUnlock()
+	if len(oldConvs) <= 0 && len(oldRooms) <= 0 {
+		return false
+	}
+
+	//decode conversation id
+	segs, parity, ok := f.decode(f.decodeBuff, data)
+	if !ok || parity || len(segs) < kcpOverhead {
+		_, isOld := f.oldAddrs.Load(addr.String())
+		return isOld
+	}
+	conv := binary.LittleEndian.Uint32(segs)
+	if oldConvs[conv] {
+		f.oldAddrs.Store(addr.String(), true)
+		return true
+	}
+	if roomId, ok := f.decodeConnectRoom(segs); ok && oldRooms[roomId] {
+		f.addOldConv(conv)
+		f.oldAddrs.Store(addr.String(), true)
+		return true
+	}
+	f.oldAddrs.Delete(addr.String())
+	return false
+}
+
+//add conversation id of old process, copy on write as read without lock
+func (f *PacketConn) addOldConv(conv uint32) {
+	f.Lock()
+	defer f.Unlock()
+	if f.relayOut == nil {
+		return
+	}
+	oldConvs := make(map[uint32]bool, len(f.oldConvs) + 1)
+	for k := range f.oldConvs {
+		oldConvs[k] = true
+	}
+	oldConvs[conv] = true
+	f.oldConvs = oldConvs
+}
+
+//decode room id of connect message, first packet of session at sn 0
+func (f *PacketConn) decodeConnectRoom(segs []byte) (uint64, bool) {
+	for len(segs) >= kcpOverhead {
+		cmd := segs[4]
+		sn := binary.LittleEndian.Uint32(segs[12:])
+		size := int(binary.LittleEndian.Uint32(segs[20:]))
+		if size < 0 || size > len(segs) - kcpOverhead {
+			return 0, false
+		}
+		if cmd != kcpCmdPush || sn != 0 {
+			segs = segs[kcpOverhead + size:]
+			continue
+		}
+		packet, err := protocol.NewProtocol().ReadPacket(bytes.NewReader(segs[kcpOverhead:kcpOverhead + size]))
+		if err != nil || pb.ID(packet.GetMessageId()) != pb.ID_MSG_Connect {
+			return 0, false
+		}
+		msg := &pb.C2S_ConnectMsg{}
+		if err = packet.UnmarshalPB(msg); err != nil {
+			return 0, false
+		}
+		return msg.GetBattleID(), true
+	}
+	return 0, false
+}
+
+//count push segments and fec parity of written packet
+//push segment with sn already sent is a retransmit.
+func (f *PacketConn) countWritten(data []byte, addr net.Addr) {
+	if len(data) > kcpMaxPacketSize || addr == nil {
+		return
+	}
+	buff := f.statsBuffPool.Get().([]byte)
+	defer f.statsBuffPool.Put(buff)
+	segs, parity, ok := f.decode(buff, data)
+	if !ok {
+		return
+	}
+
+	//parity packet, route by remote addr
+	if parity {
+		if v, ok := f.statsAddrs.Load(addr.String()); ok {
+			atomic.AddUint64(&v.(*sessionStats).parity, 1)
+		}
+		return
+	}
+	if len(segs) < kcpOverhead {
+		return
+	}
+	v, ok := f.stats.Load(binary.LittleEndian.Uint32(segs))
+	if !ok {
+		return
+	}
+	stats := v.(*sessionStats)
+	f.statsAddrs.Store(addr.String(), stats)
+
+	//walk segments
+	for len(segs) >= kcpOverhead {
+		cmd := segs[4]
+		sn := binary.LittleEndian.Uint32(segs[12:])
+		size := int(binary.LittleEndian.Uint32(segs[20:]))
+		if size < 0 || size > len(segs) - kcpOverhead {
+			return
+		}
+		segs = segs[kcpOverhead + size:]
+		if cmd != kcpCmdPush {
+			continue
+		}
+		atomic.AddUint64(&stats.segments, 1)
+		nextSn := atomic.LoadUint32(&stats.nextSn)
+		if sn < nextSn {
+			atomic.AddUint64(&stats.retransmits, 1)
+		}else{
+			atomic.StoreUint32(&stats.nextSn, sn + 1)
+		}
+	}
+}
+
+//count fec shards of read packet
+//group recovered when shards received reach data shards with some data shard lost, like kcp-go.
+func (f *PacketConn) countRead(data []byte, addr net.Addr) {
+	if len(data) > kcpMaxPacketSize || addr == nil || !f.fec {
+		return
+	}
+	buff := f.statsBuffPool.Get().([]byte)
+	defer f.statsBuffPool.Put(buff)
+	header, parity, ok := f.decodeFec(buff, data)
+	if !ok {
+		return
+	}
+
+	//get session, parity packet by remote addr
+	var (
+		v interface{}
+	)
+	if parity {
+		v, ok = f.statsAddrs.Load(addr.String())
+	}else{
+		segs := header[kcpFecHeaderSize + 2:]
+		if len(segs) < kcpOverhead {
+			return
+		}
+		v, ok = f.stats.Load(binary.LittleEndian.Uint32(segs))
+		if ok {
+			f.statsAddrs.Store(addr.String(), v)
+		}
+	}
+	if !ok {
+		return
+	}
+	stats := v.(*sessionStats)
+
+	//count shard in group
+	seqId := binary.LittleEndian.Uint32(header)
+	group := seqId / (kcpDataShards + kcpParityShards)
+	stats.Lock()
+	defer stats.Unlock()
+	if group < stats.fecGroup {
+		return
+	}
+	if group > stats.fecGroup {
+		stats.fecGroup = group
+		stats.fecData = 0
+		stats.fecParity = 0
+		stats.fecDone = false
+	}
+	if stats.fecDone {
+		return
+	}
+	if parity {
+		stats.fecParity++
+	}else{
+		stats.fecData++
+	}
+	if stats.fecData + stats.fecParity >= kcpDataShards {
+		stats.fecDone = true
+		atomic.AddUint64(&stats.recovered, uint64(kcpDataShards - stats.fecData))
+	}
+}
+
+//decrypt raw packet into buff and skip fec header
+//return kcp segments, parity packet or not.
+func (f *PacketConn) decode(buff, data []byte) ([]byte, bool, bool) {
+	if !f.fec {
+		buff, ok := f.decrypt(buff, data)
+		return buff, false, ok
+	}
+	buff, parity, ok := f.decodeFec(buff, data)
+	if !ok || parity {
+		return nil, parity, ok
+	}
+	return buff[kcpFecHeaderSize + 2:], false, true
+}
+
+//decrypt raw packet into buff, fec on
+//return packet from fec header, parity packet or not.
+func (f *PacketConn) decodeFec(buff, data []byte) ([]byte, bool, bool) {
+	buff, ok := f.decrypt(buff, data)
+	if !ok || len(buff) < kcpFecHeaderSize + 2 {
+		return nil, false, false
+	}
+	return buff, binary.LittleEndian.Uint16(buff[4:]) != kcpFecTypeData, true
+}
+
+//decrypt raw packet into buff and check crc
+func (f *PacketConn) decrypt(buff, data []byte) ([]byte, bool) {
+	if len(data) > len(buff) {
+		return nil, false
+	}
+	buff = buff[:len(data)]
+	copy(buff, data)
+	if f.block == nil {
+		return buff, true
+	}
+	if len(buff) < kcpNonceSize + kcpCrcSize {
+		return nil, false
+	}
+	f.block.Decrypt(buff, buff)
+	buff = buff[kcpNonceSize:]
+	checksum := crc32.ChecksumIEEE(buff[kcpCrcSize:])
+	if checksum != binary.LittleEndian.Uint32(buff) {
+		return nil, false
+	}
+	return buff[kcpCrcSize:], true
+}
@@ -0,0 +1,245 @@
+package network
+
+import (
+	"encoding/binary"
+	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/pb"
+	"github.com/andyzhou/thorn/protocol"
+	"github.com/xtaci/kcp-go"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//packet conn discards written packets
+type discardConn struct {
+	net.PacketConn
+}
+
+func (f *discardConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return len(b), nil
+}
+
+//build fec data packet of kcp segments
+func fecPacket(conv uint32, cmd uint8, sns ...uint32) []byte {
+	return fecShard(0, conv, cmd, sns...)
+}
+
+//build fec data packet of kcp segments with seq id
+func fecShard(seqId, conv uint32, cmd uint8, sns ...uint32) []byte {
+	buff := make([]byte, kcpFecHeaderSize + 2)
+	binary.LittleEndian.PutUint32(buff, seqId)
+	binary.LittleEndian.PutUint16(buff[4:], kcpFecTypeData)
+	for _, sn := range sns {
+		seg := make([]byte, kcpOverhead + 2)
+		binary.LittleEndian.PutUint32(seg, conv)
+		seg[4] = cmd
+		binary.LittleEndian.PutUint32(seg[12:], sn)
+		binary.LittleEndian.PutUint32(seg[20:], 2)
+		buff = append(buff, seg...)
+	}
+	return buff
+}
+
+//build fec data packet of connect message to room at sn
+func connectPacket(conv, sn uint32, roomId uint64) []byte {
+	data := protocol.NewPacketWithPara(uint8(pb.ID_MSG_Connect), &pb.C2S_ConnectMsg{PlayerID:1, BattleID:roomId}).Pack()
+	buff := make([]byte, kcpFecHeaderSize + 2 + kcpOverhead)
+	binary.LittleEndian.PutUint16(buff[4:], kcpFecTypeData)
+	seg := buff[kcpFecHeaderSize + 2:]
+	binary.LittleEndian.PutUint32(seg, conv)
+	seg[4] = kcpCmdPush
+	binary.LittleEndian.PutUint32(seg[12:], sn)
+	binary.LittleEndian.PutUint32(seg[20:], uint32(len(data)))
+	return append(buff, data...)
+}
+
+//relay conn of old process
+type nopConn struct {
+	net.Conn
+}
+
+func TestPacketConnRelayOldRoom(t *testing.T) {
+	parity := make([]byte, kcpFecHeaderSize + 2)
+	binary.LittleEndian.PutUint16(parity[4:], kcpFecTypeData + 1)
+	tests := []struct {
+		name    string
+		packets [][]byte
+		old     []bool //each packet belong to old process
+	}{
+		{"old session", [][]byte{fecPacket(1, kcpCmdPush, 0)}, []bool{true}},
+		{"connect old room", [][]byte{connectPacket(2, 0, 7), fecPacket(2, kcpCmdPush, 1), parity},
+			[]bool{true, true, true}},
+		{"connect new room", [][]byte{connectPacket(2, 0, 8), fecPacket(2, kcpCmdPush, 1), parity},
+			[]bool{false, false, false}},
+		{"connect not first packet", [][]byte{connectPacket(2, 3, 7)}, []bool{false}},
+		{"other message first", [][]byte{fecPacket(2, kcpCmdPush, 0), connectPacket(2, 1, 7)}, []bool{false, false}},
+	}
+	addr := &net.UDPAddr{IP:net.IPv4(127, 0, 0, 1), Port:1}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := NewPacketConn(&discardConn{}, nil, true)
+			conn.SetRelayOut(&nopConn{}, []uint32{1}, []uint64{7})
+			for i, packet := range test.packets {
+				if got := conn.isOldSession(packet, addr); got != test.old[i] {
+					t.Fatalf("packet %d old %v, want %v", i, got, test.old[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPacketConnCountWritten(t *testing.T) {
+	parity := make([]byte, kcpFecHeaderSize + 2)
+	binary.LittleEndian.PutUint16(parity[4:], kcpFecTypeData + 1)
+	tests := []struct {
+		name        string
+		packets     [][]byte
+		segments    uint64
+		retransmits uint64
+		parity      uint64
+	}{
+		{"new segments", [][]byte{fecPacket(1, kcpCmdPush, 0, 1), fecPacket(1, kcpCmdPush, 2)}, 3, 0, 0},
+		{"retransmit", [][]byte{fecPacket(1, kcpCmdPush, 0, 1), fecPacket(1, kcpCmdPush, 0)}, 3, 1, 0},
+		{"ack not counted", [][]byte{fecPacket(1, kcpCmdPush + 1, 0, 1)}, 0, 0, 0},
+		{"other session", [][]byte{fecPacket(2, kcpCmdPush, 0)}, 0, 0, 0},
+		{"parity of session addr", [][]byte{fecPacket(1, kcpCmdPush, 0), parity, parity}, 1, 0, 2},
+		{"parity of unknown addr", [][]byte{parity}, 0, 0, 0},
+		{"truncated", [][]byte{fecPacket(1, kcpCmdPush, 0)[:kcpFecHeaderSize + 2 + kcpOverhead]}, 0, 0, 0},
+	}
+	addr := &net.UDPAddr{IP:net.IPv4(127, 0, 0, 1), Port:1}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := NewPacketConn(&discardConn{}, nil, true)
+			conn.SetSessionStats(true)
+			stats := conn.trackSession(1)
+			for _, packet := range test.packets {
+				conn.WriteTo(packet, addr)
+			}
+			if stats.segments != test.segments || stats.retransmits != test.retransmits || stats.parity != test.parity {
+				t.Fatalf("stats %d/%d/%d, want %d/%d/%d", stats.segments, stats.retransmits, stats.parity,
+					test.segments, test.retransmits, test.parity)
+			}
+			conn.untrackSession(1, stats)
+			conn.WriteTo(test.packets[0], addr)
+			if atomic.LoadUint64(&stats.segments) != test.segments {
+				t.Fatal("untracked session still counted")
+			}
+		})
+	}
+}
+
+func TestPacketConnStatsOff(t *testing.T) {
+	conn := NewPacketConn(&discardConn{}, nil, true)
+	if stats := conn.trackSession(1); stats != nil {
+		t.Fatal("session tracked with stats off")
+	}
+	conn.untrackSession(1, nil)
+}
+
+func TestPacketConnCountRecovered(t *testing.T) {
+	//shards of group, data by seq id, parity over data shards
+	shards := func(seqIds ...uint32) [][]byte {
+		packets := make([][]byte, 0)
+		for _, seqId := range seqIds {
+			if seqId % (kcpDataShards + kcpParityShards) < kcpDataShards {
+				packets = append(packets, fecShard(seqId, 1, kcpCmdPush, seqId))
+				continue
+			}
+			parity := make([]byte, kcpFecHeaderSize + 2)
+			binary.LittleEndian.PutUint32(parity, seqId)
+			binary.LittleEndian.PutUint16(parity[4:], kcpFecTypeData + 1)
+			packets = append(packets, parity)
+		}
+		return packets
+	}
+	tests := []struct {
+		name      string
+		packets   [][]byte
+		recovered uint64
+	}{
+		{"no loss", shards(0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 0},
+		{"one data lost", shards(0, 1, 2, 3, 4, 5, 6, 7, 8, 10, 11), 1},
+		{"parity lost", shards(0, 1, 2, 3, 4, 5, 6, 7, 8, 9), 0},
+		{"three data lost", shards(0, 1, 2, 3, 4, 5, 6, 10, 11, 12), 3},
+		{"lost over parity", shards(0, 1, 2, 3, 4, 5, 10, 11, 12), 0},
+		{"next group", shards(0, 1, 2, 3, 4, 5, 6, 7, 8, 13, 10), 0},
+		{"two groups", shards(0, 1, 2, 3, 4, 5, 6, 7, 8, 10, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23), 2},
+		{"parity of unknown addr", shards(10, 11, 12), 0},
+	}
+	addr := &net.UDPAddr{IP:net.IPv4(127, 0, 0, 1), Port:1}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := NewPacketConn(&discardConn{}, nil, true)
+			conn.SetSessionStats(true)
+			stats := conn.trackSession(1)
+			for _, packet := range test.packets {
+				conn.countRead(packet, addr)
+			}
+			if stats.recovered != test.recovered {
+				t.Fatalf("recovered %d, want %d", stats.recovered, test.recovered)
+			}
+		})
+	}
+}
+
+func TestPacketConnCountRetransmitOverLoss(t *testing.T) {
+	block, err := NewBlockCrypt("", "pass", "salt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP:net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	impairConn := NewImpairConn(udpConn, &conf.ImpairConf{Loss:0.2, Seed:1})
+	packetConn := NewPacketConn(impairConn, block, true)
+	packetConn.SetSessionStats(true)
+	listener, err := kcp.ServeConn(block, kcpDataShards, kcpParityShards, packetConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	client, err := kcp.DialWithOptions(udpConn.LocalAddr().String(), block, kcpDataShards, kcpParityShards)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Write([]byte("hello"))
+
+	//server sends, client reads all through lossy link
+	sess, err := listener.AcceptKCP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+	sess.SetNoDelay(1, 10, 2, 1)
+	client.SetNoDelay(1, 10, 2, 1)
+	stats := packetConn.trackSession(sess.GetConv())
+	data := make([]byte, 64 * 1024)
+	go sess.Write(data)
+	client.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if _, err = io.ReadFull(client, make([]byte, len(data))); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadUint64(&stats.segments) == 0 || atomic.LoadUint64(&stats.retransmits) == 0 {
+		t.Fatalf("segments %d, retransmits %d, want both counted",
+			atomic.LoadUint64(&stats.segments), atomic.LoadUint64(&stats.retransmits))
+	}
+	if atomic.LoadUint64(&stats.parity) == 0 {
+		t.Fatal("fec parity not counted")
+	}
+
+	//client sends through lossy link, server recovers some by fec
+	go client.Write(data)
+	sess.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if _, err = io.ReadFull(sess, make([]byte, len(data))); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadUint64(&stats.recovered) == 0 {
+		t.Fatal("fec recovered not counted")
+	}
+}
@@ -61,7 +61,7 @@ func (f *Router) OnMessage(
 
 	case pb.ID_MSG_Heartbeat://heart beat
 		{
-			err = f.writePacket(conn, uint8(pb.ID_MSG_Heartbeat), nil)
+			err = f.processHeartbeat(conn, packet)
 		}
 
 	case pb.ID_MSG_END://end
@@ -155,6 +155,28 @@ func (f *Router) processConnMessage(
 	return nil
 }
 
+//reply heart beat before join room, with clock sync if client time sent
+//heart beat of joined player replied by room.
+func (f *Router) processHeartbeat(
+		conn iface.IConn,
+		packet iface.IPacket,
+	) error {
+	if _, ok := conn.GetExtraData().(uint64); ok {
+		return nil
+	}
+	msg := &pb.C2S_HeartbeatMsg{}
+	if len(packet.GetData()) <= 0 || packet.UnmarshalPB(msg) != nil || msg.GetClientTime() <= 0 {
+		return f.writePacket(conn, uint8(pb.ID_MSG_Heartbeat), nil)
+	}
+	now := f.manager.GetClock().Now().UnixMilli()
+	reply := &pb.S2C_HeartbeatMsg{
+		ClientTime:msg.GetClientTime(),
+		ServerRecvTime:now,
+		ServerSendTime:now,
+	}
+	return f.writePacket(conn, uint8(pb.ID_MSG_Heartbeat), reply)
+}
+
 //async write packet
 func (f *Router) writePacket(
 		conn iface.IConn,
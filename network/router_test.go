@@ -0,0 +1,42 @@
+package network
+
+import (
+	"github.com/andyzhou/thorn/clock"
+	"github.com/andyzhou/thorn/pb"
+	"github.com/andyzhou/thorn/protocol"
+	"testing"
+	"time"
+)
+
+func TestRouterHeartbeat(t *testing.T) {
+	tests := []struct {
+		name      string
+		extraData interface{}
+		msg       *pb.C2S_HeartbeatMsg
+		replies   int
+	}{
+		{"not joined", nil, nil, 1},
+		{"not joined with clock sync", nil, &pb.C2S_HeartbeatMsg{ClientTime:1}, 1},
+		{"joined replied by room", uint64(1), nil, 0},
+		{"joined with clock sync replied by room", uint64(1), &pb.C2S_HeartbeatMsg{ClientTime:1}, 0},
+	}
+	clk := clock.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	manager := NewManagerWithClock(clk)
+	defer manager.Close()
+	router := NewRouter(manager)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := NewMemConn(nil, clk)
+			defer conn.Close()
+			conn.SetExtraData(test.extraData)
+			var msg interface{}
+			if test.msg != nil {
+				msg = test.msg
+			}
+			router.OnMessage(conn, protocol.NewPacketWithPara(uint8(pb.ID_MSG_Heartbeat), msg))
+			if replies := len(conn.TakePackets()); replies != test.replies {
+				t.Fatalf("replies %d, want %d", replies, test.replies)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+//go:build linux
+
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"syscall"
+)
+
+/*
+ * zero downtime upgrade, linux only
+ * - old process listen on unix socket, wait new process
+ * - pass udp socket fd and relay socket fd to new process by SCM_RIGHTS
+ * - new process accept new sessions, relay old sessions by conversation id
+ * - rooms stay in old process, new session connect to old room relayed by room id,
+ *   old process accept relayed sessions only after handoff.
+ */
+
+//inter macro define
+const (
+	upgradeInfoBuffSize = 1024 * 64
+	upgradeAck          = byte(1)
+)
+
+//handoff info
+type upgradeInfo struct {
+	Convs []uint32 `json:"convs"` //conversation ids of old sessions
+	Rooms []uint64 `json:"rooms"` //ids of old rooms
+}
+
+//try inherit udp socket from old process
+//return nil if no old process
+func inheritPacketConn(sockPath string) (net.PacketConn, net.Conn, []uint32, []uint64, error) {
+	//dial old process
+	addr := &net.UnixAddr{Name: sockPath, Net: "unix"}
+	conn, err := net.DialUnix("unix", nil, addr)
+	if err != nil {
+		//no old process
+		return nil, nil, nil, nil, nil
+	}
+	defer conn.Close()
+
+	//receive handoff info and fds
+	buff := make([]byte, upgradeInfoBuffSize)
+	oob := make([]byte, syscall.CmsgSpace(4 * 2))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buff, oob)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) <= 0 {
+		return nil, nil, nil, nil, errors.New("invalid upgrade control message")
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 2 {
+		return nil, nil, nil, nil, errors.New("invalid upgrade fds")
+	}
+
+	//rebuild udp conn and relay conn
+	udpFile := os.NewFile(uintptr(fds[0]), "udp")
+	udpConn, err := net.FilePacketConn(udpFile)
+	udpFile.Close()
+	if err != nil {
+		syscall.Close(fds[1])
+		return nil, nil, nil, nil, err
+	}
+	relayFile := os.NewFile(uintptr(fds[1]), "relay")
+	relayConn, err := net.FileConn(relayFile)
+	relayFile.Close()
+	if err != nil {
+		udpConn.Close()
+		return nil, nil, nil, nil, err
+	}
+
+	//decode info
+	info := &upgradeInfo{}
+	if err = json.Unmarshal(buff[:n], info); err != nil {
+		udpConn.Close()
+		relayConn.Close()
+		return nil, nil, nil, nil, err
+	}
+
+	//ack old process
+	if _, err = conn.Write([]byte{upgradeAck}); err != nil {
+		udpConn.Close()
+		relayConn.Close()
+		return nil, nil, nil, nil, err
+	}
+	return udpConn, relayConn, info.Convs, info.Rooms, nil
+}
+
+//listen unix socket and wait new process for upgrade
+func (f *KcpServer) serveUpgrade() error {
+	//remove old socket file
+	os.Remove(f.conf.UpgradeSock)
+	addr := &net.UnixAddr{Name: f.conf.UpgradeSock, Net: "unix"}
+	ln, err := net.ListenUnix("unix", addr)
+	if err != nil {
+		return err
+	}
+	f.Lock()
+	f.upgradeListener = ln
+	f.Unlock()
+
+	//spawn process
+	go func() {
+		for {
+			conn, err := ln.AcceptUnix()
+			if err != nil {
+				return
+			}
+			err = f.handoff(conn)
+			conn.Close()
+			if err != nil {
+				log.Println("kcpServer.serveUpgrade, handoff failed, err:", err)
+				continue
+			}
+			//new process own the socket file now
+			ln.SetUnlinkOnClose(false)
+			ln.Close()
+			close(f.upgradeChan)
+			return
+		}
+	}()
+	return nil
+}
+
+//pass udp socket to new process
+func (f *KcpServer) handoff(conn *net.UnixConn) error {
+	//get udp socket file
+	if f.packetConn == nil {
+		return errors.New("udp conn not wrapped")
+	}
+	udpConn, ok := f.packetConn.GetRawConn().(*net.UDPConn)
+	if !ok {
+		return errors.New("invalid udp conn")
+	}
+	udpFile, err := udpConn.File()
+	if err != nil {
+		return err
+	}
+	defer udpFile.Close()
+
+	//init relay socket pair
+	pair, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_DGRAM | syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(pair[1])
+	relayFile := os.NewFile(uintptr(pair[0]), "relay")
+	relayConn, err := net.FileConn(relayFile)
+	relayFile.Close()
+	if err != nil {
+		return err
+	}
+
+	//refuse new connect
+	f.Lock()
+	f.needQuit = true
+	f.Unlock()
+
+	//send info and fds
+	info := &upgradeInfo{
+		Convs:f.getConvs(),
+		Rooms:f.manager.GetRoomIds(),
+	}
+	data, _ := json.Marshal(info)
+	rights := syscall.UnixRights(int(udpFile.Fd()), pair[1])
+	if _, _, err = conn.WriteMsgUnix(data, rights, nil); err != nil {
+		f.resumeAccept(relayConn)
+		return err
+	}
+
+	//wait ack
+	ack := make([]byte, 1)
+	if _, err = conn.Read(ack); err != nil || ack[0] != upgradeAck {
+		f.resumeAccept(relayConn)
+		return errors.New("new process not ack")
+	}
+
+	//read old sessions packets from relay
+	//only relayed sessions come in, accept them unless closing
+	f.packetConn.SwitchToRelay(relayConn)
+	f.Lock()
+	f.needQuit = f.closing
+	f.Unlock()
+	log.Printf("kcpServer.handoff, udp socket passed, old sessions:%d\n", len(info.Convs))
+	return nil
+}
+
+//resume accept when handoff failed
+func (f *KcpServer) resumeAccept(relay net.Conn) {
+	relay.Close()
+	f.Lock()
+	f.needQuit = f.closing
+	f.Unlock()
+}
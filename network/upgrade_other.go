@@ -0,0 +1,22 @@
+//go:build !linux
+
+package network
+
+import (
+	"errors"
+	"net"
+)
+
+/*
+ * zero downtime upgrade, not support on this platform
+ */
+
+//try inherit udp socket from old process
+func inheritPacketConn(sockPath string) (net.PacketConn, net.Conn, []uint32, []uint64, error) {
+	return nil, nil, nil, nil, errors.New("upgrade only support on linux")
+}
+
+//listen unix socket and wait new process for upgrade
+func (f *KcpServer) serveUpgrade() error {
+	return errors.New("upgrade only support on linux")
+}
@@ -24,18 +24,27 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 type ID int32
 
 const (
-	ID_MSG_BEGIN     ID = 0
-	ID_MSG_Connect   ID = 1
-	ID_MSG_Heartbeat ID = 2
-	ID_MSG_JoinRoom  ID = 10
-	ID_MSG_Progress  ID = 11
-	ID_MSG_Ready     ID = 12
-	ID_MSG_Start     ID = 13
-	ID_MSG_Frame     ID = 14
-	ID_MSG_Input     ID = 15
-	ID_MSG_Result    ID = 16
-	ID_MSG_Close     ID = 17
-	ID_MSG_END       ID = 20
+	ID_MSG_BEGIN        ID = 0
+	ID_MSG_Connect      ID = 1
+	ID_MSG_Heartbeat    ID = 2
+	ID_MSG_JoinRoom     ID = 10
+	ID_MSG_Progress     ID = 11
+	ID_MSG_Ready        ID = 12
+	ID_MSG_Start        ID = 13
+	ID_MSG_Frame        ID = 14
+	ID_MSG_Input        ID = 15
+	ID_MSG_Result       ID = 16
+	ID_MSG_Close        ID = 17
+	ID_MSG_Snapshot     ID = 18
+	ID_MSG_CatchUp      ID = 19
+	ID_MSG_END          ID = 20
+	ID_MSG_Ack          ID = 21
+	ID_MSG_InputStats   ID = 22
+	ID_MSG_InputAck     ID = 23
+	ID_MSG_Timing       ID = 24
+	ID_MSG_Quality      ID = 25
+	ID_MSG_PlayerStatus ID = 26
+	ID_MSG_Bot          ID = 27
 )
 
 var ID_name = map[int32]string{
@@ -50,22 +59,40 @@ var ID_name = map[int32]string{
 	15: "MSG_Input",
 	16: "MSG_Result",
 	17: "MSG_Close",
+	18: "MSG_Snapshot",
+	19: "MSG_CatchUp",
 	20: "MSG_END",
+	21: "MSG_Ack",
+	22: "MSG_InputStats",
+	23: "MSG_InputAck",
+	24: "MSG_Timing",
+	25: "MSG_Quality",
+	26: "MSG_PlayerStatus",
+	27: "MSG_Bot",
 }
 
 var ID_value = map[string]int32{
-	"MSG_BEGIN":     0,
-	"MSG_Connect":   1,
-	"MSG_Heartbeat": 2,
-	"MSG_JoinRoom":  10,
-	"MSG_Progress":  11,
-	"MSG_Ready":     12,
-	"MSG_Start":     13,
-	"MSG_Frame":     14,
-	"MSG_Input":     15,
-	"MSG_Result":    16,
-	"MSG_Close":     17,
-	"MSG_END":       20,
+	"MSG_BEGIN":        0,
+	"MSG_Connect":      1,
+	"MSG_Heartbeat":    2,
+	"MSG_JoinRoom":     10,
+	"MSG_Progress":     11,
+	"MSG_Ready":        12,
+	"MSG_Start":        13,
+	"MSG_Frame":        14,
+	"MSG_Input":        15,
+	"MSG_Result":       16,
+	"MSG_Close":        17,
+	"MSG_Snapshot":     18,
+	"MSG_CatchUp":      19,
+	"MSG_END":          20,
+	"MSG_Ack":          21,
+	"MSG_InputStats":   22,
+	"MSG_InputAck":     23,
+	"MSG_Timing":       24,
+	"MSG_Quality":      25,
+	"MSG_PlayerStatus": 26,
+	"MSG_Bot":          27,
 }
 
 func (x ID) String() string {
@@ -85,6 +112,8 @@ const (
 	ERROR_CODE_ERR_NoRoom    ERROR_CODE = 2
 	ERROR_CODE_ERR_RoomState ERROR_CODE = 3
 	ERROR_CODE_ERR_Token     ERROR_CODE = 4
+	ERROR_CODE_ERR_Forfeit   ERROR_CODE = 5
+	ERROR_CODE_ERR_Duplicate ERROR_CODE = 6
 )
 
 var ERROR_CODE_name = map[int32]string{
@@ -93,6 +122,8 @@ var ERROR_CODE_name = map[int32]string{
 	2: "ERR_NoRoom",
 	3: "ERR_RoomState",
 	4: "ERR_Token",
+	5: "ERR_Forfeit",
+	6: "ERR_Duplicate",
 }
 
 var ERROR_CODE_value = map[string]int32{
@@ -101,6 +132,8 @@ var ERROR_CODE_value = map[string]int32{
 	"ERR_NoRoom":    2,
 	"ERR_RoomState": 3,
 	"ERR_Token":     4,
+	"ERR_Forfeit":   5,
+	"ERR_Duplicate": 6,
 }
 
 func (x ERROR_CODE) String() string {
@@ -111,6 +144,152 @@ func (ERROR_CODE) EnumDescriptor() ([]byte, []int) {
 	return fileDescriptor_33c57e4bae7b9afd, []int{1}
 }
 
+//close reason
+type CLOSE_REASON int32
+
+const (
+	CLOSE_REASON_CLOSE_Normal   CLOSE_REASON = 0
+	CLOSE_REASON_CLOSE_Shutdown CLOSE_REASON = 1
+	CLOSE_REASON_CLOSE_Abort    CLOSE_REASON = 2
+	CLOSE_REASON_CLOSE_Replaced CLOSE_REASON = 3
+)
+
+var CLOSE_REASON_name = map[int32]string{
+	0: "CLOSE_Normal",
+	1: "CLOSE_Shutdown",
+	2: "CLOSE_Abort",
+	3: "CLOSE_Replaced",
+}
+
+var CLOSE_REASON_value = map[string]int32{
+	"CLOSE_Normal":   0,
+	"CLOSE_Shutdown": 1,
+	"CLOSE_Abort":    2,
+	"CLOSE_Replaced": 3,
+}
+
+func (x CLOSE_REASON) String() string {
+	return proto.EnumName(CLOSE_REASON_name, int32(x))
+}
+
+func (CLOSE_REASON) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{2}
+}
+
+//leave reason
+type LEAVE_REASON int32
+
+const (
+	LEAVE_REASON_LEAVE_Close   LEAVE_REASON = 0
+	LEAVE_REASON_LEAVE_Timeout LEAVE_REASON = 1
+	LEAVE_REASON_LEAVE_Afk     LEAVE_REASON = 2
+)
+
+var LEAVE_REASON_name = map[int32]string{
+	0: "LEAVE_Close",
+	1: "LEAVE_Timeout",
+	2: "LEAVE_Afk",
+}
+
+var LEAVE_REASON_value = map[string]int32{
+	"LEAVE_Close":   0,
+	"LEAVE_Timeout": 1,
+	"LEAVE_Afk":     2,
+}
+
+func (x LEAVE_REASON) String() string {
+	return proto.EnumName(LEAVE_REASON_name, int32(x))
+}
+
+func (LEAVE_REASON) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{3}
+}
+
+//player status
+type PLAYER_STATUS int32
+
+const (
+	PLAYER_STATUS_PLAYER_Online       PLAYER_STATUS = 0
+	PLAYER_STATUS_PLAYER_Offline      PLAYER_STATUS = 1
+	PLAYER_STATUS_PLAYER_AfkWarn      PLAYER_STATUS = 2
+	PLAYER_STATUS_PLAYER_Kicked       PLAYER_STATUS = 3
+	PLAYER_STATUS_PLAYER_Forfeit      PLAYER_STATUS = 4
+	PLAYER_STATUS_PLAYER_Reconnecting PLAYER_STATUS = 5
+)
+
+var PLAYER_STATUS_name = map[int32]string{
+	0: "PLAYER_Online",
+	1: "PLAYER_Offline",
+	2: "PLAYER_AfkWarn",
+	3: "PLAYER_Kicked",
+	4: "PLAYER_Forfeit",
+	5: "PLAYER_Reconnecting",
+}
+
+var PLAYER_STATUS_value = map[string]int32{
+	"PLAYER_Online":       0,
+	"PLAYER_Offline":      1,
+	"PLAYER_AfkWarn":      2,
+	"PLAYER_Kicked":       3,
+	"PLAYER_Forfeit":      4,
+	"PLAYER_Reconnecting": 5,
+}
+
+func (x PLAYER_STATUS) String() string {
+	return proto.EnumName(PLAYER_STATUS_name, int32(x))
+}
+
+func (PLAYER_STATUS) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{4}
+}
+
+//input result
+type INPUT_RESULT int32
+
+const (
+	INPUT_RESULT_INPUT_Ok             INPUT_RESULT = 0
+	INPUT_RESULT_INPUT_Duplicate      INPUT_RESULT = 1
+	INPUT_RESULT_INPUT_Late           INPUT_RESULT = 2
+	INPUT_RESULT_INPUT_RateLimited    INPUT_RESULT = 3
+	INPUT_RESULT_INPUT_InvalidPayload INPUT_RESULT = 4
+	INPUT_RESULT_INPUT_NotRunning     INPUT_RESULT = 5
+	INPUT_RESULT_INPUT_FrameClosed    INPUT_RESULT = 6
+	INPUT_RESULT_INPUT_FrameEvicted   INPUT_RESULT = 7
+	INPUT_RESULT_INPUT_MergeDropped   INPUT_RESULT = 8
+)
+
+var INPUT_RESULT_name = map[int32]string{
+	0: "INPUT_Ok",
+	1: "INPUT_Duplicate",
+	2: "INPUT_Late",
+	3: "INPUT_RateLimited",
+	4: "INPUT_InvalidPayload",
+	5: "INPUT_NotRunning",
+	6: "INPUT_FrameClosed",
+	7: "INPUT_FrameEvicted",
+	8: "INPUT_MergeDropped",
+}
+
+var INPUT_RESULT_value = map[string]int32{
+	"INPUT_Ok":             0,
+	"INPUT_Duplicate":      1,
+	"INPUT_Late":           2,
+	"INPUT_RateLimited":    3,
+	"INPUT_InvalidPayload": 4,
+	"INPUT_NotRunning":     5,
+	"INPUT_FrameClosed":    6,
+	"INPUT_FrameEvicted":   7,
+	"INPUT_MergeDropped":   8,
+}
+
+func (x INPUT_RESULT) String() string {
+	return proto.EnumName(INPUT_RESULT_name, int32(x))
+}
+
+func (INPUT_RESULT) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{5}
+}
+
 //connect message, first message from client side
 type C2S_ConnectMsg struct {
 	PlayerID             uint64   `protobuf:"varint,1,opt,name=playerID,proto3" json:"playerID,omitempty"`
@@ -170,6 +349,7 @@ func (m *C2S_ConnectMsg) GetToken() string {
 //connect message from server side (S2C)
 type S2C_ConnectMsg struct {
 	ErrorCode            ERROR_CODE `protobuf:"varint,1,opt,name=errorCode,proto3,enum=pb.ERROR_CODE" json:"errorCode,omitempty"`
+	Observer             bool       `protobuf:"varint,2,opt,name=observer,proto3" json:"observer,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
 	XXX_unrecognized     []byte     `json:"-"`
 	XXX_sizecache        int32      `json:"-"`
@@ -207,6 +387,207 @@ func (m *S2C_ConnectMsg) GetErrorCode() ERROR_CODE {
 	return ERROR_CODE_ERR_Ok
 }
 
+func (m *S2C_ConnectMsg) GetObserver() bool {
+	if m != nil {
+		return m.Observer
+	}
+	return false
+}
+
+//heart beat message, body is optional (C2S)
+type C2S_HeartbeatMsg struct {
+	AckFrameCount        uint32   `protobuf:"varint,1,opt,name=ackFrameCount,proto3" json:"ackFrameCount,omitempty"`
+	ClientTime           int64    `protobuf:"varint,2,opt,name=clientTime,proto3" json:"clientTime,omitempty"`
+	LastServerTime       int64    `protobuf:"varint,3,opt,name=lastServerTime,proto3" json:"lastServerTime,omitempty"`
+	LastRecvTime         int64    `protobuf:"varint,4,opt,name=lastRecvTime,proto3" json:"lastRecvTime,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *C2S_HeartbeatMsg) Reset()         { *m = C2S_HeartbeatMsg{} }
+func (m *C2S_HeartbeatMsg) String() string { return proto.CompactTextString(m) }
+func (*C2S_HeartbeatMsg) ProtoMessage()    {}
+func (*C2S_HeartbeatMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{2}
+}
+
+func (m *C2S_HeartbeatMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_C2S_HeartbeatMsg.Unmarshal(m, b)
+}
+func (m *C2S_HeartbeatMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_C2S_HeartbeatMsg.Marshal(b, m, deterministic)
+}
+func (m *C2S_HeartbeatMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_C2S_HeartbeatMsg.Merge(m, src)
+}
+func (m *C2S_HeartbeatMsg) XXX_Size() int {
+	return xxx_messageInfo_C2S_HeartbeatMsg.Size(m)
+}
+func (m *C2S_HeartbeatMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_C2S_HeartbeatMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_C2S_HeartbeatMsg proto.InternalMessageInfo
+
+func (m *C2S_HeartbeatMsg) GetAckFrameCount() uint32 {
+	if m != nil {
+		return m.AckFrameCount
+	}
+	return 0
+}
+
+func (m *C2S_HeartbeatMsg) GetClientTime() int64 {
+	if m != nil {
+		return m.ClientTime
+	}
+	return 0
+}
+
+func (m *C2S_HeartbeatMsg) GetLastServerTime() int64 {
+	if m != nil {
+		return m.LastServerTime
+	}
+	return 0
+}
+
+func (m *C2S_HeartbeatMsg) GetLastRecvTime() int64 {
+	if m != nil {
+		return m.LastRecvTime
+	}
+	return 0
+}
+
+//heart beat reply with clock sync (S2C), if clientTime sent
+//rtt = (t3 - t0) - (t2 - t1), offset = ((t1 - t0) + (t2 - t3)) / 2
+//t0 clientTime, t1 serverRecvTime, t2 serverSendTime, t3 client receive time.
+type S2C_HeartbeatMsg struct {
+	ClientTime           int64    `protobuf:"varint,1,opt,name=clientTime,proto3" json:"clientTime,omitempty"`
+	ServerRecvTime       int64    `protobuf:"varint,2,opt,name=serverRecvTime,proto3" json:"serverRecvTime,omitempty"`
+	ServerSendTime       int64    `protobuf:"varint,3,opt,name=serverSendTime,proto3" json:"serverSendTime,omitempty"`
+	FrameID              uint32   `protobuf:"varint,4,opt,name=frameID,proto3" json:"frameID,omitempty"`
+	FrameTime            int64    `protobuf:"varint,5,opt,name=frameTime,proto3" json:"frameTime,omitempty"`
+	Offset               int64    `protobuf:"varint,6,opt,name=offset,proto3" json:"offset,omitempty"`
+	Rtt                  int64    `protobuf:"varint,7,opt,name=rtt,proto3" json:"rtt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *S2C_HeartbeatMsg) Reset()         { *m = S2C_HeartbeatMsg{} }
+func (m *S2C_HeartbeatMsg) String() string { return proto.CompactTextString(m) }
+func (*S2C_HeartbeatMsg) ProtoMessage()    {}
+func (*S2C_HeartbeatMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{3}
+}
+
+func (m *S2C_HeartbeatMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_S2C_HeartbeatMsg.Unmarshal(m, b)
+}
+func (m *S2C_HeartbeatMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_S2C_HeartbeatMsg.Marshal(b, m, deterministic)
+}
+func (m *S2C_HeartbeatMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_S2C_HeartbeatMsg.Merge(m, src)
+}
+func (m *S2C_HeartbeatMsg) XXX_Size() int {
+	return xxx_messageInfo_S2C_HeartbeatMsg.Size(m)
+}
+func (m *S2C_HeartbeatMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_S2C_HeartbeatMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_S2C_HeartbeatMsg proto.InternalMessageInfo
+
+func (m *S2C_HeartbeatMsg) GetClientTime() int64 {
+	if m != nil {
+		return m.ClientTime
+	}
+	return 0
+}
+
+func (m *S2C_HeartbeatMsg) GetServerRecvTime() int64 {
+	if m != nil {
+		return m.ServerRecvTime
+	}
+	return 0
+}
+
+func (m *S2C_HeartbeatMsg) GetServerSendTime() int64 {
+	if m != nil {
+		return m.ServerSendTime
+	}
+	return 0
+}
+
+func (m *S2C_HeartbeatMsg) GetFrameID() uint32 {
+	if m != nil {
+		return m.FrameID
+	}
+	return 0
+}
+
+func (m *S2C_HeartbeatMsg) GetFrameTime() int64 {
+	if m != nil {
+		return m.FrameTime
+	}
+	return 0
+}
+
+func (m *S2C_HeartbeatMsg) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *S2C_HeartbeatMsg) GetRtt() int64 {
+	if m != nil {
+		return m.Rtt
+	}
+	return 0
+}
+
+//frame ack message (C2S)
+type C2S_AckMsg struct {
+	AckFrameCount        uint32   `protobuf:"varint,1,opt,name=ackFrameCount,proto3" json:"ackFrameCount,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *C2S_AckMsg) Reset()         { *m = C2S_AckMsg{} }
+func (m *C2S_AckMsg) String() string { return proto.CompactTextString(m) }
+func (*C2S_AckMsg) ProtoMessage()    {}
+func (*C2S_AckMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{4}
+}
+
+func (m *C2S_AckMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_C2S_AckMsg.Unmarshal(m, b)
+}
+func (m *C2S_AckMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_C2S_AckMsg.Marshal(b, m, deterministic)
+}
+func (m *C2S_AckMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_C2S_AckMsg.Merge(m, src)
+}
+func (m *C2S_AckMsg) XXX_Size() int {
+	return xxx_messageInfo_C2S_AckMsg.Size(m)
+}
+func (m *C2S_AckMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_C2S_AckMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_C2S_AckMsg proto.InternalMessageInfo
+
+func (m *C2S_AckMsg) GetAckFrameCount() uint32 {
+	if m != nil {
+		return m.AckFrameCount
+	}
+	return 0
+}
+
 //join room message (S2C)
 type S2C_JoinRoomMsg struct {
 	RoomSeatId           int32    `protobuf:"varint,1,opt,name=roomSeatId,proto3" json:"roomSeatId,omitempty"`
@@ -222,7 +603,7 @@ func (m *S2C_JoinRoomMsg) Reset()         { *m = S2C_JoinRoomMsg{} }
 func (m *S2C_JoinRoomMsg) String() string { return proto.CompactTextString(m) }
 func (*S2C_JoinRoomMsg) ProtoMessage()    {}
 func (*S2C_JoinRoomMsg) Descriptor() ([]byte, []int) {
-	return fileDescriptor_33c57e4bae7b9afd, []int{2}
+	return fileDescriptor_33c57e4bae7b9afd, []int{5}
 }
 
 func (m *S2C_JoinRoomMsg) XXX_Unmarshal(b []byte) error {
@@ -274,6 +655,8 @@ func (m *S2C_JoinRoomMsg) GetRandomSeed() int32 {
 //game start message (S2C)
 type S2C_StartMsg struct {
 	TimeStamp            int64    `protobuf:"varint,1,opt,name=timeStamp,proto3" json:"timeStamp,omitempty"`
+	StartTime            int64    `protobuf:"varint,2,opt,name=startTime,proto3" json:"startTime,omitempty"`
+	Frequency            uint32   `protobuf:"varint,3,opt,name=frequency,proto3" json:"frequency,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -283,7 +666,7 @@ func (m *S2C_StartMsg) Reset()         { *m = S2C_StartMsg{} }
 func (m *S2C_StartMsg) String() string { return proto.CompactTextString(m) }
 func (*S2C_StartMsg) ProtoMessage()    {}
 func (*S2C_StartMsg) Descriptor() ([]byte, []int) {
-	return fileDescriptor_33c57e4bae7b9afd, []int{3}
+	return fileDescriptor_33c57e4bae7b9afd, []int{6}
 }
 
 func (m *S2C_StartMsg) XXX_Unmarshal(b []byte) error {
@@ -311,6 +694,20 @@ func (m *S2C_StartMsg) GetTimeStamp() int64 {
 	return 0
 }
 
+func (m *S2C_StartMsg) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+func (m *S2C_StartMsg) GetFrequency() uint32 {
+	if m != nil {
+		return m.Frequency
+	}
+	return 0
+}
+
 //read progress (C2S)
 type C2S_ProgressMsg struct {
 	Pro                  int32    `protobuf:"varint,1,opt,name=pro,proto3" json:"pro,omitempty"`
@@ -323,7 +720,7 @@ func (m *C2S_ProgressMsg) Reset()         { *m = C2S_ProgressMsg{} }
 func (m *C2S_ProgressMsg) String() string { return proto.CompactTextString(m) }
 func (*C2S_ProgressMsg) ProtoMessage()    {}
 func (*C2S_ProgressMsg) Descriptor() ([]byte, []int) {
-	return fileDescriptor_33c57e4bae7b9afd, []int{4}
+	return fileDescriptor_33c57e4bae7b9afd, []int{7}
 }
 
 func (m *C2S_ProgressMsg) XXX_Unmarshal(b []byte) error {
@@ -364,7 +761,7 @@ func (m *S2C_ProgressMsg) Reset()         { *m = S2C_ProgressMsg{} }
 func (m *S2C_ProgressMsg) String() string { return proto.CompactTextString(m) }
 func (*S2C_ProgressMsg) ProtoMessage()    {}
 func (*S2C_ProgressMsg) Descriptor() ([]byte, []int) {
-	return fileDescriptor_33c57e4bae7b9afd, []int{5}
+	return fileDescriptor_33c57e4bae7b9afd, []int{8}
 }
 
 func (m *S2C_ProgressMsg) XXX_Unmarshal(b []byte) error {
@@ -414,7 +811,7 @@ func (m *C2S_InputMsg) Reset()         { *m = C2S_InputMsg{} }
 func (m *C2S_InputMsg) String() string { return proto.CompactTextString(m) }
 func (*C2S_InputMsg) ProtoMessage()    {}
 func (*C2S_InputMsg) Descriptor() ([]byte, []int) {
-	return fileDescriptor_33c57e4bae7b9afd, []int{6}
+	return fileDescriptor_33c57e4bae7b9afd, []int{9}
 }
 
 func (m *C2S_InputMsg) XXX_Unmarshal(b []byte) error {
@@ -470,6 +867,7 @@ type InputData struct {
 	X                    int32    `protobuf:"varint,3,opt,name=x,proto3" json:"x,omitempty"`
 	Y                    int32    `protobuf:"varint,4,opt,name=y,proto3" json:"y,omitempty"`
 	RoomSeatId           int32    `protobuf:"varint,5,opt,name=roomSeatId,proto3" json:"roomSeatId,omitempty"`
+	Seq                  uint32   `protobuf:"varint,6,opt,name=seq,proto3" json:"seq,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -479,7 +877,7 @@ func (m *InputData) Reset()         { *m = InputData{} }
 func (m *InputData) String() string { return proto.CompactTextString(m) }
 func (*InputData) ProtoMessage()    {}
 func (*InputData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_33c57e4bae7b9afd, []int{7}
+	return fileDescriptor_33c57e4bae7b9afd, []int{10}
 }
 
 func (m *InputData) XXX_Unmarshal(b []byte) error {
@@ -535,7 +933,15 @@ func (m *InputData) GetRoomSeatId() int32 {
 	return 0
 }
 
+func (m *InputData) GetSeq() uint32 {
+	if m != nil {
+		return m.Seq
+	}
+	return 0
+}
+
 //frame data
+//input ordered by roomSeatId, then seq
 type FrameData struct {
 	FrameID              uint32       `protobuf:"varint,1,opt,name=frameID,proto3" json:"frameID,omitempty"`
 	Input                []*InputData `protobuf:"bytes,2,rep,name=input,proto3" json:"input,omitempty"`
@@ -548,7 +954,7 @@ func (m *FrameData) Reset()         { *m = FrameData{} }
 func (m *FrameData) String() string { return proto.CompactTextString(m) }
 func (*FrameData) ProtoMessage()    {}
 func (*FrameData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_33c57e4bae7b9afd, []int{8}
+	return fileDescriptor_33c57e4bae7b9afd, []int{11}
 }
 
 func (m *FrameData) XXX_Unmarshal(b []byte) error {
@@ -595,7 +1001,7 @@ func (m *S2C_FrameMsg) Reset()         { *m = S2C_FrameMsg{} }
 func (m *S2C_FrameMsg) String() string { return proto.CompactTextString(m) }
 func (*S2C_FrameMsg) ProtoMessage()    {}
 func (*S2C_FrameMsg) Descriptor() ([]byte, []int) {
-	return fileDescriptor_33c57e4bae7b9afd, []int{9}
+	return fileDescriptor_33c57e4bae7b9afd, []int{12}
 }
 
 func (m *S2C_FrameMsg) XXX_Unmarshal(b []byte) error {
@@ -635,7 +1041,7 @@ func (m *C2S_ResultMsg) Reset()         { *m = C2S_ResultMsg{} }
 func (m *C2S_ResultMsg) String() string { return proto.CompactTextString(m) }
 func (*C2S_ResultMsg) ProtoMessage()    {}
 func (*C2S_ResultMsg) Descriptor() ([]byte, []int) {
-	return fileDescriptor_33c57e4bae7b9afd, []int{10}
+	return fileDescriptor_33c57e4bae7b9afd, []int{13}
 }
 
 func (m *C2S_ResultMsg) XXX_Unmarshal(b []byte) error {
@@ -663,11 +1069,710 @@ func (m *C2S_ResultMsg) GetWinnerID() uint64 {
 	return 0
 }
 
+//room closed (S2C)
+type S2C_CloseMsg struct {
+	Reason               CLOSE_REASON `protobuf:"varint,1,opt,name=reason,proto3,enum=pb.CLOSE_REASON" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *S2C_CloseMsg) Reset()         { *m = S2C_CloseMsg{} }
+func (m *S2C_CloseMsg) String() string { return proto.CompactTextString(m) }
+func (*S2C_CloseMsg) ProtoMessage()    {}
+func (*S2C_CloseMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{14}
+}
+
+func (m *S2C_CloseMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_S2C_CloseMsg.Unmarshal(m, b)
+}
+func (m *S2C_CloseMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_S2C_CloseMsg.Marshal(b, m, deterministic)
+}
+func (m *S2C_CloseMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_S2C_CloseMsg.Merge(m, src)
+}
+func (m *S2C_CloseMsg) XXX_Size() int {
+	return xxx_messageInfo_S2C_CloseMsg.Size(m)
+}
+func (m *S2C_CloseMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_S2C_CloseMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_S2C_CloseMsg proto.InternalMessageInfo
+
+func (m *S2C_CloseMsg) GetReason() CLOSE_REASON {
+	if m != nil {
+		return m.Reason
+	}
+	return CLOSE_REASON_CLOSE_Normal
+}
+
+//state snapshot after frame applied (C2S)
+type C2S_SnapshotMsg struct {
+	FrameID              uint32   `protobuf:"varint,1,opt,name=frameID,proto3" json:"frameID,omitempty"`
+	Hash                 uint64   `protobuf:"varint,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	Data                 []byte   `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *C2S_SnapshotMsg) Reset()         { *m = C2S_SnapshotMsg{} }
+func (m *C2S_SnapshotMsg) String() string { return proto.CompactTextString(m) }
+func (*C2S_SnapshotMsg) ProtoMessage()    {}
+func (*C2S_SnapshotMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{15}
+}
+
+func (m *C2S_SnapshotMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_C2S_SnapshotMsg.Unmarshal(m, b)
+}
+func (m *C2S_SnapshotMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_C2S_SnapshotMsg.Marshal(b, m, deterministic)
+}
+func (m *C2S_SnapshotMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_C2S_SnapshotMsg.Merge(m, src)
+}
+func (m *C2S_SnapshotMsg) XXX_Size() int {
+	return xxx_messageInfo_C2S_SnapshotMsg.Size(m)
+}
+func (m *C2S_SnapshotMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_C2S_SnapshotMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_C2S_SnapshotMsg proto.InternalMessageInfo
+
+func (m *C2S_SnapshotMsg) GetFrameID() uint32 {
+	if m != nil {
+		return m.FrameID
+	}
+	return 0
+}
+
+func (m *C2S_SnapshotMsg) GetHash() uint64 {
+	if m != nil {
+		return m.Hash
+	}
+	return 0
+}
+
+func (m *C2S_SnapshotMsg) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+//agreed state snapshot for reconnect (S2C)
+//frames after frameID follow it.
+type S2C_SnapshotMsg struct {
+	FrameID              uint32   `protobuf:"varint,1,opt,name=frameID,proto3" json:"frameID,omitempty"`
+	Hash                 uint64   `protobuf:"varint,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	Data                 []byte   `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *S2C_SnapshotMsg) Reset()         { *m = S2C_SnapshotMsg{} }
+func (m *S2C_SnapshotMsg) String() string { return proto.CompactTextString(m) }
+func (*S2C_SnapshotMsg) ProtoMessage()    {}
+func (*S2C_SnapshotMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{16}
+}
+
+func (m *S2C_SnapshotMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_S2C_SnapshotMsg.Unmarshal(m, b)
+}
+func (m *S2C_SnapshotMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_S2C_SnapshotMsg.Marshal(b, m, deterministic)
+}
+func (m *S2C_SnapshotMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_S2C_SnapshotMsg.Merge(m, src)
+}
+func (m *S2C_SnapshotMsg) XXX_Size() int {
+	return xxx_messageInfo_S2C_SnapshotMsg.Size(m)
+}
+func (m *S2C_SnapshotMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_S2C_SnapshotMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_S2C_SnapshotMsg proto.InternalMessageInfo
+
+func (m *S2C_SnapshotMsg) GetFrameID() uint32 {
+	if m != nil {
+		return m.FrameID
+	}
+	return 0
+}
+
+func (m *S2C_SnapshotMsg) GetHash() uint64 {
+	if m != nil {
+		return m.Hash
+	}
+	return 0
+}
+
+func (m *S2C_SnapshotMsg) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+//catch up progress of backlog frames (S2C)
+//live frames after endFrameID may arrive before backlog done.
+type S2C_CatchUpMsg struct {
+	FrameID              uint32   `protobuf:"varint,1,opt,name=frameID,proto3" json:"frameID,omitempty"`
+	EndFrameID           uint32   `protobuf:"varint,2,opt,name=endFrameID,proto3" json:"endFrameID,omitempty"`
+	Done                 bool     `protobuf:"varint,3,opt,name=done,proto3" json:"done,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *S2C_CatchUpMsg) Reset()         { *m = S2C_CatchUpMsg{} }
+func (m *S2C_CatchUpMsg) String() string { return proto.CompactTextString(m) }
+func (*S2C_CatchUpMsg) ProtoMessage()    {}
+func (*S2C_CatchUpMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{17}
+}
+
+func (m *S2C_CatchUpMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_S2C_CatchUpMsg.Unmarshal(m, b)
+}
+func (m *S2C_CatchUpMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_S2C_CatchUpMsg.Marshal(b, m, deterministic)
+}
+func (m *S2C_CatchUpMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_S2C_CatchUpMsg.Merge(m, src)
+}
+func (m *S2C_CatchUpMsg) XXX_Size() int {
+	return xxx_messageInfo_S2C_CatchUpMsg.Size(m)
+}
+func (m *S2C_CatchUpMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_S2C_CatchUpMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_S2C_CatchUpMsg proto.InternalMessageInfo
+
+func (m *S2C_CatchUpMsg) GetFrameID() uint32 {
+	if m != nil {
+		return m.FrameID
+	}
+	return 0
+}
+
+func (m *S2C_CatchUpMsg) GetEndFrameID() uint32 {
+	if m != nil {
+		return m.EndFrameID
+	}
+	return 0
+}
+
+func (m *S2C_CatchUpMsg) GetDone() bool {
+	if m != nil {
+		return m.Done
+	}
+	return false
+}
+
+//input lateness stats of receiver (S2C)
+//late input is applied to current open frame instead of its frameID.
+type S2C_InputStatsMsg struct {
+	Inputs               uint32   `protobuf:"varint,1,opt,name=inputs,proto3" json:"inputs,omitempty"`
+	Late                 uint32   `protobuf:"varint,2,opt,name=late,proto3" json:"late,omitempty"`
+	MaxLateFrames        uint32   `protobuf:"varint,3,opt,name=maxLateFrames,proto3" json:"maxLateFrames,omitempty"`
+	AvgLateFrames        float32  `protobuf:"fixed32,4,opt,name=avgLateFrames,proto3" json:"avgLateFrames,omitempty"`
+	RecentInputs         uint32   `protobuf:"varint,5,opt,name=recentInputs,proto3" json:"recentInputs,omitempty"`
+	RecentLate           uint32   `protobuf:"varint,6,opt,name=recentLate,proto3" json:"recentLate,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *S2C_InputStatsMsg) Reset()         { *m = S2C_InputStatsMsg{} }
+func (m *S2C_InputStatsMsg) String() string { return proto.CompactTextString(m) }
+func (*S2C_InputStatsMsg) ProtoMessage()    {}
+func (*S2C_InputStatsMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{18}
+}
+
+func (m *S2C_InputStatsMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_S2C_InputStatsMsg.Unmarshal(m, b)
+}
+func (m *S2C_InputStatsMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_S2C_InputStatsMsg.Marshal(b, m, deterministic)
+}
+func (m *S2C_InputStatsMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_S2C_InputStatsMsg.Merge(m, src)
+}
+func (m *S2C_InputStatsMsg) XXX_Size() int {
+	return xxx_messageInfo_S2C_InputStatsMsg.Size(m)
+}
+func (m *S2C_InputStatsMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_S2C_InputStatsMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_S2C_InputStatsMsg proto.InternalMessageInfo
+
+func (m *S2C_InputStatsMsg) GetInputs() uint32 {
+	if m != nil {
+		return m.Inputs
+	}
+	return 0
+}
+
+func (m *S2C_InputStatsMsg) GetLate() uint32 {
+	if m != nil {
+		return m.Late
+	}
+	return 0
+}
+
+func (m *S2C_InputStatsMsg) GetMaxLateFrames() uint32 {
+	if m != nil {
+		return m.MaxLateFrames
+	}
+	return 0
+}
+
+func (m *S2C_InputStatsMsg) GetAvgLateFrames() float32 {
+	if m != nil {
+		return m.AvgLateFrames
+	}
+	return 0
+}
+
+func (m *S2C_InputStatsMsg) GetRecentInputs() uint32 {
+	if m != nil {
+		return m.RecentInputs
+	}
+	return 0
+}
+
+func (m *S2C_InputStatsMsg) GetRecentLate() uint32 {
+	if m != nil {
+		return m.RecentLate
+	}
+	return 0
+}
+
+//input ack or reject (S2C)
+type S2C_InputAckMsg struct {
+	Sid                  int32        `protobuf:"varint,1,opt,name=sid,proto3" json:"sid,omitempty"`
+	Result               INPUT_RESULT `protobuf:"varint,2,opt,name=result,proto3,enum=pb.INPUT_RESULT" json:"result,omitempty"`
+	FrameID              uint32       `protobuf:"varint,3,opt,name=frameID,proto3" json:"frameID,omitempty"`
+	LateFrames           uint32       `protobuf:"varint,4,opt,name=lateFrames,proto3" json:"lateFrames,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *S2C_InputAckMsg) Reset()         { *m = S2C_InputAckMsg{} }
+func (m *S2C_InputAckMsg) String() string { return proto.CompactTextString(m) }
+func (*S2C_InputAckMsg) ProtoMessage()    {}
+func (*S2C_InputAckMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{19}
+}
+
+func (m *S2C_InputAckMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_S2C_InputAckMsg.Unmarshal(m, b)
+}
+func (m *S2C_InputAckMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_S2C_InputAckMsg.Marshal(b, m, deterministic)
+}
+func (m *S2C_InputAckMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_S2C_InputAckMsg.Merge(m, src)
+}
+func (m *S2C_InputAckMsg) XXX_Size() int {
+	return xxx_messageInfo_S2C_InputAckMsg.Size(m)
+}
+func (m *S2C_InputAckMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_S2C_InputAckMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_S2C_InputAckMsg proto.InternalMessageInfo
+
+func (m *S2C_InputAckMsg) GetSid() int32 {
+	if m != nil {
+		return m.Sid
+	}
+	return 0
+}
+
+func (m *S2C_InputAckMsg) GetResult() INPUT_RESULT {
+	if m != nil {
+		return m.Result
+	}
+	return INPUT_RESULT_INPUT_Ok
+}
+
+func (m *S2C_InputAckMsg) GetFrameID() uint32 {
+	if m != nil {
+		return m.FrameID
+	}
+	return 0
+}
+
+func (m *S2C_InputAckMsg) GetLateFrames() uint32 {
+	if m != nil {
+		return m.LateFrames
+	}
+	return 0
+}
+
+//tick frequency and input delay, on start and change (S2C)
+//input frame of player is current frame + inputDelay + inputHold
+type S2C_TimingMsg struct {
+	Frequency            uint32   `protobuf:"varint,1,opt,name=frequency,proto3" json:"frequency,omitempty"`
+	InputDelay           uint32   `protobuf:"varint,2,opt,name=inputDelay,proto3" json:"inputDelay,omitempty"`
+	FrameID              uint32   `protobuf:"varint,3,opt,name=frameID,proto3" json:"frameID,omitempty"`
+	InputHold            uint32   `protobuf:"varint,4,opt,name=inputHold,proto3" json:"inputHold,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *S2C_TimingMsg) Reset()         { *m = S2C_TimingMsg{} }
+func (m *S2C_TimingMsg) String() string { return proto.CompactTextString(m) }
+func (*S2C_TimingMsg) ProtoMessage()    {}
+func (*S2C_TimingMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{20}
+}
+
+func (m *S2C_TimingMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_S2C_TimingMsg.Unmarshal(m, b)
+}
+func (m *S2C_TimingMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_S2C_TimingMsg.Marshal(b, m, deterministic)
+}
+func (m *S2C_TimingMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_S2C_TimingMsg.Merge(m, src)
+}
+func (m *S2C_TimingMsg) XXX_Size() int {
+	return xxx_messageInfo_S2C_TimingMsg.Size(m)
+}
+func (m *S2C_TimingMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_S2C_TimingMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_S2C_TimingMsg proto.InternalMessageInfo
+
+func (m *S2C_TimingMsg) GetFrequency() uint32 {
+	if m != nil {
+		return m.Frequency
+	}
+	return 0
+}
+
+func (m *S2C_TimingMsg) GetInputDelay() uint32 {
+	if m != nil {
+		return m.InputDelay
+	}
+	return 0
+}
+
+func (m *S2C_TimingMsg) GetFrameID() uint32 {
+	if m != nil {
+		return m.FrameID
+	}
+	return 0
+}
+
+func (m *S2C_TimingMsg) GetInputHold() uint32 {
+	if m != nil {
+		return m.InputHold
+	}
+	return 0
+}
+
+//connect quality of player
+type PlayerQuality struct {
+	Id                   uint64   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Online               bool     `protobuf:"varint,2,opt,name=online,proto3" json:"online,omitempty"`
+	Rtt                  int64    `protobuf:"varint,3,opt,name=rtt,proto3" json:"rtt,omitempty"`
+	Jitter               int64    `protobuf:"varint,4,opt,name=jitter,proto3" json:"jitter,omitempty"`
+	PacketsIn            uint64   `protobuf:"varint,5,opt,name=packetsIn,proto3" json:"packetsIn,omitempty"`
+	PacketsOut           uint64   `protobuf:"varint,6,opt,name=packetsOut,proto3" json:"packetsOut,omitempty"`
+	Pending              int32    `protobuf:"varint,7,opt,name=pending,proto3" json:"pending,omitempty"`
+	Segments             uint64   `protobuf:"varint,8,opt,name=segments,proto3" json:"segments,omitempty"`
+	Retransmits          uint64   `protobuf:"varint,9,opt,name=retransmits,proto3" json:"retransmits,omitempty"`
+	Parity               uint64   `protobuf:"varint,10,opt,name=parity,proto3" json:"parity,omitempty"`
+	Loss                 float32  `protobuf:"fixed32,11,opt,name=loss,proto3" json:"loss,omitempty"`
+	Recovered            uint64   `protobuf:"varint,12,opt,name=recovered,proto3" json:"recovered,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PlayerQuality) Reset()         { *m = PlayerQuality{} }
+func (m *PlayerQuality) String() string { return proto.CompactTextString(m) }
+func (*PlayerQuality) ProtoMessage()    {}
+func (*PlayerQuality) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{21}
+}
+
+func (m *PlayerQuality) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PlayerQuality.Unmarshal(m, b)
+}
+func (m *PlayerQuality) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PlayerQuality.Marshal(b, m, deterministic)
+}
+func (m *PlayerQuality) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PlayerQuality.Merge(m, src)
+}
+func (m *PlayerQuality) XXX_Size() int {
+	return xxx_messageInfo_PlayerQuality.Size(m)
+}
+func (m *PlayerQuality) XXX_DiscardUnknown() {
+	xxx_messageInfo_PlayerQuality.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PlayerQuality proto.InternalMessageInfo
+
+func (m *PlayerQuality) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *PlayerQuality) GetOnline() bool {
+	if m != nil {
+		return m.Online
+	}
+	return false
+}
+
+func (m *PlayerQuality) GetRtt() int64 {
+	if m != nil {
+		return m.Rtt
+	}
+	return 0
+}
+
+func (m *PlayerQuality) GetJitter() int64 {
+	if m != nil {
+		return m.Jitter
+	}
+	return 0
+}
+
+func (m *PlayerQuality) GetPacketsIn() uint64 {
+	if m != nil {
+		return m.PacketsIn
+	}
+	return 0
+}
+
+func (m *PlayerQuality) GetPacketsOut() uint64 {
+	if m != nil {
+		return m.PacketsOut
+	}
+	return 0
+}
+
+func (m *PlayerQuality) GetPending() int32 {
+	if m != nil {
+		return m.Pending
+	}
+	return 0
+}
+
+func (m *PlayerQuality) GetSegments() uint64 {
+	if m != nil {
+		return m.Segments
+	}
+	return 0
+}
+
+func (m *PlayerQuality) GetRetransmits() uint64 {
+	if m != nil {
+		return m.Retransmits
+	}
+	return 0
+}
+
+func (m *PlayerQuality) GetParity() uint64 {
+	if m != nil {
+		return m.Parity
+	}
+	return 0
+}
+
+func (m *PlayerQuality) GetLoss() float32 {
+	if m != nil {
+		return m.Loss
+	}
+	return 0
+}
+
+func (m *PlayerQuality) GetRecovered() uint64 {
+	if m != nil {
+		return m.Recovered
+	}
+	return 0
+}
+
+//connect quality of all players in room, periodically (S2C)
+type S2C_QualityMsg struct {
+	Players              []*PlayerQuality `protobuf:"bytes,1,rep,name=players,proto3" json:"players,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *S2C_QualityMsg) Reset()         { *m = S2C_QualityMsg{} }
+func (m *S2C_QualityMsg) String() string { return proto.CompactTextString(m) }
+func (*S2C_QualityMsg) ProtoMessage()    {}
+func (*S2C_QualityMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{22}
+}
+
+func (m *S2C_QualityMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_S2C_QualityMsg.Unmarshal(m, b)
+}
+func (m *S2C_QualityMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_S2C_QualityMsg.Marshal(b, m, deterministic)
+}
+func (m *S2C_QualityMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_S2C_QualityMsg.Merge(m, src)
+}
+func (m *S2C_QualityMsg) XXX_Size() int {
+	return xxx_messageInfo_S2C_QualityMsg.Size(m)
+}
+func (m *S2C_QualityMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_S2C_QualityMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_S2C_QualityMsg proto.InternalMessageInfo
+
+func (m *S2C_QualityMsg) GetPlayers() []*PlayerQuality {
+	if m != nil {
+		return m.Players
+	}
+	return nil
+}
+
+//player status changed (S2C)
+type S2C_PlayerStatusMsg struct {
+	Id                   uint64        `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status               PLAYER_STATUS `protobuf:"varint,2,opt,name=status,proto3,enum=pb.PLAYER_STATUS" json:"status,omitempty"`
+	Reason               LEAVE_REASON  `protobuf:"varint,3,opt,name=reason,proto3,enum=pb.LEAVE_REASON" json:"reason,omitempty"`
+	Remain               uint32        `protobuf:"varint,4,opt,name=remain,proto3" json:"remain,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *S2C_PlayerStatusMsg) Reset()         { *m = S2C_PlayerStatusMsg{} }
+func (m *S2C_PlayerStatusMsg) String() string { return proto.CompactTextString(m) }
+func (*S2C_PlayerStatusMsg) ProtoMessage()    {}
+func (*S2C_PlayerStatusMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{23}
+}
+
+func (m *S2C_PlayerStatusMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_S2C_PlayerStatusMsg.Unmarshal(m, b)
+}
+func (m *S2C_PlayerStatusMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_S2C_PlayerStatusMsg.Marshal(b, m, deterministic)
+}
+func (m *S2C_PlayerStatusMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_S2C_PlayerStatusMsg.Merge(m, src)
+}
+func (m *S2C_PlayerStatusMsg) XXX_Size() int {
+	return xxx_messageInfo_S2C_PlayerStatusMsg.Size(m)
+}
+func (m *S2C_PlayerStatusMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_S2C_PlayerStatusMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_S2C_PlayerStatusMsg proto.InternalMessageInfo
+
+func (m *S2C_PlayerStatusMsg) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *S2C_PlayerStatusMsg) GetStatus() PLAYER_STATUS {
+	if m != nil {
+		return m.Status
+	}
+	return PLAYER_STATUS_PLAYER_Online
+}
+
+func (m *S2C_PlayerStatusMsg) GetReason() LEAVE_REASON {
+	if m != nil {
+		return m.Reason
+	}
+	return LEAVE_REASON_LEAVE_Close
+}
+
+func (m *S2C_PlayerStatusMsg) GetRemain() uint32 {
+	if m != nil {
+		return m.Remain
+	}
+	return 0
+}
+
+//seats controlled by bot changed, or on reconnect (S2C)
+type S2C_BotMsg struct {
+	RoomSeatIds          []int32  `protobuf:"varint,1,rep,packed,name=roomSeatIds,proto3" json:"roomSeatIds,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *S2C_BotMsg) Reset()         { *m = S2C_BotMsg{} }
+func (m *S2C_BotMsg) String() string { return proto.CompactTextString(m) }
+func (*S2C_BotMsg) ProtoMessage()    {}
+func (*S2C_BotMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_33c57e4bae7b9afd, []int{24}
+}
+
+func (m *S2C_BotMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_S2C_BotMsg.Unmarshal(m, b)
+}
+func (m *S2C_BotMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_S2C_BotMsg.Marshal(b, m, deterministic)
+}
+func (m *S2C_BotMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_S2C_BotMsg.Merge(m, src)
+}
+func (m *S2C_BotMsg) XXX_Size() int {
+	return xxx_messageInfo_S2C_BotMsg.Size(m)
+}
+func (m *S2C_BotMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_S2C_BotMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_S2C_BotMsg proto.InternalMessageInfo
+
+func (m *S2C_BotMsg) GetRoomSeatIds() []int32 {
+	if m != nil {
+		return m.RoomSeatIds
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterEnum("pb.ID", ID_name, ID_value)
 	proto.RegisterEnum("pb.ERROR_CODE", ERROR_CODE_name, ERROR_CODE_value)
+	proto.RegisterEnum("pb.CLOSE_REASON", CLOSE_REASON_name, CLOSE_REASON_value)
+	proto.RegisterEnum("pb.LEAVE_REASON", LEAVE_REASON_name, LEAVE_REASON_value)
+	proto.RegisterEnum("pb.PLAYER_STATUS", PLAYER_STATUS_name, PLAYER_STATUS_value)
+	proto.RegisterEnum("pb.INPUT_RESULT", INPUT_RESULT_name, INPUT_RESULT_value)
 	proto.RegisterType((*C2S_ConnectMsg)(nil), "pb.C2S_ConnectMsg")
 	proto.RegisterType((*S2C_ConnectMsg)(nil), "pb.S2C_ConnectMsg")
+	proto.RegisterType((*C2S_HeartbeatMsg)(nil), "pb.C2S_HeartbeatMsg")
+	proto.RegisterType((*S2C_HeartbeatMsg)(nil), "pb.S2C_HeartbeatMsg")
+	proto.RegisterType((*C2S_AckMsg)(nil), "pb.C2S_AckMsg")
 	proto.RegisterType((*S2C_JoinRoomMsg)(nil), "pb.S2C_JoinRoomMsg")
 	proto.RegisterType((*S2C_StartMsg)(nil), "pb.S2C_StartMsg")
 	proto.RegisterType((*C2S_ProgressMsg)(nil), "pb.C2S_ProgressMsg")
@@ -677,48 +1782,123 @@ func init() {
 	proto.RegisterType((*FrameData)(nil), "pb.FrameData")
 	proto.RegisterType((*S2C_FrameMsg)(nil), "pb.S2C_FrameMsg")
 	proto.RegisterType((*C2S_ResultMsg)(nil), "pb.C2S_ResultMsg")
+	proto.RegisterType((*S2C_CloseMsg)(nil), "pb.S2C_CloseMsg")
+	proto.RegisterType((*C2S_SnapshotMsg)(nil), "pb.C2S_SnapshotMsg")
+	proto.RegisterType((*S2C_SnapshotMsg)(nil), "pb.S2C_SnapshotMsg")
+	proto.RegisterType((*S2C_CatchUpMsg)(nil), "pb.S2C_CatchUpMsg")
+	proto.RegisterType((*S2C_InputStatsMsg)(nil), "pb.S2C_InputStatsMsg")
+	proto.RegisterType((*S2C_InputAckMsg)(nil), "pb.S2C_InputAckMsg")
+	proto.RegisterType((*S2C_TimingMsg)(nil), "pb.S2C_TimingMsg")
+	proto.RegisterType((*PlayerQuality)(nil), "pb.PlayerQuality")
+	proto.RegisterType((*S2C_QualityMsg)(nil), "pb.S2C_QualityMsg")
+	proto.RegisterType((*S2C_PlayerStatusMsg)(nil), "pb.S2C_PlayerStatusMsg")
+	proto.RegisterType((*S2C_BotMsg)(nil), "pb.S2C_BotMsg")
 }
 
 func init() { proto.RegisterFile("message.proto", fileDescriptor_33c57e4bae7b9afd) }
 
 var fileDescriptor_33c57e4bae7b9afd = []byte{
-	// 594 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x54, 0x5d, 0x6b, 0xdb, 0x30,
-	0x14, 0xad, 0xed, 0x24, 0x5d, 0x6e, 0x62, 0x47, 0x15, 0x65, 0x98, 0x31, 0x46, 0x70, 0x19, 0x84,
-	0xae, 0xf4, 0x21, 0x65, 0xaf, 0x7b, 0x58, 0x9c, 0x75, 0x2e, 0xf4, 0x03, 0x79, 0xec, 0x69, 0x2c,
-	0x28, 0xb3, 0xd6, 0x99, 0xc6, 0x96, 0x91, 0x55, 0xd6, 0x3c, 0xec, 0xd7, 0xed, 0x8f, 0x8d, 0x2b,
-	0x5b, 0xf9, 0x28, 0x7b, 0xd3, 0xb9, 0xf2, 0x3d, 0xe7, 0xdc, 0x0f, 0x19, 0xfc, 0x42, 0xd4, 0x35,
-	0xbf, 0x17, 0xe7, 0x95, 0x92, 0x5a, 0x52, 0xb7, 0x5a, 0x46, 0xdf, 0x21, 0x98, 0x4d, 0xd3, 0xc5,
-	0x4c, 0x96, 0xa5, 0xf8, 0xa1, 0xaf, 0xeb, 0x7b, 0xfa, 0x0a, 0x5e, 0x54, 0x2b, 0xbe, 0x16, 0x2a,
-	0x89, 0x43, 0x67, 0xec, 0x4c, 0x3a, 0x6c, 0x83, 0xf1, 0x6e, 0xc9, 0xb5, 0x5e, 0x89, 0x24, 0x0e,
-	0xdd, 0xe6, 0xce, 0x62, 0x7a, 0x0c, 0x5d, 0x2d, 0x1f, 0x44, 0x19, 0xc2, 0xd8, 0x99, 0xf4, 0x59,
-	0x03, 0xa2, 0x0f, 0x10, 0xa4, 0xd3, 0xd9, 0x2e, 0xff, 0x19, 0xf4, 0x85, 0x52, 0x52, 0xcd, 0x64,
-	0x26, 0x8c, 0x40, 0x30, 0x0d, 0xce, 0xab, 0xe5, 0xf9, 0x9c, 0xb1, 0x5b, 0xb6, 0x98, 0xdd, 0xc6,
-	0x73, 0xb6, 0xfd, 0x20, 0xfa, 0x03, 0x23, 0xcc, 0xbf, 0x92, 0x79, 0xc9, 0xa4, 0x2c, 0x90, 0xe0,
-	0x0d, 0x80, 0x92, 0xb2, 0x48, 0x05, 0xd7, 0x49, 0x66, 0x18, 0xba, 0x6c, 0x27, 0x42, 0x5f, 0x42,
-	0x4f, 0xea, 0x5f, 0x42, 0xd5, 0xa1, 0x3b, 0xf6, 0x26, 0x1d, 0xd6, 0x22, 0x4a, 0xa1, 0x53, 0x29,
-	0x59, 0x87, 0xde, 0xd8, 0x9b, 0x74, 0x99, 0x39, 0x1b, 0x2e, 0x5e, 0x66, 0x98, 0x2b, 0xb2, 0xb0,
-	0xd3, 0x72, 0x6d, 0x22, 0xd1, 0x19, 0x0c, 0x51, 0x3e, 0xd5, 0x5c, 0x19, 0xf3, 0xaf, 0xa1, 0xaf,
-	0xf3, 0x42, 0xa4, 0x9a, 0x17, 0x95, 0x91, 0xf6, 0xd8, 0x36, 0x10, 0x9d, 0xc0, 0x08, 0x9b, 0x79,
-	0xa7, 0xe4, 0xbd, 0x12, 0x75, 0x8d, 0x09, 0x04, 0xbc, 0x4a, 0xc9, 0xd6, 0x25, 0x1e, 0xa3, 0x8b,
-	0xa6, 0xa2, 0xdd, 0x8f, 0x02, 0x70, 0xf3, 0xac, 0x6d, 0xb6, 0x9b, 0x67, 0x36, 0xc9, 0xdd, 0x26,
-	0x7d, 0x85, 0x21, 0x32, 0x27, 0x65, 0xf5, 0xa8, 0x5b, 0xda, 0x3a, 0xb7, 0xc5, 0xe3, 0x91, 0x0e,
-	0xc1, 0x79, 0x6a, 0x33, 0x9c, 0x27, 0x44, 0xeb, 0xd0, 0x6b, 0xd0, 0x9a, 0x86, 0x70, 0xf8, 0x53,
-	0xf1, 0x02, 0xa7, 0x86, 0x25, 0xfa, 0xcc, 0xc2, 0x28, 0x87, 0xbe, 0xe1, 0x8c, 0xb9, 0xe6, 0xff,
-	0xb3, 0x81, 0x22, 0xee, 0x33, 0x11, 0x6f, 0x4f, 0xa4, 0x63, 0x45, 0xf6, 0xc7, 0xd2, 0x7d, 0x3e,
-	0x96, 0xe8, 0x0a, 0xfa, 0x9f, 0x50, 0xd5, 0x48, 0xed, 0x38, 0x72, 0xf6, 0x1c, 0xd1, 0x13, 0xe8,
-	0xe6, 0xe8, 0xc8, 0x0c, 0x6f, 0x30, 0xf5, 0x71, 0x35, 0x36, 0x16, 0x59, 0x73, 0x17, 0xbd, 0x6f,
-	0xc6, 0x62, 0xf8, 0xb0, 0x1d, 0x6f, 0xa1, 0x67, 0xf2, 0xeb, 0xd0, 0xd9, 0x66, 0x6d, 0xd4, 0x58,
-	0x7b, 0x19, 0xbd, 0x03, 0x1f, 0xbb, 0xc8, 0x44, 0xfd, 0xb8, 0xb2, 0xbb, 0xfe, 0x3b, 0x2f, 0xcb,
-	0xdd, 0x5d, 0xb7, 0xf8, 0xf4, 0xaf, 0x03, 0x6e, 0x12, 0x53, 0x1f, 0xfa, 0xd7, 0xe9, 0xe5, 0xe2,
-	0xe3, 0xfc, 0x32, 0xb9, 0x21, 0x07, 0x74, 0x04, 0x03, 0x84, 0xed, 0x3e, 0x13, 0x87, 0x1e, 0x81,
-	0x8f, 0x81, 0xcf, 0x82, 0x2b, 0xbd, 0x14, 0x5c, 0x13, 0x97, 0x12, 0x18, 0x62, 0xc8, 0xee, 0x2c,
-	0x01, 0x1b, 0xb1, 0x33, 0x27, 0x03, 0x4b, 0xcb, 0x04, 0xcf, 0xd6, 0x64, 0x68, 0xa1, 0xd9, 0x33,
-	0xe2, 0x5b, 0x68, 0x2a, 0x20, 0x81, 0x85, 0xa6, 0x0d, 0x64, 0x44, 0x03, 0x80, 0x26, 0x17, 0xcb,
-	0x20, 0xc4, 0x5e, 0xcf, 0x56, 0xb2, 0x16, 0xe4, 0x88, 0x0e, 0xe0, 0x10, 0xe1, 0xfc, 0x26, 0x26,
-	0xc7, 0xa7, 0xdf, 0x00, 0xb6, 0x0f, 0x8b, 0x02, 0xf4, 0xe6, 0x8c, 0x2d, 0x6e, 0x1f, 0xc8, 0x01,
-	0x7a, 0xc2, 0xf3, 0x8d, 0xbc, 0x33, 0xaf, 0x9b, 0x38, 0xc8, 0xdb, 0x44, 0x8c, 0x6b, 0x17, 0x4b,
-	0x43, 0x8c, 0x28, 0xd5, 0x5c, 0x0b, 0xe2, 0xa1, 0x14, 0x86, 0xbe, 0xe0, 0xdb, 0x26, 0x9d, 0x65,
-	0xcf, 0xfc, 0x48, 0x2e, 0xfe, 0x05, 0x00, 0x00, 0xff, 0xff, 0x09, 0x9c, 0x66, 0x48, 0x59, 0x04,
-	0x00, 0x00,
+	// 1619 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x57, 0xcd, 0x6e, 0x23, 0xc7,
+	0x11, 0xde, 0xe1, 0x9f, 0xc4, 0x12, 0x49, 0xb5, 0x7a, 0xd7, 0xeb, 0x89, 0xb3, 0x30, 0x84, 0x71,
+	0x12, 0x28, 0x72, 0xb0, 0x07, 0x19, 0x01, 0x72, 0xc9, 0x81, 0x2b, 0x72, 0x6d, 0x3a, 0x5a, 0x69,
+	0xd3, 0xa3, 0x75, 0xe0, 0x1c, 0x2c, 0xb4, 0x38, 0x2d, 0x69, 0xc2, 0xe1, 0xf4, 0x78, 0xa6, 0x29,
+	0xaf, 0x10, 0x04, 0xc8, 0x25, 0x08, 0x90, 0x63, 0x9e, 0x21, 0x2f, 0x92, 0x27, 0xf0, 0x13, 0xe4,
+	0x96, 0x07, 0x09, 0xaa, 0xba, 0x9b, 0x33, 0x94, 0xd6, 0x8b, 0x1c, 0x7c, 0xeb, 0xef, 0xeb, 0xfa,
+	0xeb, 0xaa, 0xea, 0xea, 0x19, 0x18, 0x2e, 0x55, 0x55, 0xc9, 0x6b, 0xf5, 0xbc, 0x28, 0xb5, 0xd1,
+	0xbc, 0x55, 0x5c, 0x46, 0xdf, 0xc0, 0xe8, 0xf8, 0x28, 0xbe, 0x38, 0xd6, 0x79, 0xae, 0xe6, 0xe6,
+	0x55, 0x75, 0xcd, 0x3f, 0x82, 0xed, 0x22, 0x93, 0x77, 0xaa, 0x9c, 0x4d, 0xc2, 0x60, 0x3f, 0x38,
+	0xe8, 0x88, 0x35, 0xc6, 0xbd, 0x4b, 0x69, 0x4c, 0xa6, 0x66, 0x93, 0xb0, 0x65, 0xf7, 0x3c, 0xe6,
+	0x4f, 0xa0, 0x6b, 0xf4, 0x42, 0xe5, 0x21, 0xec, 0x07, 0x07, 0x7d, 0x61, 0x41, 0xf4, 0x47, 0x18,
+	0xc5, 0x47, 0xc7, 0x4d, 0xfb, 0xbf, 0x82, 0xbe, 0x2a, 0x4b, 0x5d, 0x1e, 0xeb, 0x44, 0x91, 0x83,
+	0xd1, 0xd1, 0xe8, 0x79, 0x71, 0xf9, 0x7c, 0x2a, 0xc4, 0x99, 0xb8, 0x38, 0x3e, 0x9b, 0x4c, 0x45,
+	0x2d, 0x80, 0x1e, 0xf5, 0x65, 0xa5, 0xca, 0x5b, 0x55, 0x92, 0xc7, 0x6d, 0xb1, 0xc6, 0xd1, 0xbf,
+	0x02, 0x60, 0x18, 0xfc, 0x17, 0x4a, 0x96, 0xe6, 0x52, 0x49, 0x32, 0xff, 0x33, 0x18, 0xca, 0xf9,
+	0xe2, 0x65, 0x29, 0x97, 0xea, 0x58, 0xaf, 0x72, 0x43, 0x2e, 0x86, 0x62, 0x93, 0xe4, 0x1f, 0x03,
+	0xcc, 0xb3, 0x54, 0xe5, 0xe6, 0x3c, 0x5d, 0x2a, 0x32, 0xdc, 0x16, 0x0d, 0x86, 0xff, 0x02, 0x46,
+	0x99, 0xac, 0x4c, 0x4c, 0x8e, 0x48, 0xa6, 0x4d, 0x32, 0xf7, 0x58, 0x1e, 0xc1, 0x00, 0x19, 0xa1,
+	0xe6, 0xb7, 0x24, 0xd5, 0x21, 0xa9, 0x0d, 0x2e, 0xfa, 0x6f, 0x00, 0x0c, 0x73, 0xb0, 0x11, 0xe6,
+	0x66, 0x00, 0xc1, 0xbb, 0x02, 0xb0, 0xa7, 0x5c, 0x9b, 0xb6, 0x41, 0xde, 0x63, 0x6b, 0xb9, 0x58,
+	0xe5, 0x49, 0x33, 0xd0, 0x4d, 0x96, 0x87, 0xb0, 0x75, 0x85, 0xc7, 0x9f, 0x4d, 0x28, 0xc6, 0xa1,
+	0xf0, 0x90, 0x3f, 0x83, 0x3e, 0x2d, 0x49, 0xb9, 0x4b, 0xca, 0x35, 0xc1, 0x9f, 0x42, 0x4f, 0x5f,
+	0x5d, 0x55, 0xca, 0x84, 0x3d, 0xda, 0x72, 0x88, 0x33, 0x68, 0x97, 0xc6, 0x84, 0x5b, 0x44, 0xe2,
+	0x32, 0x3a, 0x02, 0xc0, 0x62, 0x8c, 0xe7, 0x8b, 0xff, 0xbb, 0x0c, 0xd1, 0x5f, 0x60, 0x17, 0x33,
+	0xf3, 0xa5, 0x4e, 0x73, 0xa1, 0xf5, 0xd2, 0x25, 0xa6, 0xd4, 0x7a, 0x19, 0x2b, 0x69, 0x66, 0x09,
+	0x69, 0x75, 0x45, 0x83, 0xa1, 0x80, 0xcc, 0x8d, 0x2a, 0xab, 0xb0, 0xb5, 0xdf, 0x3e, 0xe8, 0x08,
+	0x87, 0x38, 0x87, 0x4e, 0x51, 0xea, 0x2a, 0x6c, 0xef, 0xb7, 0x0f, 0xba, 0x82, 0xd6, 0x64, 0x4b,
+	0xe6, 0x09, 0xea, 0xaa, 0x24, 0xec, 0x38, 0x5b, 0x6b, 0x26, 0xba, 0x81, 0x01, 0xba, 0x8f, 0x8d,
+	0x2c, 0xa9, 0x28, 0xcf, 0xa0, 0x6f, 0xd2, 0xa5, 0x8a, 0x8d, 0x5c, 0x16, 0xae, 0x26, 0x35, 0x81,
+	0xbb, 0x15, 0x4a, 0x36, 0xaa, 0x51, 0x13, 0x36, 0x8d, 0xea, 0xdb, 0x95, 0xca, 0xe7, 0x77, 0x54,
+	0x83, 0xa1, 0xa8, 0x89, 0xe8, 0x13, 0xd8, 0xc5, 0xe4, 0xbc, 0x2e, 0xf5, 0x75, 0xa9, 0xaa, 0x0a,
+	0x9d, 0x31, 0x68, 0x17, 0xa5, 0x76, 0x27, 0xc4, 0x65, 0xf4, 0x99, 0xcd, 0x46, 0x53, 0x68, 0x04,
+	0xad, 0x34, 0x71, 0xd7, 0xb0, 0x95, 0x26, 0x5e, 0xa9, 0x55, 0x2b, 0x7d, 0x05, 0x03, 0xb4, 0x3c,
+	0xcb, 0x8b, 0x95, 0x71, 0x66, 0xab, 0xd4, 0x27, 0x0e, 0x97, 0x7c, 0x00, 0xc1, 0x5b, 0xa7, 0x11,
+	0xbc, 0x45, 0x64, 0xe3, 0xeb, 0x8a, 0xe0, 0xee, 0x87, 0xdb, 0x22, 0xfa, 0x33, 0xf4, 0xc9, 0xe6,
+	0x44, 0x1a, 0xf9, 0xae, 0x30, 0xd0, 0x49, 0xeb, 0x9e, 0x93, 0xf6, 0x86, 0x93, 0x8e, 0x77, 0xb2,
+	0x59, 0xd2, 0xee, 0x83, 0x92, 0xa2, 0x35, 0xf5, 0x2d, 0x35, 0xd8, 0x50, 0xe0, 0x32, 0xfa, 0x12,
+	0xfa, 0xd4, 0x25, 0xe4, 0xbc, 0x11, 0x63, 0xb0, 0xd9, 0xba, 0x9f, 0x40, 0x37, 0xc5, 0x18, 0xa9,
+	0x15, 0x76, 0x8e, 0x86, 0x38, 0x46, 0xd6, 0x41, 0x0b, 0xbb, 0x17, 0xfd, 0xda, 0x16, 0x99, 0xec,
+	0x61, 0x82, 0x7e, 0x0e, 0x3d, 0xd2, 0xaf, 0xc2, 0xa0, 0xd6, 0x5a, 0x7b, 0x13, 0x6e, 0x33, 0xfa,
+	0x14, 0x86, 0x98, 0x57, 0xa1, 0xaa, 0x55, 0xe6, 0xe7, 0xe2, 0x77, 0x69, 0x9e, 0x37, 0xe7, 0xa2,
+	0xc7, 0xd1, 0x6f, 0xac, 0x8f, 0xe3, 0x4c, 0x57, 0xe4, 0xe3, 0x00, 0x7a, 0xa5, 0x92, 0x95, 0xce,
+	0xdd, 0x80, 0x63, 0xe8, 0xe3, 0xf8, 0xe4, 0x2c, 0x9e, 0x5e, 0x88, 0xe9, 0x38, 0x3e, 0x3b, 0x15,
+	0x6e, 0x3f, 0x8a, 0x6d, 0x63, 0xc4, 0xb9, 0x2c, 0xaa, 0x1b, 0x4d, 0x8e, 0x7e, 0xf8, 0xbc, 0x1c,
+	0x3a, 0x37, 0xb2, 0xba, 0x71, 0xa3, 0x97, 0xd6, 0xc8, 0x25, 0xd2, 0x48, 0xca, 0xfd, 0x40, 0xd0,
+	0x1a, 0x8d, 0x52, 0x5f, 0xff, 0xa8, 0x46, 0xbf, 0x71, 0x93, 0x5c, 0x9a, 0xf9, 0xcd, 0x9b, 0xe2,
+	0xfd, 0x36, 0x3f, 0x06, 0x50, 0x79, 0xf2, 0xd2, 0x6d, 0xb6, 0x68, 0xb3, 0xc1, 0x90, 0x7d, 0x9d,
+	0xdb, 0x59, 0xb5, 0x2d, 0x68, 0x1d, 0x7d, 0x1f, 0xc0, 0x1e, 0x3a, 0xa0, 0x02, 0xc6, 0x46, 0x1a,
+	0xba, 0x00, 0x4f, 0xa1, 0x47, 0x65, 0xac, 0x9c, 0x0b, 0x87, 0xd0, 0x42, 0x26, 0x8d, 0x72, 0xb6,
+	0x69, 0x8d, 0x33, 0x67, 0x29, 0xdf, 0x9e, 0x48, 0xa3, 0x5e, 0xda, 0x02, 0xdb, 0x6b, 0xb8, 0x49,
+	0xa2, 0x94, 0xbc, 0xbd, 0x6e, 0x48, 0x61, 0x9f, 0xb6, 0xc4, 0x26, 0x89, 0x83, 0xbd, 0x54, 0x73,
+	0x95, 0x9b, 0x99, 0xf5, 0xde, 0x25, 0x53, 0x1b, 0x1c, 0xf5, 0x35, 0x61, 0xd4, 0x73, 0xed, 0xdb,
+	0x60, 0xa2, 0xbf, 0x07, 0xb6, 0x0e, 0x24, 0xee, 0xe6, 0xe2, 0xc3, 0xeb, 0x49, 0xbd, 0x82, 0x4d,
+	0x16, 0xb6, 0xea, 0x5e, 0x99, 0x9d, 0xbe, 0x7e, 0x73, 0x7e, 0x21, 0xa6, 0xf1, 0x9b, 0x93, 0x73,
+	0xe1, 0xf6, 0x9b, 0xf9, 0x6e, 0x3f, 0xc8, 0x77, 0xb6, 0x79, 0xa0, 0xa1, 0x68, 0x30, 0xd1, 0xdf,
+	0x02, 0x18, 0x62, 0x24, 0xe7, 0xe9, 0x32, 0xcd, 0xaf, 0xdd, 0xa8, 0xab, 0xc7, 0x55, 0x70, 0x6f,
+	0x5c, 0xa1, 0x3d, 0xca, 0xf3, 0x44, 0x65, 0xf2, 0xce, 0xd7, 0xaf, 0x66, 0xde, 0x13, 0xc9, 0x33,
+	0xe8, 0x93, 0xdc, 0x17, 0x3a, 0x4b, 0x5c, 0x20, 0x35, 0x11, 0xfd, 0xbb, 0x05, 0xc3, 0xd7, 0xf4,
+	0x31, 0xf1, 0xfb, 0x95, 0xcc, 0x52, 0x73, 0xf7, 0x60, 0xb2, 0xe0, 0x78, 0xcf, 0xb3, 0x34, 0x57,
+	0xee, 0xb5, 0x77, 0xc8, 0xbf, 0x37, 0xed, 0xf5, 0x7b, 0x83, 0x92, 0x7f, 0x4a, 0x8d, 0x51, 0xa5,
+	0x7b, 0x74, 0x1d, 0xc2, 0x08, 0x0a, 0x39, 0x5f, 0x28, 0x53, 0xcd, 0x72, 0x2a, 0x5b, 0x47, 0xd4,
+	0x04, 0x9e, 0xcc, 0x81, 0xb3, 0x95, 0x7d, 0xd3, 0x3a, 0xa2, 0xc1, 0xe0, 0xc9, 0x0a, 0x95, 0x27,
+	0x69, 0x7e, 0x4d, 0x6f, 0x5b, 0x57, 0x78, 0x88, 0xf7, 0xbf, 0x52, 0xd7, 0x4b, 0x95, 0x9b, 0x2a,
+	0xdc, 0xb6, 0xf7, 0xdf, 0x63, 0xbe, 0x0f, 0x3b, 0xa5, 0x32, 0xa5, 0xcc, 0xab, 0x65, 0x6a, 0xaa,
+	0xb0, 0x4f, 0xdb, 0x4d, 0x0a, 0xa3, 0x2d, 0x64, 0x99, 0x9a, 0x3b, 0xfa, 0x3c, 0xea, 0x08, 0x87,
+	0xa8, 0x8f, 0x75, 0x55, 0x85, 0x3b, 0xd4, 0x84, 0xb4, 0xc6, 0x13, 0x94, 0x6a, 0xae, 0x6f, 0x55,
+	0xa9, 0x92, 0x70, 0x60, 0x4f, 0xb0, 0x26, 0xa2, 0xdf, 0xda, 0x7b, 0xe8, 0x12, 0x88, 0xb5, 0xfc,
+	0x14, 0xb6, 0xec, 0x17, 0x9a, 0x1f, 0x69, 0x7b, 0xd8, 0x42, 0x1b, 0x79, 0x16, 0x5e, 0x22, 0xfa,
+	0x67, 0x00, 0x8f, 0xe9, 0x95, 0x21, 0x8c, 0xf7, 0x6c, 0xf5, 0xce, 0x97, 0xe6, 0x97, 0xd0, 0xab,
+	0x68, 0xd3, 0xb5, 0xa5, 0xb5, 0x79, 0x32, 0xfe, 0x7a, 0x2a, 0x2e, 0xe2, 0xf3, 0xf1, 0xf9, 0x9b,
+	0x58, 0x38, 0x81, 0xc6, 0xb4, 0x6b, 0xd7, 0x1d, 0x7c, 0x32, 0x1d, 0x7f, 0x75, 0x7f, 0xda, 0x61,
+	0x16, 0x4a, 0xb5, 0x94, 0x69, 0xee, 0x5a, 0xc3, 0xa1, 0xe8, 0x39, 0x00, 0xc6, 0xf4, 0xc2, 0xce,
+	0x2a, 0xcc, 0xe6, 0xfa, 0x75, 0xb0, 0x67, 0xea, 0x8a, 0x26, 0x75, 0xf8, 0x9f, 0x16, 0xb4, 0x66,
+	0x13, 0x3e, 0x84, 0xfe, 0xab, 0xf8, 0xf3, 0x8b, 0x17, 0xd3, 0xcf, 0x67, 0xa7, 0xec, 0x11, 0xdf,
+	0x85, 0x1d, 0x84, 0xee, 0x5b, 0x93, 0x05, 0x7c, 0x0f, 0x86, 0x48, 0xac, 0x3f, 0xbc, 0x58, 0x8b,
+	0x33, 0x18, 0x20, 0xe5, 0xbf, 0x38, 0x18, 0x78, 0xc6, 0xbf, 0xba, 0x6c, 0xc7, 0x9b, 0x15, 0x4a,
+	0x26, 0x77, 0x6c, 0xe0, 0x21, 0x7d, 0x25, 0xb0, 0xa1, 0x87, 0x74, 0xb3, 0xd8, 0xc8, 0x43, 0xba,
+	0xe3, 0x6c, 0x97, 0x8f, 0x00, 0xac, 0x2e, 0xde, 0x58, 0xc6, 0xfc, 0x36, 0xbd, 0x0c, 0x6c, 0xcf,
+	0x3b, 0xf3, 0x93, 0x99, 0xf1, 0x75, 0xd0, 0x76, 0xac, 0xb2, 0xc7, 0x7c, 0x07, 0xb6, 0x90, 0x98,
+	0x9e, 0x4e, 0xd8, 0x13, 0x0f, 0xc6, 0xf3, 0x05, 0xfb, 0x80, 0x73, 0x18, 0xad, 0x5d, 0xd1, 0x80,
+	0x64, 0x4f, 0xbd, 0x41, 0x3f, 0x62, 0xd8, 0x87, 0x3e, 0x02, 0x7b, 0xd5, 0x59, 0xe8, 0x1d, 0xb8,
+	0x46, 0x60, 0x3f, 0xe1, 0x4f, 0x80, 0xd1, 0x81, 0x1b, 0x0d, 0xc0, 0x3e, 0xf2, 0x9e, 0x5e, 0x68,
+	0xc3, 0x7e, 0x7a, 0xf8, 0xd7, 0x00, 0xa0, 0xfe, 0x1e, 0xe7, 0x00, 0xbd, 0xa9, 0x10, 0x17, 0x67,
+	0x0b, 0xf6, 0x08, 0x1d, 0xe2, 0xfa, 0x54, 0x5b, 0x7d, 0x16, 0xa0, 0x43, 0xcb, 0x50, 0x42, 0x5b,
+	0x98, 0x75, 0xc4, 0x88, 0xd0, 0xba, 0x62, 0x6d, 0xcc, 0x02, 0x52, 0xe7, 0xf8, 0x4b, 0xc0, 0x3a,
+	0x18, 0x12, 0xc2, 0x97, 0xba, 0xbc, 0x52, 0xa9, 0x61, 0x5d, 0xaf, 0x32, 0x59, 0x15, 0x59, 0x3a,
+	0x47, 0x95, 0xde, 0xe1, 0xd7, 0x30, 0x68, 0x3e, 0x98, 0x9c, 0x79, 0x7c, 0xaa, 0xcb, 0xa5, 0xcc,
+	0xd8, 0x23, 0x4c, 0x87, 0x65, 0xe2, 0x9b, 0x95, 0x49, 0xf4, 0x77, 0x39, 0x0b, 0xd0, 0xb2, 0xe5,
+	0xc6, 0x97, 0xba, 0xc4, 0x7a, 0xaf, 0x85, 0x84, 0x2a, 0x32, 0x39, 0x57, 0x09, 0x6b, 0x1f, 0x8e,
+	0x61, 0xd0, 0xec, 0x4e, 0x54, 0xb2, 0xd8, 0x56, 0xe9, 0x11, 0x86, 0x63, 0x09, 0xfc, 0xb2, 0xd3,
+	0x2b, 0x6c, 0xa5, 0x21, 0xf4, 0x2d, 0x35, 0xbe, 0x5a, 0xb0, 0xd6, 0xe1, 0x3f, 0x02, 0x18, 0x6e,
+	0x5c, 0x06, 0xbe, 0xb7, 0x26, 0xce, 0x68, 0x62, 0xd9, 0x00, 0x3d, 0x75, 0x75, 0x45, 0x5c, 0xd0,
+	0xe0, 0xc6, 0x57, 0x8b, 0x3f, 0xc8, 0x32, 0xb7, 0x09, 0x73, 0xdc, 0xef, 0xd2, 0xf9, 0x02, 0x43,
+	0x6c, 0x88, 0xf9, 0x24, 0x75, 0xf8, 0x87, 0xf0, 0xd8, 0x71, 0x42, 0xcd, 0x6d, 0x8f, 0x63, 0x85,
+	0xbb, 0x87, 0xdf, 0x07, 0x30, 0x68, 0x3e, 0x18, 0x7c, 0x00, 0xdb, 0x16, 0x53, 0xc5, 0x1e, 0xc3,
+	0xae, 0x45, 0x75, 0x7a, 0xa9, 0x68, 0x96, 0xc4, 0x97, 0x8a, 0xb5, 0xf8, 0x07, 0xb0, 0xe7, 0x4c,
+	0x48, 0xa3, 0x4e, 0xd2, 0x65, 0x6a, 0x28, 0x8e, 0x10, 0x9e, 0x58, 0x7a, 0x96, 0xdf, 0xca, 0x2c,
+	0x4d, 0x5e, 0xcb, 0xbb, 0x4c, 0xcb, 0x84, 0x75, 0xb0, 0x8b, 0xec, 0xce, 0xa9, 0x36, 0x62, 0x95,
+	0xe7, 0x14, 0x4a, 0x6d, 0xc6, 0x7e, 0xe4, 0x63, 0x3e, 0x13, 0xd6, 0xe3, 0x4f, 0x81, 0x37, 0xe8,
+	0xe9, 0x6d, 0x3a, 0x47, 0xf3, 0x5b, 0x35, 0xff, 0x4a, 0x95, 0xd7, 0x6a, 0x52, 0xea, 0xa2, 0x50,
+	0x09, 0xdb, 0xbe, 0xec, 0xd1, 0x0f, 0xea, 0x67, 0xff, 0x1b, 0x00, 0xad, 0xa6, 0xd6, 0xbd, 0xb1,
+	0x0e, 0x00, 0x00,
 }
@@ -1,6 +1,9 @@
 package protocol
 
-import "time"
+import (
+	"github.com/andyzhou/thorn/conf"
+	"time"
+)
 
 /*
  * kcp config, implement of `IConfig`
@@ -12,6 +15,8 @@ type Config struct {
 	packetReceiveChanLimit uint32        // the limit of packet receive channel
 	connReadTimeout        time.Duration // read timeout
 	connWriteTimeout       time.Duration // write timeout
+	rateLimit              int               // max packets per second per connect, 0 means no limit
+	kcpTune                *conf.KcpTuneConf // kcp tuning for new sessions
 }
 
 //construct
@@ -25,10 +30,24 @@ func NewConfig(
 		packetReceiveChanLimit:receiveChanLimit,
 		connReadTimeout:readTimeOut,
 		connWriteTimeout:writeTimeOut,
+		kcpTune:conf.NewKcpTuneConf(),
 	}
 	return this
 }
 
+//set rate limit
+func (f *Config) SetRateLimit(limit int) {
+	f.rateLimit = limit
+}
+
+//set kcp tuning
+func (f *Config) SetKcpTune(tune *conf.KcpTuneConf) {
+	if tune == nil {
+		return
+	}
+	f.kcpTune = tune
+}
+
 func (f *Config) GetPacketSendChanLimit() uint32 {
 	return f.packetSendChanLimit
 }
@@ -43,4 +62,12 @@ func (f *Config) GetConnReadTimeout() time.Duration {
 
 func (f *Config) GetConnWriteTimeout() time.Duration {
 	return f.connWriteTimeout
+}
+
+func (f *Config) GetRateLimit() int {
+	return f.rateLimit
+}
+
+func (f *Config) GetKcpTune() *conf.KcpTuneConf {
+	return f.kcpTune
 }
\ No newline at end of file
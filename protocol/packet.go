@@ -35,6 +35,7 @@ type Packet struct {
 
 type PlayerPacket struct {
 	id     uint64        //player id
+	conn   iface.IConn   //conn of packet
 	packet iface.IPacket //original packet
 }
 
@@ -49,6 +50,10 @@ func (f *PlayerPacket) GetId() uint64 {
 	return f.id
 }
 
+func (f *PlayerPacket) GetConn() iface.IConn {
+	return f.conn
+}
+
 func (f *PlayerPacket) GetPacket() iface.IPacket {
 	return f.packet
 }
@@ -57,6 +62,10 @@ func (f *PlayerPacket) SetId(id uint64) {
 	f.id = id
 }
 
+func (f *PlayerPacket) SetConn(conn iface.IConn) {
+	f.conn = conn
+}
+
 func (f *PlayerPacket) SetPacket(packet iface.IPacket) {
 	f.packet = packet
 }
@@ -108,11 +117,16 @@ func NewPacketWithPara(
 }
 
 //pack data
+//nil if data over MaxPacketLen, reader refuses it.
 func (f *Packet) Pack() []byte {
 	//basic check
 	if f.id < 0 {
 		return nil
 	}
+	if len(f.data) > MaxPacketLen {
+		log.Printf("Packet.Pack, message %d data %d bytes over max %d\n", f.id, len(f.data), MaxPacketLen)
+		return nil
+	}
 
 	//init data buff
 	//dataBuff := bytes.NewBuffer(nil)
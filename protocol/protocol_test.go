@@ -0,0 +1,73 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/andyzhou/thorn/define"
+	"github.com/andyzhou/thorn/pb"
+	"github.com/golang/protobuf/proto"
+	"math"
+	"testing"
+)
+
+func TestSnapshotPacket(t *testing.T) {
+	data := bytes.Repeat([]byte{0xab}, define.MaxSnapshotSize)
+	tests := []struct {
+		name string
+		msg  proto.Message
+		out  proto.Message
+	}{
+		{"upload", &pb.C2S_SnapshotMsg{FrameID:math.MaxUint32, Hash:math.MaxUint64, Data:data}, &pb.C2S_SnapshotMsg{}},
+		{"download", &pb.S2C_SnapshotMsg{FrameID:math.MaxUint32, Hash:math.MaxUint64, Data:data}, &pb.S2C_SnapshotMsg{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			//max size snapshot fits in one packet
+			buff := NewPacketWithPara(uint8(pb.ID_MSG_Snapshot), test.msg).Pack()
+			if buff == nil {
+				t.Fatal("snapshot of max size not packed")
+			}
+			packet, err := NewProtocol().ReadPacket(bytes.NewReader(buff))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pb.ID(packet.GetMessageId()) != pb.ID_MSG_Snapshot {
+				t.Fatalf("message id %d", packet.GetMessageId())
+			}
+			if err = packet.UnmarshalPB(test.out); err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(test.msg, test.out) {
+				t.Fatal("snapshot changed by packet")
+			}
+		})
+	}
+}
+
+func TestOversizePacket(t *testing.T) {
+	tests := []struct {
+		name    string
+		dataLen int
+		packed  bool
+	}{
+		{"max", MaxPacketLen, true},
+		{"over max", MaxPacketLen + 1, false},
+		{"over uint16", math.MaxUint16 + 1, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			packet := NewPacketWithPara(uint8(pb.ID_MSG_Snapshot), make([]byte, test.dataLen))
+			if got := packet.Pack() != nil; got != test.packed {
+				t.Fatalf("packed %v, want %v", got, test.packed)
+			}
+		})
+	}
+
+	//reader refuses oversize length in header
+	header := make([]byte, MinPacketLen)
+	binary.BigEndian.PutUint16(header, MaxPacketLen + 1)
+	buff := append(header, make([]byte, MaxPacketLen + 1)...)
+	if _, err := NewProtocol().ReadPacket(bytes.NewReader(buff)); err == nil {
+		t.Fatal("oversize packet read")
+	}
+}
@@ -0,0 +1,194 @@
+package thorn
+
+import (
+	"errors"
+	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/logger"
+	"github.com/andyzhou/thorn/protocol"
+	"time"
+)
+
+/*
+ * hot conf reload for server
+ * - log level, rate limit, admin auth keys, metrics switch
+ * - kcp tuning for new sessions, room policy defaults
+ */
+
+//reload conf file, all values apply or none
+func (f *Server) Reload() error {
+	f.reloadLock.Lock()
+	defer f.reloadLock.Unlock()
+
+	//load and apply
+	err := f.reload()
+	f.updateReloadStatus(err)
+	return err
+}
+
+//apply loaded conf, for run without conf file
+//all values apply or none.
+func (f *Server) ApplyFileConf(cfg *conf.FileConf) error {
+	f.reloadLock.Lock()
+	defer f.reloadLock.Unlock()
+
+	//check and apply
+	err := cfg.Check()
+	if err == nil {
+		err = f.applyFileConf(cfg)
+	}
+	f.updateReloadStatus(err)
+	return err
+}
+
+//get last reload status
+func (f *Server) GetReloadStatus() ReloadStatus {
+	f.reloadLock.Lock()
+	defer f.reloadLock.Unlock()
+	return f.reloadStatus
+}
+
+//////////////
+//private func
+//////////////
+
+//get current file conf, maybe nil
+func (f *Server) getFileConf() *conf.FileConf {
+	cfg, _ := f.fileConf.Load().(*conf.FileConf)
+	return cfg
+}
+
+//update reload status
+func (f *Server) updateReloadStatus(err error) {
+	f.reloadStatus.Time = time.Now().Unix()
+	f.reloadStatus.Ok = err == nil
+	f.reloadStatus.Error = ""
+	if err != nil {
+		f.reloadStatus.Error = err.Error()
+		logger.Errorf("Server:Reload failed, file:%v, err:%v\n", f.conf.ConfFile, err)
+	}else{
+		f.reloadStatus.Count++
+		logger.Infof("Server:Reload success, file:%v\n", f.conf.ConfFile)
+	}
+}
+
+//load conf file and apply
+func (f *Server) reload() error {
+	//check
+	if f.conf.ConfFile == "" {
+		return errors.New("no conf file")
+	}
+
+	//load file
+	cfg, err := conf.LoadFileConf(f.conf.ConfFile)
+	if err != nil {
+		return err
+	}
+	return f.applyFileConf(cfg)
+}
+
+//apply file conf
+func (f *Server) applyFileConf(cfg *conf.FileConf) error {
+	var (
+		err error
+	)
+	//check
+	if cfg == nil {
+		return errors.New("invalid parameter")
+	}
+	if f.kcp == nil {
+		return errors.New("server is stopped")
+	}
+
+	//prepare values before apply
+	level := logger.GetLevel()
+	if cfg.LogLevel != "" {
+		level, err = logger.ParseLevel(cfg.LogLevel)
+		if err != nil {
+			return err
+		}
+	}
+	oldConfig := f.kcp.GetConfig()
+	config := protocol.NewConfig(
+			oldConfig.GetPacketSendChanLimit(),
+			oldConfig.GetPacketReceiveChanLimit(),
+			oldConfig.GetConnReadTimeout(),
+			oldConfig.GetConnWriteTimeout(),
+		)
+	config.SetRateLimit(cfg.RateLimit)
+	config.SetKcpTune(cfg.Kcp)
+
+	//apply
+	logger.SetLevel(level)
+	f.kcp.SetConfig(config)
+	f.fileConf.Store(cfg)
+	return nil
+}
+
+//fill room conf with policy defaults
+func (f *Server) applyRoomPolicy(cfg *conf.RoomConf) {
+	fileConf := f.getFileConf()
+	if fileConf == nil || fileConf.Room == nil {
+		return
+	}
+	policy := fileConf.Room
+	if cfg.MaxPlayers <= 0 {
+		cfg.MaxPlayers = policy.MaxPlayers
+	}
+	if cfg.Frequency <= 0 {
+		cfg.Frequency = policy.Frequency
+	}
+	if cfg.TimeLimit <= 0 {
+		cfg.TimeLimit = policy.TimeLimit
+	}
+	if cfg.NotifyTime <= 0 {
+		cfg.NotifyTime = policy.NotifyTime
+	}
+	if cfg.MaxFrameMemory <= 0 {
+		cfg.MaxFrameMemory = policy.MaxFrameMemory
+	}
+	if cfg.FrameRetention == "" {
+		cfg.FrameRetention = policy.FrameRetention
+	}
+	if cfg.CatchUpRate <= 0 {
+		cfg.CatchUpRate = policy.CatchUpRate
+	}
+	if cfg.InputRateLimit <= 0 {
+		cfg.InputRateLimit = policy.InputRateLimit
+	}
+	if cfg.InputPolicy == "" {
+		cfg.InputPolicy = policy.InputPolicy
+	}
+	if cfg.InputsPerFrame <= 0 {
+		cfg.InputsPerFrame = policy.InputsPerFrame
+	}
+	if cfg.InputDelay <= 0 {
+		cfg.InputDelay = policy.InputDelay
+	}
+	if !cfg.AdaptiveTiming {
+		cfg.AdaptiveTiming = policy.AdaptiveTiming
+	}
+	if cfg.MaxInputHold <= 0 {
+		cfg.MaxInputHold = policy.MaxInputHold
+	}
+	if cfg.HeartbeatTimeout <= 0 {
+		cfg.HeartbeatTimeout = policy.HeartbeatTimeout
+	}
+	if cfg.TimeoutAction == "" {
+		cfg.TimeoutAction = policy.TimeoutAction
+	}
+	if cfg.AfkTimeout <= 0 {
+		cfg.AfkTimeout = policy.AfkTimeout
+	}
+	if cfg.AfkAction == "" {
+		cfg.AfkAction = policy.AfkAction
+	}
+	if cfg.ReconnectGrace <= 0 {
+		cfg.ReconnectGrace = policy.ReconnectGrace
+	}
+	if cfg.GraceOutcome == "" {
+		cfg.GraceOutcome = policy.GraceOutcome
+	}
+	if cfg.DuplicateLogin == "" {
+		cfg.DuplicateLogin = policy.DuplicateLogin
+	}
+}
@@ -32,10 +32,40 @@ func (f *Frame) GetData() []*pb.InputData {
 	return f.data
 }
 
+//add data, ordered by room seat id then seq
 func (f *Frame) AddData(data *pb.InputData)bool {
 	f.Lock()
 	defer f.Unlock()
-	f.data = append(f.data, data)
+	pos := len(f.data)
+	for i, v := range f.data {
+		if v.GetRoomSeatId() > data.GetRoomSeatId() ||
+			(v.GetRoomSeatId() == data.GetRoomSeatId() && v.GetSeq() > data.GetSeq()) {
+			pos = i
+			break
+		}
+	}
+
+	//copy on write, data got before not changed
+	result := make([]*pb.InputData, 0, len(f.data) + 1)
+	result = append(result, f.data[:pos]...)
+	result = append(result, data)
+	f.data = append(result, f.data[pos:]...)
+	return true
+}
+
+//replace data at pos
+func (f *Frame) SetData(pos int, data *pb.InputData) bool {
+	f.Lock()
+	defer f.Unlock()
+	if pos < 0 || pos >= len(f.data) {
+		return false
+	}
+
+	//copy on write
+	result := make([]*pb.InputData, len(f.data))
+	copy(result, f.data)
+	result[pos] = data
+	f.data = result
 	return true
 }
 
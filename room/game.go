@@ -2,35 +2,69 @@ package room
 
 import (
 	"fmt"
+	"github.com/andyzhou/thorn/clock"
 	"github.com/andyzhou/thorn/define"
 	"github.com/andyzhou/thorn/iface"
+	"github.com/andyzhou/thorn/logger"
 	"github.com/andyzhou/thorn/pb"
 	"github.com/andyzhou/thorn/protocol"
+	"github.com/golang/protobuf/proto"
 	"log"
 	"reflect"
 	"runtime"
+	"sort"
 	"sync"
 	"sync/atomic"
-	"time"
 )
 
 /*
  * game face, implement of IGame
  */
 
+//tag and length bytes of one frame in S2C_FrameMsg
+const frameFieldOverhead = 3
+
 //face info
 type Game struct {
-	id          uint64 //room id
-	startTime   int64
-	randSeed    int32
-	state       int
-	gl          iface.IGameListener //original game listener
-	logic       iface.ILockStep
-	players     sync.Map //player map, playerId -> IPlayer
-	playerCount int32
-	frameCount  uint32
-	result      map[uint64]uint64
-	dirty       bool
+	id               uint64 //room id
+	startTime        int64
+	startTimeMs      int64  //start time of frame 0, unix ms
+	tickTime         int64  //time of last tick, unix ms
+	randSeed         int32
+	state            int
+	gl               iface.IGameListener //original game listener
+	logic            iface.ILockStep
+	snapshots        iface.ISnapshotStore
+	players          sync.Map //player map, playerId -> IPlayer
+	playerCount      int32
+	frameCount       uint32
+	result           map[uint64]uint64
+	replaySink       iface.IReplaySink
+	inputMerger      iface.IInputMerger //for merge input policy
+	botProvider      iface.IBotProvider //inputs for offline seats
+	retention        string             //frame retention policy
+	catchUpRate      int                //backlog frames per tick
+	inputRateLimit   int                //max inputs per second per player, 0 means no limit
+	frequency        int                //current tick frequency
+	baseFrequency    int                //configured tick frequency, max of adaptive
+	inputDelay       uint32             //current input delay frames
+	baseInputDelay   uint32             //configured input delay frames, min of adaptive
+	adaptive         bool               //adapt frequency and input delay by rtt and lateness
+	adaptInputs      int                //inputs since last adapt
+	adaptLate        int                //late inputs since last adapt
+	maxInputHold     int                //max ms to hold back faster players, 0 means no equalization
+	heartbeatTimeout int64              //seconds of no heart beat, player marked disconnected
+	timeoutAction    string             //action for heart beat timeout
+	afkTimeout       int64              //seconds of no input, 0 means no afk check
+	afkAction        string             //action for afk player
+	superviseTime    int64              //second of last supervise
+	reconnectGrace   int64              //seconds seat reserved for disconnected player, 0 means no grace
+	graceOutcome     string             //outcome when grace expired
+	aborted          bool               //ended with no result
+	overReason       pb.CLOSE_REASON    //reason of game over, pushed with result
+	duplicateLogin   string             //policy for player connecting twice
+	dirty            bool
+	clock            iface.IClock
 	sync.RWMutex
 }
 
@@ -40,20 +74,32 @@ func NewGame(
 		players []uint64,
 		randSeed int32,
 		gl iface.IGameListener,
+		clk iface.IClock,
 	) *Game {
 	//self init
+	clk = clock.Or(clk)
 	this := &Game{
 		id:roomId,
 		randSeed:randSeed,
 		gl:gl,
-		startTime:time.Now().Unix(),
-		logic:NewLockStep(),
+		clock:clk,
+		startTime:clk.Now().Unix(),
+		snapshots:NewSnapshotStore(),
+		catchUpRate:define.CatchUpRate / define.RoomFrequency,
+		frequency:define.RoomFrequency,
+		baseFrequency:define.RoomFrequency,
+		heartbeatTimeout:define.HeartbeatTimeout,
+		timeoutAction:define.PlayerActionKick,
+		afkAction:define.PlayerActionWarn,
+		graceOutcome:define.GraceOutcomeContinue,
+		duplicateLogin:define.DuplicateLoginKick,
 		players:sync.Map{},
 		result:make(map[uint64]uint64),
 	}
+	this.logic = NewLockStep(0, this.onFramesEvicted)
 	//init players
 	for idx, v := range players {
-		player := NewPlayer(v, int32(idx + 1))
+		player := NewPlayer(v, int32(idx + 1), clk)
 		this.players.Store(v, player)
 	}
 	return this
@@ -90,30 +136,67 @@ func (f *Game) JoinGame(playerId uint64, conn iface.IConn) bool {
 		return false
 	}
 
-	//check conn, if conn not nil, need reset
-	if player.GetConn() != nil {
-		//TODO, multi thread issue,
-		//if call p.client.Close(),
-		//will kick entry player
-		player.GetConn().SetExtraData(nil)
+	//check forfeit
+	if player.IsForfeit() {
+		log.Printf("[game(%d)] player[%d] forfeited\n", f.id, playerId)
+		conn.AsyncWritePacket(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Connect),
+			&pb.S2C_ConnectMsg{
+				ErrorCode:pb.ERROR_CODE_ERR_Forfeit,
+			}), 0)
+		return false
+	}
+
+	//check duplicate login, timed out session always replaced
+	old := player.GetConn()
+	replaced := old != nil && !player.IsTimedOut()
+	if replaced {
+		switch f.duplicateLogin {
+		case define.DuplicateLoginReject:
+			log.Printf("[game(%d)] player[%d] online, reject new session\n", f.id, playerId)
+			msg.ErrorCode = pb.ERROR_CODE_ERR_Duplicate
+			conn.AsyncWritePacket(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Connect), msg), 0)
+			return false
+		case define.DuplicateLoginObserve:
+			return f.joinObserver(player, conn)
+		}
+	}
+	if old != nil {
+		//kick old session, its messages and close ignored
 		log.Printf("[game(%d)] player[%d] replace\n", f.id, playerId)
+		old.SetExtraData(nil)
+		old.AsyncWritePacket(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Close), &pb.S2C_CloseMsg{
+			Reason:pb.CLOSE_REASON_CLOSE_Replaced,
+		}), 0)
+		closeConn(old)
 	}
 
 	//sync conn
+	reconnecting := player.GetDisconnectTime() > 0
 	player.Connect(conn)
 
 	//send message to player
 	player.SendMessage(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Connect), msg))
+	if reconnecting {
+		//back in reconnect grace
+		f.broadcastPlayerStatus(player, pb.PLAYER_STATUS_PLAYER_Online, pb.LEAVE_REASON_LEAVE_Close)
+	}
 
 	//call cb of game listener
 	//this is the callback of room face
 	f.gl.OnJoinGame(conn, f.id, playerId)
-	atomic.AddInt32(&f.playerCount, 1)
+	if !replaced {
+		atomic.AddInt32(&f.playerCount, 1)
+	}
 	return true
 }
 
 //leave game
 func (f *Game) LeaveGame(playerId uint64) bool {
+	return f.LeaveGameByConn(playerId, nil)
+}
+
+//leave game by closed conn, skip if conn replaced by new session
+func (f *Game) LeaveGameByConn(playerId uint64, conn iface.IConn) bool {
 	//basic check
 	if playerId <= 0 {
 		return false
@@ -124,19 +207,21 @@ func (f *Game) LeaveGame(playerId uint64) bool {
 	if player == nil {
 		return false
 	}
-
-	//clean up
-	player.CleanUp()
-
-	//call cb of game listener
-	//this is the callback of room face
-	f.gl.OnLeaveGame(f.id, playerId)
-	atomic.AddInt32(&f.playerCount, -1)
+	if conn != nil && player.GetConn() != conn {
+		logger.Debugf("[game(%d)] player[%d] old session closed\n", f.id, playerId)
+		return true
+	}
+	f.leaveGame(player, pb.LEAVE_REASON_LEAVE_Close)
 	return true
 }
 
 //process message
 func (f *Game) ProcessMessage(playerId uint64, packet iface.IPacket) bool {
+	return f.ProcessMessageByConn(playerId, nil, packet)
+}
+
+//process message, skip if conn is not current session of player
+func (f *Game) ProcessMessageByConn(playerId uint64, conn iface.IConn, packet iface.IPacket) bool {
 	//basic check
 	if playerId <= 0 || packet == nil {
 		return false
@@ -147,10 +232,21 @@ func (f *Game) ProcessMessage(playerId uint64, packet iface.IPacket) bool {
 	if player == nil {
 		return false
 	}
+	if conn != nil && player.GetConn() != conn {
+		//replaced or observer session
+		logger.Debugf("[game(%d)] processMsg player[%d] msg=[%d] not current session\n",
+					f.id, player.GetId(), packet.GetMessageId())
+		return false
+	}
 
-	log.Printf("[game(%d)] processMsg player[%d] msg=[%d]\n",
+	logger.Debugf("[game(%d)] processMsg player[%d] msg=[%d]\n",
 				f.id, player.GetId(), packet.GetMessageId())
 
+	//timed out player is back
+	if player.IsTimedOut() {
+		f.resumePlayer(player)
+	}
+
 	//get message id
 	messageId := pb.ID(packet.GetMessageId())
 
@@ -204,8 +300,35 @@ func (f *Game) ProcessMessage(playerId uint64, packet iface.IPacket) bool {
 
 	case pb.ID_MSG_Heartbeat://heart beat
 		{
-			player.SendMessage(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Heartbeat), nil))
+			recvTime := f.clock.Now().UnixMilli()
 			player.RefreshHeartbeatTime()
+
+			//ack and clock sync piggybacked, optional
+			msg := &pb.C2S_HeartbeatMsg{}
+			if len(packet.GetData()) > 0 {
+				if err := packet.UnmarshalPB(msg); err != nil {
+					msg = &pb.C2S_HeartbeatMsg{}
+				}
+			}
+			if msg.GetAckFrameCount() > 0 {
+				f.doAck(player, msg.GetAckFrameCount())
+			}
+			if msg.GetLastServerTime() > 0 {
+				player.EndClockSync(msg.GetLastServerTime(), msg.GetLastRecvTime())
+			}
+			f.replyHeartbeat(player, msg, recvTime)
+		}
+
+	case pb.ID_MSG_Ack://frame ack
+		{
+			msg := &pb.C2S_AckMsg{}
+			if err := packet.UnmarshalPB(msg); nil != err {
+				log.Printf("[game(%d)] processMsg player[%d] msg=[%d] UnmarshalPB error:[%s]\n",
+							f.id, player.GetId(), packet.GetMessageId(), err.Error())
+				return false
+			}
+			f.doAck(player, msg.GetAckFrameCount())
+			player.EndRTTProbe(msg.GetAckFrameCount(), f.clock.Now().UnixMilli())
 		}
 
 	case pb.ID_MSG_Ready://ready
@@ -228,12 +351,18 @@ func (f *Game) ProcessMessage(playerId uint64, packet iface.IPacket) bool {
 			if err := packet.UnmarshalPB(msg); nil != err {
 				fmt.Printf("[game(%d)] processMsg player[%d] msg=[%d] UnmarshalPB error:[%s]\n",
 							f.id, player.GetId(), packet.GetMessageId(), err.Error())
+				f.sendInputAck(player, &pb.S2C_InputAckMsg{
+					Result:pb.INPUT_RESULT_INPUT_InvalidPayload,
+				})
 				return false
 			}
 			//push input
-			if !f.pushInput(player, msg) {
-				log.Printf("[game(%d)] processMsg player[%d] msg=[%d] pushInput failed\n",
-							f.id, player.GetId(), packet.GetMessageId())
+			player.RefreshInputTime()
+			ack := f.pushInput(player, msg)
+			f.sendInputAck(player, ack)
+			if ack.Result != pb.INPUT_RESULT_INPUT_Ok {
+				logger.Debugf("[game(%d)] processMsg player[%d] msg=[%d] pushInput failed, result:%v\n",
+							f.id, player.GetId(), packet.GetMessageId(), ack.Result)
 				break
 			}
 
@@ -241,6 +370,27 @@ func (f *Game) ProcessMessage(playerId uint64, packet iface.IPacket) bool {
 			f.dirty = true
 		}
 
+	case pb.ID_MSG_Snapshot://state snapshot
+		{
+			if f.state != define.Gaming {
+				break
+			}
+			msg := &pb.C2S_SnapshotMsg{}
+			if err := packet.UnmarshalPB(msg); nil != err {
+				log.Printf("[game(%d)] processMsg player[%d] msg=[%d] UnmarshalPB error:[%s]\n",
+							f.id, player.GetId(), packet.GetMessageId(), err.Error())
+				return false
+			}
+			//only closed frame
+			if msg.GetFrameID() >= f.logic.GetFrameCount() {
+				break
+			}
+			if f.snapshots.Push(player.GetId(), msg, f.getOnlinePlayerCount()) {
+				logger.Debugf("[game(%d)] snapshot agreed, frame:%d, hash:%x\n",
+							f.id, msg.GetFrameID(), msg.GetHash())
+			}
+		}
+
 	case pb.ID_MSG_Result://result
 		{
 			msg := &pb.C2S_ResultMsg{}
@@ -273,6 +423,7 @@ func (f *Game) Tick(now int64) bool {
 	switch f.state {
 	case define.GameReady:
 		{
+			f.supervise(now)
 			delta := now - f.startTime
 			if delta < define.MaxReadyTime {
 				if f.checkReady() {
@@ -296,6 +447,14 @@ func (f *Game) Tick(now int64) bool {
 		}
 	case define.Gaming:
 		{
+			f.supervise(now)
+			if f.aborted {
+				f.state = define.GameOver
+				f.overReason = pb.CLOSE_REASON_CLOSE_Abort
+				log.Printf("[game(%d)] game aborted, no result\n", f.id)
+				return true
+			}
+
 			if f.checkOver() {
 				f.state = define.GameOver
 				log.Printf("[game(%d)] game over successfully!!\n", f.id)
@@ -309,8 +468,25 @@ func (f *Game) Tick(now int64) bool {
 			}
 
 			//other logic
+			f.pushBotInputs()
 			f.logic.Tick()
+			f.tickTime = f.clock.Now().UnixMilli()
 			f.broadcastFrameData()
+			f.streamCatchUps()
+			if f.logic.GetFrameCount() % define.InputStatsTicks == 0 {
+				f.reportInputStats()
+			}
+			if f.adaptive && f.logic.GetFrameCount() % define.AdaptiveTimingTicks == 0 {
+				f.adaptTiming()
+			}
+			if f.maxInputHold > 0 && f.logic.GetFrameCount() % define.EqualizeLatencyTicks == 0 {
+				f.equalizeLatency()
+			}
+			if f.logic.GetFrameCount() % define.QualityTicks == 0 {
+				f.broadcast(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Quality), &pb.S2C_QualityMsg{
+					Players:f.GetQuality(),
+				}))
+			}
 			return true
 		}
 	case define.GameOver:
@@ -328,17 +504,238 @@ func (f *Game) Tick(now int64) bool {
 	return false
 }
 
-//get result
+//get result, empty if aborted
 func (f *Game) GetResult() map[uint64]uint64 {
+	if f.aborted {
+		return map[uint64]uint64{}
+	}
 	return f.result
 }
 
+//get reason of game over
+func (f *Game) GetOverReason() pb.CLOSE_REASON {
+	return f.overReason
+}
+
 //close game
 func (f *Game) Close() {
-	packet := protocol.NewPacketWithPara(uint8(pb.ID_MSG_Close), nil)
+	f.CloseWithReason(pb.CLOSE_REASON_CLOSE_Normal)
+}
+
+//close game with reason
+//game not over yet stopped by shutdown pushes partial result and frames into sinks.
+func (f *Game) CloseWithReason(reason pb.CLOSE_REASON) {
+	if f.aborted && reason == pb.CLOSE_REASON_CLOSE_Normal {
+		reason = pb.CLOSE_REASON_CLOSE_Abort
+	}
+	if reason == pb.CLOSE_REASON_CLOSE_Shutdown && f.state != define.GameStop {
+		if f.state != define.GameOver {
+			f.overReason = reason
+		}
+		f.doGameOver()
+		f.state = define.GameStop
+		log.Printf("[game(%d)] game over by shutdown\n", f.id)
+	}
+	msg := &pb.S2C_CloseMsg{
+		Reason:reason,
+	}
+	packet := protocol.NewPacketWithPara(uint8(pb.ID_MSG_Close), msg)
 	f.broadcast(packet)
 }
 
+//set replay sink
+func (f *Game) SetReplaySink(sink iface.IReplaySink) {
+	f.replaySink = sink
+}
+
+//get frames not confirmed of all players
+func (f *Game) GetPlayerLags() map[uint64]uint32 {
+	lags := map[uint64]uint32{}
+	frameCount := f.logic.GetFrameCount()
+	sf := func(k, v interface{}) bool {
+		player, ok := v.(iface.IPlayer)
+		if ok && player != nil {
+			lags[player.GetId()] = f.getLag(player, frameCount)
+		}
+		return true
+	}
+	f.players.Range(sf)
+	return lags
+}
+
+//get input stats of all players
+func (f *Game) GetInputStats() map[uint64]*pb.S2C_InputStatsMsg {
+	stats := map[uint64]*pb.S2C_InputStatsMsg{}
+	sf := func(k, v interface{}) bool {
+		player, ok := v.(iface.IPlayer)
+		if ok && player != nil {
+			stats[player.GetId()] = player.GetInputStats(false)
+		}
+		return true
+	}
+	f.players.Range(sf)
+	return stats
+}
+
+//get connect quality of all players, ordered by seat
+func (f *Game) GetQuality() []*pb.PlayerQuality {
+	players := make([]iface.IPlayer, 0)
+	sf := func(k, v interface{}) bool {
+		player, ok := v.(iface.IPlayer)
+		if ok && player != nil {
+			players = append(players, player)
+		}
+		return true
+	}
+	f.players.Range(sf)
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].GetIdx() < players[j].GetIdx()
+	})
+	quality := make([]*pb.PlayerQuality, 0, len(players))
+	for _, player := range players {
+		quality = append(quality, player.GetQuality())
+	}
+	return quality
+}
+
+//set frame memory cap and retention policy
+//maxMemory 0 means default, policy "" means spill.
+func (f *Game) SetFrameRetention(maxMemory int, policy string) {
+	f.logic.SetMaxMemory(maxMemory)
+	f.retention = policy
+}
+
+//set backlog frames per tick for catching up players
+func (f *Game) SetCatchUpRate(framesPerTick int) {
+	if framesPerTick <= 0 {
+		framesPerTick = 1
+	}
+	f.catchUpRate = framesPerTick
+}
+
+//set max inputs per second per player, 0 means no limit
+func (f *Game) SetInputRateLimit(limit int) {
+	f.inputRateLimit = limit
+}
+
+//set tick frequency and input delay frames
+//adaptive mode lowers frequency and raises delay by rtt and lateness of players.
+func (f *Game) SetTiming(frequency, inputDelay int, adaptive bool) {
+	if frequency <= 0 {
+		frequency = define.RoomFrequency
+	}
+	if inputDelay < 0 {
+		inputDelay = 0
+	}
+	f.frequency = frequency
+	f.baseFrequency = frequency
+	f.inputDelay = uint32(inputDelay)
+	f.baseInputDelay = uint32(inputDelay)
+	f.adaptive = adaptive
+}
+
+//set latency equalization, hold back inputs of faster players up to maxHold ms
+//0 means off.
+func (f *Game) SetLatencyEqualization(maxHold int) {
+	if maxHold < 0 {
+		maxHold = 0
+	}
+	f.maxInputHold = maxHold
+}
+
+//set heart beat and afk supervision
+//timeout 0 means default heart beat timeout or no afk check, action "" means default.
+func (f *Game) SetSupervision(heartbeatTimeout int, timeoutAction string, afkTimeout int, afkAction string) {
+	if heartbeatTimeout > 0 {
+		f.heartbeatTimeout = int64(heartbeatTimeout)
+	}
+	if timeoutAction != "" {
+		f.timeoutAction = timeoutAction
+	}
+	if afkTimeout > 0 {
+		f.afkTimeout = int64(afkTimeout)
+	}
+	if afkAction != "" {
+		f.afkAction = afkAction
+	}
+}
+
+//set seconds seat reserved for disconnected player and outcome when expired
+//grace 0 means no grace, outcome "" means continue.
+func (f *Game) SetReconnectGrace(grace int, outcome string) {
+	if grace < 0 {
+		grace = 0
+	}
+	f.reconnectGrace = int64(grace)
+	if outcome != "" {
+		f.graceOutcome = outcome
+	}
+}
+
+//set policy for player connecting while old session online, "" means kick
+func (f *Game) SetDuplicateLogin(policy string) {
+	if policy == "" {
+		policy = define.DuplicateLoginKick
+	}
+	f.duplicateLogin = policy
+}
+
+//get current tick frequency
+func (f *Game) GetFrequency() int {
+	return f.frequency
+}
+
+//set policy for more inputs of one player in frame
+func (f *Game) SetInputPolicy(policy string, inputsPerFrame int) {
+	f.logic.SetInputPolicy(policy, inputsPerFrame, f.mergeInput)
+}
+
+//set input merger, for merge input policy
+func (f *Game) SetInputMerger(merger iface.IInputMerger) {
+	f.inputMerger = merger
+}
+
+//set bot provider, for seats of offline players
+func (f *Game) SetBotProvider(provider iface.IBotProvider) {
+	f.botProvider = provider
+}
+
+//push system input into current or future frame, tagged by define.SystemSeatId
+//frameId 0 means current frame, return frame id of input.
+func (f *Game) PushSystemInput(frameId uint32, input *pb.InputData) (uint32, error) {
+	//check
+	if input == nil {
+		return 0, define.ErrorOfInvalidPara
+	}
+	if f.state != define.Gaming {
+		return 0, define.ErrRoomNotRunning
+	}
+
+	//check target frame
+	frameCount := f.logic.GetFrameCount()
+	target := frameId
+	if target == 0 {
+		target = frameCount
+	}
+	if target < frameCount {
+		return 0, define.ErrFrameClosed
+	}
+	if target > frameCount + define.MaxSystemAheadFrames {
+		return 0, define.ErrFrameTooFar
+	}
+
+	//push copy as system seat, input of caller untouched
+	input = proto.Clone(input).(*pb.InputData)
+	input.Id = 0
+	input.RoomSeatId = define.SystemSeatId
+	if err := f.logic.PushCommandAt(target, input); err != nil {
+		return 0, err
+	}
+	f.dirty = true
+	logger.Debugf("[game(%d)] system input, frame:%d, sid:%d\n", f.id, target, input.GetSid())
+	return target, nil
+}
+
 //clean up
 func (f *Game) CleanUp() {
 	//clear player
@@ -361,6 +758,14 @@ func (f *Game) CleanUp() {
 //private func
 ////////////////
 
+//merge inputs of same player by merger, last wins if no merger
+func (f *Game) mergeInput(frameId uint32, old, input *pb.InputData) *pb.InputData {
+	if f.inputMerger == nil {
+		return input
+	}
+	return f.inputMerger.MergeInput(f.id, frameId, old, input)
+}
+
 //do ready
 func (f *Game) doReady(p iface.IPlayer) {
 	//check
@@ -398,6 +803,7 @@ func (f *Game) doStart() {
 	//init for game start
 	f.frameCount = 0
 	f.logic.Reset()
+	f.snapshots.Reset()
 
 	//init players
 	sf := func(k, v interface{}) bool {
@@ -405,21 +811,34 @@ func (f *Game) doStart() {
 		if ok && player != nil {
 			player.SetReady()
 			player.SetProgress(100)
+			player.SetAckFrameCount(0)
+			player.SetInputHold(0)
+			player.RefreshInputTime()
 		}
 		return true
 	}
 	f.players.Range(sf)
 
 	//init message
-	f.startTime = time.Now().Unix()
+	now := f.clock.Now()
+	f.startTime = now.Unix()
+	f.startTimeMs = now.UnixMilli()
+	f.tickTime = f.startTimeMs
 
 	msg := &pb.S2C_StartMsg{
 		TimeStamp:f.startTime,
+		StartTime:f.startTimeMs,
+		Frequency:uint32(f.baseFrequency),
 	}
 	packet := protocol.NewPacketWithPara(uint8(pb.ID_MSG_Start), msg)
 
 	//broadcast to all
 	f.broadcast(packet)
+	f.frequency = f.baseFrequency
+	f.inputDelay = f.baseInputDelay
+	f.adaptInputs = 0
+	f.adaptLate = 0
+	f.broadcastTiming()
 
 	//callback for game start
 	f.gl.OnStartGame(f.id)
@@ -427,11 +846,55 @@ func (f *Game) doStart() {
 
 //game is over
 func (f *Game) doGameOver() {
+	//push frames into replay sink
+	if f.replaySink != nil {
+		frames := make([]*pb.FrameData, 0)
+		frameCount := f.logic.GetFrameCount()
+		for i := f.logic.GetFirstFrame(); i < frameCount; i++ {
+			frameData := f.logic.GetFrame(i)
+			if frameData == nil {
+				continue
+			}
+			frames = append(frames, &pb.FrameData{
+				FrameID:i,
+				Input:frameData.GetData(),
+			})
+		}
+		if err := f.replaySink.OnFrames(f.id, frames); err != nil {
+			log.Printf("[game(%d)] replay sink failed, err:%v\n", f.id, err)
+		}
+	}
 	f.gl.OneGameOver(f.id)
 }
 
+//cb for frames evicted by memory cap
+func (f *Game) onFramesEvicted(frames []*pb.FrameData) {
+	if f.retention == define.FrameRetentionDrop || f.replaySink == nil {
+		logger.Debugf("[game(%d)] drop %d old frames\n", f.id, len(frames))
+		return
+	}
+	if err := f.replaySink.OnFrames(f.id, frames); err != nil {
+		log.Printf("[game(%d)] replay sink failed, err:%v\n", f.id, err)
+	}
+}
+
 //push client input
-func (f *Game) pushInput(p iface.IPlayer, msg *pb.C2S_InputMsg) bool {
+//return ack with assigned frame or reject reason.
+func (f *Game) pushInput(p iface.IPlayer, msg *pb.C2S_InputMsg) *pb.S2C_InputAckMsg {
+	ack := &pb.S2C_InputAckMsg{
+		Sid:msg.GetSid(),
+	}
+
+	//check state and rate
+	if f.state != define.Gaming {
+		ack.Result = pb.INPUT_RESULT_INPUT_NotRunning
+		return ack
+	}
+	if !p.AllowInput(f.clock.Now().Unix(), f.inputRateLimit) {
+		ack.Result = pb.INPUT_RESULT_INPUT_RateLimited
+		return ack
+	}
+
 	input := &pb.InputData{
 		Id:		p.GetId(),
 		Sid:	msg.GetSid(),
@@ -439,53 +902,427 @@ func (f *Game) pushInput(p iface.IPlayer, msg *pb.C2S_InputMsg) bool {
 		Y:		msg.GetY(),
 		RoomSeatId:p.GetIdx(),
 	}
-	f.logic.PushCommand(input)
+
+	//target frame of client, not before current frame plus input delay and hold
+	frameCount := f.logic.GetFrameCount()
+	minFrame := frameCount + f.inputDelay + p.GetInputHold()
+	target := msg.GetFrameID()
+	lateFrames := uint32(0)
+	if target < frameCount && target > 0 {
+		lateFrames = frameCount - target
+	}
+	if target < minFrame || target > minFrame + define.MaxInputAheadFrames {
+		//closed, inside delay or too far ahead
+		target = minFrame
+	}
+	p.RecordInput(lateFrames)
+	f.adaptInputs++
+	if lateFrames > 0 {
+		f.adaptLate++
+	}
+	if define.MaxInputLateFrames > 0 && lateFrames > define.MaxInputLateFrames {
+		ack.Result = pb.INPUT_RESULT_INPUT_Late
+		ack.LateFrames = lateFrames
+		return ack
+	}
+	if err := f.logic.PushCommandAt(target, input); err != nil {
+		ack.Result = inputResultOf(err)
+		return ack
+	}
+	ack.FrameID = target
+	ack.LateFrames = lateFrames
+	return ack
+}
+
+//get input ack result of push error
+func inputResultOf(err error) pb.INPUT_RESULT {
+	switch err {
+	case nil:
+		return pb.INPUT_RESULT_INPUT_Ok
+	case define.ErrFrameClosed:
+		return pb.INPUT_RESULT_INPUT_FrameClosed
+	case define.ErrFrameEvicted:
+		return pb.INPUT_RESULT_INPUT_FrameEvicted
+	case define.ErrInputDropped:
+		return pb.INPUT_RESULT_INPUT_MergeDropped
+	case define.ErrorOfInvalidPara:
+		return pb.INPUT_RESULT_INPUT_InvalidPayload
+	default:
+		return pb.INPUT_RESULT_INPUT_Duplicate
+	}
+}
+
+//send input ack or reject
+func (f *Game) sendInputAck(p iface.IPlayer, ack *pb.S2C_InputAckMsg) {
+	p.SendMessage(protocol.NewPacketWithPara(uint8(pb.ID_MSG_InputAck), ack))
+}
+
+//player leave game, close conn
+func (f *Game) leaveGame(p iface.IPlayer, reason pb.LEAVE_REASON) {
+	//check conn, maybe left already
+	if p.GetConn() == nil {
+		return
+	}
+	timedOut := p.IsTimedOut()
+	p.CleanUp()
+	if !timedOut {
+		//call cb of game listener
+		//this is the callback of room face
+		f.onLeaveGame(p, reason)
+		atomic.AddInt32(&f.playerCount, -1)
+		f.startBot(p)
+	}
+
+	//reserve seat for reconnect
+	if f.reconnectGrace > 0 && f.state == define.Gaming && !p.IsForfeit() {
+		p.SetDisconnectTime(f.clock.Now().Unix())
+		f.broadcastReconnecting(p, f.reconnectGrace)
+	}
+}
+
+//timed out player send message again, mark online
+func (f *Game) resumePlayer(p iface.IPlayer) {
+	log.Printf("[game(%d)] player[%d] resume from heart beat timeout\n", f.id, p.GetId())
+	p.SetTimedOut(false)
+	p.RefreshInputTime()
+	atomic.AddInt32(&f.playerCount, 1)
+	f.gl.OnJoinGame(p.GetConn(), f.id, p.GetId())
+	f.broadcastPlayerStatus(p, pb.PLAYER_STATUS_PLAYER_Online, pb.LEAVE_REASON_LEAVE_Close)
+	f.stopBot(p)
+}
+
+//check heart beat timeout and afk players, once per second
+func (f *Game) supervise(now int64) {
+	if now == f.superviseTime {
+		return
+	}
+	f.superviseTime = now
+
+	//collect first, action will change players
+	var (
+		timeouts []iface.IPlayer
+		afks     []iface.IPlayer
+		expires  []iface.IPlayer
+	)
+	sf := func(k, v interface{}) bool {
+		p, ok := v.(iface.IPlayer)
+		if !ok || p == nil {
+			return true
+		}
+		if p.GetConn() == nil && p.GetDisconnectTime() > 0 {
+			//in reconnect grace
+			left := f.reconnectGrace - (now - p.GetDisconnectTime())
+			if left <= 0 {
+				expires = append(expires, p)
+			}else{
+				f.broadcastReconnecting(p, left)
+			}
+			return true
+		}
+		if p.GetConn() == nil || p.IsTimedOut() {
+			return true
+		}
+		if now - p.GetLastHeartbeatTime() >= f.heartbeatTimeout {
+			timeouts = append(timeouts, p)
+			return true
+		}
+		if f.afkTimeout > 0 && f.state == define.Gaming &&
+			p.IsOnline() && p.IsReady() && !p.IsAfkWarned() &&
+			now - p.GetLastInputTime() >= f.afkTimeout {
+			afks = append(afks, p)
+		}
+		return true
+	}
+	f.players.Range(sf)
+
+	for _, p := range timeouts {
+		log.Printf("[game(%d)] player[%d] heart beat timeout, action:%v\n",
+					f.id, p.GetId(), f.timeoutAction)
+		f.doPlayerAction(p, f.timeoutAction, pb.LEAVE_REASON_LEAVE_Timeout)
+	}
+	for _, p := range afks {
+		log.Printf("[game(%d)] player[%d] afk, action:%v\n", f.id, p.GetId(), f.afkAction)
+		f.doPlayerAction(p, f.afkAction, pb.LEAVE_REASON_LEAVE_Afk)
+	}
+	for _, p := range expires {
+		f.expireGrace(p)
+	}
+}
+
+//reconnect grace of player expired, apply outcome
+func (f *Game) expireGrace(p iface.IPlayer) {
+	log.Printf("[game(%d)] player[%d] reconnect grace expired, outcome:%v\n",
+				f.id, p.GetId(), f.graceOutcome)
+	p.SetDisconnectTime(0)
+	switch f.graceOutcome {
+	case define.GraceOutcomeForfeit:
+		p.SetForfeit()
+		f.stopBot(p)
+		f.broadcastPlayerStatus(p, pb.PLAYER_STATUS_PLAYER_Forfeit, pb.LEAVE_REASON_LEAVE_Close)
+		f.onPlayerForfeit(p, pb.LEAVE_REASON_LEAVE_Close)
+	case define.GraceOutcomeAbort:
+		f.aborted = true
+	default:
+		f.broadcastPlayerStatus(p, pb.PLAYER_STATUS_PLAYER_Offline, pb.LEAVE_REASON_LEAVE_Close)
+	}
+}
+
+//do action for timeout or afk player
+func (f *Game) doPlayerAction(p iface.IPlayer, action string, reason pb.LEAVE_REASON) {
+	switch action {
+	case define.PlayerActionKick:
+		f.broadcastPlayerStatus(p, pb.PLAYER_STATUS_PLAYER_Kicked, reason)
+		f.leaveGame(p, reason)
+	case define.PlayerActionForfeit:
+		p.SetForfeit()
+		f.broadcastPlayerStatus(p, pb.PLAYER_STATUS_PLAYER_Forfeit, reason)
+		f.onPlayerForfeit(p, reason)
+		f.leaveGame(p, reason)
+	default:
+		if reason != pb.LEAVE_REASON_LEAVE_Timeout {
+			//afk warn only
+			p.SetAfkWarned()
+			f.broadcastPlayerStatus(p, pb.PLAYER_STATUS_PLAYER_AfkWarn, reason)
+			return
+		}
+		//mark disconnected, conn kept for resume
+		p.SetTimedOut(true)
+		atomic.AddInt32(&f.playerCount, -1)
+		f.onLeaveGame(p, reason)
+		f.broadcastPlayerStatus(p, pb.PLAYER_STATUS_PLAYER_Offline, reason)
+		f.startBot(p)
+	}
+}
+
+//call leave cb of game listener, with reason if supported
+func (f *Game) onLeaveGame(p iface.IPlayer, reason pb.LEAVE_REASON) {
+	if gl, ok := f.gl.(iface.ILeaveReasonListener); ok {
+		gl.OnLeaveGameWithReason(f.id, p.GetId(), reason)
+		return
+	}
+	f.gl.OnLeaveGame(f.id, p.GetId())
+}
+
+//call forfeit cb of game listener if supported
+func (f *Game) onPlayerForfeit(p iface.IPlayer, reason pb.LEAVE_REASON) {
+	if gl, ok := f.gl.(iface.IPlayerForfeitListener); ok {
+		gl.OnPlayerForfeit(f.id, p.GetId(), reason)
+	}
+}
+
+//broad cast status of player
+func (f *Game) broadcastPlayerStatus(p iface.IPlayer, status pb.PLAYER_STATUS, reason pb.LEAVE_REASON) {
+	f.broadcast(protocol.NewPacketWithPara(uint8(pb.ID_MSG_PlayerStatus), &pb.S2C_PlayerStatusMsg{
+		Id:p.GetId(),
+		Status:status,
+		Reason:reason,
+	}))
+}
+
+//broad cast player reconnecting with seconds left of grace
+func (f *Game) broadcastReconnecting(p iface.IPlayer, left int64) {
+	f.broadcast(protocol.NewPacketWithPara(uint8(pb.ID_MSG_PlayerStatus), &pb.S2C_PlayerStatusMsg{
+		Id:p.GetId(),
+		Status:pb.PLAYER_STATUS_PLAYER_Reconnecting,
+		Reason:pb.LEAVE_REASON_LEAVE_Close,
+		Remain:uint32(left),
+	}))
+}
+
+//bot takes over seat of offline player, never forfeited seat
+func (f *Game) startBot(p iface.IPlayer) {
+	if f.botProvider == nil || f.state != define.Gaming || p.IsBot() || p.IsForfeit() {
+		return
+	}
+	log.Printf("[game(%d)] bot takes over player[%d] seat %d\n", f.id, p.GetId(), p.GetIdx())
+	p.SetBot(true)
+	f.broadcast(f.newBotPacket())
+}
+
+//bot gives seat back to player, false if not bot
+func (f *Game) stopBot(p iface.IPlayer) bool {
+	if !p.IsBot() {
+		return false
+	}
+	log.Printf("[game(%d)] player[%d] takes back seat %d from bot\n", f.id, p.GetId(), p.GetIdx())
+	p.SetBot(false)
+	f.broadcast(f.newBotPacket())
 	return true
 }
 
-//client reconnect
+//send seats of bots to player, skip if no bot
+func (f *Game) sendBots(p iface.IPlayer) {
+	packet := f.newBotPacket()
+	if len(packet.GetData()) == 0 {
+		return
+	}
+	p.SendMessage(packet)
+}
+
+//new packet of seats controlled by bot
+func (f *Game) newBotPacket() iface.IPacket {
+	msg := &pb.S2C_BotMsg{}
+	sf := func(k, v interface{}) bool {
+		p, ok := v.(iface.IPlayer)
+		if ok && p != nil && p.IsBot() {
+			msg.RoomSeatIds = append(msg.RoomSeatIds, p.GetIdx())
+		}
+		return true
+	}
+	f.players.Range(sf)
+	sort.Slice(msg.RoomSeatIds, func(i, j int) bool {
+		return msg.RoomSeatIds[i] < msg.RoomSeatIds[j]
+	})
+	return protocol.NewPacketWithPara(uint8(pb.ID_MSG_Bot), msg)
+}
+
+//push inputs of bots into current frame
+func (f *Game) pushBotInputs() {
+	if f.botProvider == nil {
+		return
+	}
+	var (
+		history []*pb.FrameData
+		loaded  bool
+	)
+	frameCount := f.logic.GetFrameCount()
+	sf := func(k, v interface{}) bool {
+		p, ok := v.(iface.IPlayer)
+		if !ok || p == nil || !p.IsBot() {
+			return true
+		}
+		//load recent frames once
+		if !loaded {
+			history = f.getHistory(frameCount)
+			loaded = true
+		}
+		inputs := f.botProvider.GetBotInputs(f.id, p.GetIdx(), frameCount, history)
+		for _, input := range inputs {
+			if input == nil {
+				continue
+			}
+			//bot can only act for its seat
+			input.Id = p.GetId()
+			input.RoomSeatId = p.GetIdx()
+			f.logic.PushCommandAt(frameCount, input)
+		}
+		return true
+	}
+	f.players.Range(sf)
+}
+
+//get recent closed frames before frame
+func (f *Game) getHistory(frameCount uint32) []*pb.FrameData {
+	from := f.logic.GetFirstFrame()
+	if frameCount > define.BotHistoryFrames && frameCount - define.BotHistoryFrames > from {
+		from = frameCount - define.BotHistoryFrames
+	}
+	history := make([]*pb.FrameData, 0)
+	for i := from; i < frameCount; i++ {
+		frameData := f.logic.GetFrame(i)
+		if frameData == nil {
+			continue
+		}
+		history = append(history, &pb.FrameData{
+			FrameID:i,
+			Input:frameData.GetData(),
+		})
+	}
+	return history
+}
+
+//join new session of online player as observer
+//observer gets copy of player messages, its own messages ignored.
+func (f *Game) joinObserver(p iface.IPlayer, conn iface.IConn) bool {
+	msg := &pb.S2C_ConnectMsg{
+		ErrorCode:pb.ERROR_CODE_ERR_Ok,
+		Observer:true,
+	}
+	if !p.AddObserver(conn) {
+		log.Printf("[game(%d)] player[%d] too many observers\n", f.id, p.GetId())
+		msg.ErrorCode = pb.ERROR_CODE_ERR_Duplicate
+		msg.Observer = false
+		conn.AsyncWritePacket(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Connect), msg), 0)
+		return false
+	}
+	log.Printf("[game(%d)] player[%d] join as observer\n", f.id, p.GetId())
+	conn.SetExtraData(nil)
+	conn.AsyncWritePacket(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Connect), msg), 0)
+	if f.state != define.Gaming {
+		return true
+	}
+
+	//game running, send start and frames so far
+	send := func(packet iface.IPacket) error {
+		return conn.AsyncWritePacket(packet, 0)
+	}
+	send(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Start), &pb.S2C_StartMsg{
+		TimeStamp:f.startTime,
+		StartTime:f.startTimeMs,
+		Frequency:uint32(f.baseFrequency),
+	}))
+	frameCount := f.logic.GetFrameCount()
+	i := f.logic.GetFirstFrame()
+	snapshot := f.snapshots.GetAgreed()
+	if snapshot != nil && snapshot.FrameID + 1 >= i {
+		send(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Snapshot), snapshot))
+		i = snapshot.FrameID + 1
+	}
+	f.writeFrames(send, i, frameCount)
+	return true
+}
+
+//client reconnect or late join
+//send frames after confirmed one, or latest agreed snapshot and frames after it.
 func (f *Game) doReconnect(p iface.IPlayer) bool {
 	//init message
 	msg := &pb.S2C_StartMsg{
 		TimeStamp:f.startTime,
+		StartTime:f.startTimeMs,
+		Frequency:uint32(f.baseFrequency),
 	}
 	packet := protocol.NewPacketWithPara(uint8(pb.ID_MSG_Start), msg)
 
 	//send message
 	p.SendMessage(packet)
+	f.sendTiming(p)
 
-	//process frame data
-	framesCount := f.frameCount
-	i := uint32(0)
-	c := 0
-	frameMsg := &pb.S2C_FrameMsg{}
-
-	//loop
-	for ; i <= framesCount; i++ {
-		frameData := f.logic.GetFrame(i)
-		if frameData == nil && i != (framesCount - 1) {
-			continue
-		}
+	//take seat back from bot, tell seats of bots
+	if !f.stopBot(p) {
+		f.sendBots(p)
+	}
 
-		fd := &pb.FrameData{
-			FrameID:i,
-		}
-		if frameData != nil {
-			fd.Input = frameData.GetData()
-		}
-		frameMsg.Frames = append(frameMsg.Frames, fd)
-		c++
+	//resend from confirmed frame
+	frameCount := f.logic.GetFrameCount()
+	first := f.logic.GetFirstFrame()
+	i := p.GetAckFrameCount()
+	if i < first {
+		i = first
+	}
+	if i > frameCount {
+		i = frameCount
+	}
 
-		if c >= define.KMaxFrameDataPerMsg || i == (framesCount - 1) {
-			p.SendMessage(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Frame), frameMsg))
-			c = 0
-			frameMsg = &pb.S2C_FrameMsg{}
-		}
+	//send snapshot if newer than confirmed frame
+	snapshot := f.snapshots.GetAgreed()
+	if snapshot != nil && snapshot.FrameID + 1 >= first && snapshot.FrameID + 1 > i {
+		p.SendMessage(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Snapshot), snapshot))
+		i = snapshot.FrameID + 1
 	}
 
-	//set send frame count
-	p.SetSendFrameCount(f.frameCount)
+	//re-arm ack resend, frames just sent again
+	if p.GetAckTime() > 0 {
+		p.SetAckTime(f.clock.Now().UnixMilli())
+	}
 
+	//small backlog send at once, others stream by catch up
+	if frameCount - i <= define.CatchUpThreshold {
+		p.SetSendFrameCount(f.sendFrames(p, i, frameCount))
+		return true
+	}
+	p.SetCatchUp(i, frameCount)
+	p.SetSendFrameCount(frameCount)
+	f.streamCatchUp(p)
 	return true
 }
 
@@ -500,61 +1337,385 @@ func (f *Game) broadcastFrameData() {
 	}
 
 	//set key data
-	now := time.Now().Unix()
+	now := f.clock.Now().Unix()
+	nowMs := f.clock.Now().UnixMilli()
 	sf := func(k, v interface{}) bool {
 		player, ok := v.(iface.IPlayer)
 		if !ok || player == nil {
 			return true
 		}
-		//check online
-		if !player.IsOnline() {
+		//check online, status and heart beat
+		if !f.canSendFrames(player, now) {
 			return true
 		}
-		//check status
-		if !player.IsReady() {
+		//check player last frame, skip evicted
+		i := player.GetSendFrameCount()
+		first := f.logic.GetFirstFrame()
+
+		//ack stalled, send un-acked frames again
+		if from, end := player.GetCatchUp(); from >= end && f.isAckStalled(player, nowMs) {
+			logger.Debugf("[game(%d)] player[%d] ack stalled at %d, resend to %d\n",
+						f.id, player.GetId(), player.GetAckFrameCount(), i)
+			i = player.GetAckFrameCount()
+			player.SetAckTime(nowMs)
+		}
+		if i < first {
+			i = first
+		}
+
+		//lag too much, move into backlog and go on with live frames
+		if frameCount - i > define.CatchUpThreshold {
+			if from, end := player.GetCatchUp(); from >= end {
+				player.SetCatchUp(i, frameCount)
+				player.SetSendFrameCount(frameCount)
+				return true
+			}
+		}
+
+		//send live frames, probe rtt with last one
+		next := f.sendFrames(player, i, frameCount)
+		player.SetSendFrameCount(next)
+		if next > i {
+			player.StartRTTProbe(next - 1, f.clock.Now().UnixMilli())
+		}
+		return true
+	}
+	f.players.Range(sf)
+}
+
+//reply heart beat, with clock sync if client time sent
+func (f *Game) replyHeartbeat(p iface.IPlayer, msg *pb.C2S_HeartbeatMsg, recvTime int64) {
+	if msg.GetClientTime() <= 0 {
+		p.SendMessage(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Heartbeat), nil))
+		return
+	}
+	reply := &pb.S2C_HeartbeatMsg{
+		ClientTime:msg.GetClientTime(),
+		ServerRecvTime:recvTime,
+		FrameID:f.logic.GetFrameCount(),
+		FrameTime:f.tickTime,
+		Offset:p.GetClockOffset(),
+		Rtt:p.GetRTT(),
+	}
+	reply.ServerSendTime = f.clock.Now().UnixMilli()
+	p.SetClockSync(reply.ClientTime, recvTime, reply.ServerSendTime)
+	p.SendMessage(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Heartbeat), reply))
+}
+
+//client confirmed contiguous frames, notify lag
+func (f *Game) doAck(p iface.IPlayer, ackFrameCount uint32) {
+	frameCount := f.logic.GetFrameCount()
+	if ackFrameCount > frameCount {
+		ackFrameCount = frameCount
+	}
+	p.SetAckFrameCount(ackFrameCount)
+	if gl, ok := f.gl.(iface.IPlayerLagListener); ok {
+		gl.OnPlayerLag(f.id, p.GetId(), frameCount - ackFrameCount)
+	}
+}
+
+//frames sent but ack not moved for define.AckResendTimeout or 2 rtt
+//only for clients acked before, others never ack.
+func (f *Game) isAckStalled(p iface.IPlayer, now int64) bool {
+	ackTime := p.GetAckTime()
+	if ackTime <= 0 || p.GetAckFrameCount() >= p.GetSendFrameCount() {
+		return false
+	}
+	timeout := define.AckResendTimeout
+	if rtt := 2 * p.GetRTT(); rtt > timeout {
+		timeout = rtt
+	}
+	return now - ackTime >= timeout
+}
+
+//get frames not confirmed by player
+func (f *Game) getLag(p iface.IPlayer, frameCount uint32) uint32 {
+	ack := p.GetAckFrameCount()
+	if ack >= frameCount {
+		return 0
+	}
+	return frameCount - ack
+}
+
+//adapt tick frequency and input delay by max rtt and late inputs
+func (f *Game) adaptTiming() {
+	//max rtt of online players
+	var maxRTT int64
+	sf := func(k, v interface{}) bool {
+		player, ok := v.(iface.IPlayer)
+		if ok && player != nil && player.IsOnline() && player.GetRTT() > maxRTT {
+			maxRTT = player.GetRTT()
+		}
+		return true
+	}
+	f.players.Range(sf)
+
+	//late ratio since last adapt
+	late := false
+	if f.adaptInputs > 0 {
+		late = float64(f.adaptLate) / float64(f.adaptInputs) > define.AdaptiveLateRatio
+	}
+	f.adaptInputs = 0
+	f.adaptLate = 0
+
+	//input delay cover one way trip, raise if late, shrink slowly
+	frequency := f.frequency
+	delay := f.getNeedInputDelay(maxRTT, frequency)
+	if late && delay <= f.inputDelay {
+		delay = f.inputDelay + 1
+	}else if !late && delay < f.inputDelay {
+		delay = f.inputDelay - 1
+	}
+
+	//lower frequency if delay over cap, raise back if enough margin
+	if delay > define.MaxInputDelay && frequency > define.MinFrequency {
+		frequency -= define.FrequencyStep
+		if frequency < define.MinFrequency {
+			frequency = define.MinFrequency
+		}
+		delay = f.getNeedInputDelay(maxRTT, frequency)
+	}else if !late && frequency < f.baseFrequency {
+		next := frequency + define.FrequencyStep
+		if next > f.baseFrequency {
+			next = f.baseFrequency
+		}
+		if need := f.getNeedInputDelay(maxRTT, next); need * 2 <= define.MaxInputDelay {
+			frequency = next
+			delay = need
+		}
+	}
+	if delay > define.MaxInputDelay {
+		delay = define.MaxInputDelay
+	}
+	if delay < f.baseInputDelay {
+		delay = f.baseInputDelay
+	}
+
+	//notify change
+	if frequency == f.frequency && delay == f.inputDelay {
+		return
+	}
+	log.Printf("[game(%d)] adapt timing, rtt:%dms, late:%v, frequency:%d->%d, input delay:%d->%d\n",
+				f.id, maxRTT, late, f.frequency, frequency, f.inputDelay, delay)
+	f.frequency = frequency
+	f.inputDelay = delay
+	if f.maxInputHold > 0 {
+		f.updateInputHolds()
+	}
+	f.broadcastTiming()
+}
+
+//get input delay frames cover one way trip of rtt
+func (f *Game) getNeedInputDelay(rtt int64, frequency int) uint32 {
+	delay := uint32((rtt * int64(frequency) + 1999) / 2000)
+	if delay < f.baseInputDelay {
+		delay = f.baseInputDelay
+	}
+	return delay
+}
+
+//hold back inputs of faster players by rtt, to even out with slowest one
+//hold within max input hold. return players with hold changed.
+func (f *Game) updateInputHolds() []iface.IPlayer {
+	var (
+		maxRTT  int64
+		changed []iface.IPlayer
+	)
+	sf := func(k, v interface{}) bool {
+		player, ok := v.(iface.IPlayer)
+		if ok && player != nil && player.IsOnline() && player.GetRTT() > maxRTT {
+			maxRTT = player.GetRTT()
+		}
+		return true
+	}
+	f.players.Range(sf)
+
+	//half of rtt gap, in frames
+	sf = func(k, v interface{}) bool {
+		player, ok := v.(iface.IPlayer)
+		if !ok || player == nil {
 			return true
 		}
-		//check heart beat
-		diff := now - player.GetLastHeartbeatTime()
-		if diff >= define.KBadNetworkThreshold {
+		holdMs := int64(0)
+		if player.IsOnline() && player.GetRTT() > 0 {
+			holdMs = (maxRTT - player.GetRTT()) / 2
+		}
+		if holdMs > int64(f.maxInputHold) {
+			holdMs = int64(f.maxInputHold)
+		}
+		hold := uint32((holdMs * int64(f.frequency) + 500) / 1000)
+		if hold == player.GetInputHold() {
 			return true
 		}
-		//check player last frame
-		i := player.GetSendFrameCount()
-		c := int64(0)
-		msg := &pb.S2C_FrameMsg{}
+		log.Printf("[game(%d)] equalize latency, player[%d] rtt:%dms, max rtt:%dms, input hold:%d->%d\n",
+					f.id, player.GetId(), player.GetRTT(), maxRTT, player.GetInputHold(), hold)
+		player.SetInputHold(hold)
+		changed = append(changed, player)
+		return true
+	}
+	f.players.Range(sf)
+	return changed
+}
 
-		for ; i < frameCount; i++ {
-			frameData := f.logic.GetFrame(i)
-			if frameData == nil && i != (frameCount - 1) {
-				continue
-			}
+//update input holds and notify changed players
+func (f *Game) equalizeLatency() {
+	for _, player := range f.updateInputHolds() {
+		f.sendTiming(player)
+	}
+}
 
-			//init frame data
-			fd := &pb.FrameData{
-				FrameID:i,
-			}
-			if frameData != nil {
-				fd.Input = frameData.GetData()
-			}
-			msg.Frames = append(msg.Frames, fd)
-			c++
+//send timing from current frame, with input hold of player
+func (f *Game) sendTiming(p iface.IPlayer) {
+	msg := &pb.S2C_TimingMsg{
+		Frequency:uint32(f.frequency),
+		InputDelay:f.inputDelay,
+		FrameID:f.logic.GetFrameCount(),
+		InputHold:p.GetInputHold(),
+	}
+	p.SendMessage(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Timing), msg))
+}
 
-			//if last frame or up to max frame, send them
-			if i == (frameCount - 1) || c >= define.KMaxFrameDataPerMsg {
-				player.SendMessage(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Frame), msg))
-				c = 0
-				msg = &pb.S2C_FrameMsg{}
-			}
+//send timing to all players
+func (f *Game) broadcastTiming() {
+	sf := func(k, v interface{}) bool {
+		player, ok := v.(iface.IPlayer)
+		if ok && player != nil {
+			f.sendTiming(player)
 		}
+		return true
+	}
+	f.players.Range(sf)
+}
 
-		//set frame count
-		player.SetSendFrameCount(frameCount)
+//send input stats to players with recent inputs
+func (f *Game) reportInputStats() {
+	sf := func(k, v interface{}) bool {
+		player, ok := v.(iface.IPlayer)
+		if !ok || player == nil || !player.IsOnline() {
+			return true
+		}
+		if player.GetInputStats(false).RecentInputs <= 0 {
+			return true
+		}
+		msg := player.GetInputStats(true)
+		player.SendMessage(protocol.NewPacketWithPara(uint8(pb.ID_MSG_InputStats), msg))
 		return true
 	}
 	f.players.Range(sf)
 }
 
+//stream backlog frames of all catching up players
+func (f *Game) streamCatchUps() {
+	sf := func(k, v interface{}) bool {
+		player, ok := v.(iface.IPlayer)
+		if ok && player != nil {
+			f.streamCatchUp(player)
+		}
+		return true
+	}
+	f.players.Range(sf)
+}
+
+//send backlog frames of catching up player, limited by catch up rate
+//and report progress.
+func (f *Game) streamCatchUp(p iface.IPlayer) {
+	from, end := p.GetCatchUp()
+	if from >= end || !f.canSendFrames(p, f.clock.Now().Unix()) {
+		return
+	}
+
+	//skip evicted
+	if first := f.logic.GetFirstFrame(); from < first {
+		from = first
+	}
+	to := from + uint32(f.catchUpRate)
+	if to > end {
+		to = end
+	}
+	if from < to {
+		from = f.sendFrames(p, from, to)
+	}
+	p.SetCatchUp(from, end)
+
+	//report progress
+	msg := &pb.S2C_CatchUpMsg{
+		FrameID:from,
+		EndFrameID:end,
+		Done:from >= end,
+	}
+	p.SendMessage(protocol.NewPacketWithPara(uint8(pb.ID_MSG_CatchUp), msg))
+}
+
+//send frames [from, to), frames without input skipped except the last one
+//return next frame not sent, stop if send queue is full.
+func (f *Game) sendFrames(p iface.IPlayer, from, to uint32) uint32 {
+	return f.writeFrames(p.TrySendMessage, from, to)
+}
+
+//write frames [from, to) by send func, return next frame to send
+//message split by define.KMaxFrameDataPerMsg frames and protocol.MaxPacketLen bytes.
+func (f *Game) writeFrames(send func(packet iface.IPacket) error, from, to uint32) uint32 {
+	var (
+		next = from
+		c    = 0
+		size = 0
+		msg  = &pb.S2C_FrameMsg{}
+	)
+	for i := from; i < to; i++ {
+		frameData := f.logic.GetFrame(i)
+		if frameData == nil && i != (to - 1) {
+			continue
+		}
+
+		//init frame data
+		fd := &pb.FrameData{
+			FrameID:i,
+		}
+		if frameData != nil {
+			fd.Input = frameData.GetData()
+		}
+
+		//send frames before it if message full
+		fdSize := proto.Size(fd) + frameFieldOverhead
+		if c > 0 && size + fdSize > protocol.MaxPacketLen {
+			err := send(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Frame), msg))
+			if err != nil {
+				return next
+			}
+			next = i
+			c = 0
+			size = 0
+			msg = &pb.S2C_FrameMsg{}
+		}
+		msg.Frames = append(msg.Frames, fd)
+		c++
+		size += fdSize
+
+		//if last frame or up to max frame, send them
+		if i == (to - 1) || c >= define.KMaxFrameDataPerMsg {
+			err := send(protocol.NewPacketWithPara(uint8(pb.ID_MSG_Frame), msg))
+			if err != nil {
+				//resend from here later
+				return next
+			}
+			next = i + 1
+			c = 0
+			size = 0
+			msg = &pb.S2C_FrameMsg{}
+		}
+	}
+	return to
+}
+
+//check player can receive frames
+func (f *Game) canSendFrames(p iface.IPlayer, now int64) bool {
+	if !p.IsOnline() || !p.IsReady() {
+		return false
+	}
+	//check heart beat
+	return now - p.GetLastHeartbeatTime() < define.KBadNetworkThreshold
+}
+
 //broad cast
 func (f *Game) broadcast(packet iface.IPacket) {
 	sf := func(k, v interface{}) bool {
@@ -1,102 +1,223 @@
 package room
 
 import (
+	"github.com/andyzhou/thorn/define"
 	"github.com/andyzhou/thorn/iface"
 	"github.com/andyzhou/thorn/pb"
-	"runtime"
 	"sync"
 	"sync/atomic"
+	"unsafe"
 )
 
 /*
  * lock step data face, implement of ILockStep
  * - frame data opt
+ * - frames kept in fixed size segments, addressed by index
+ * - memory capped, oldest closed segments evicted when over cap
+ * - more inputs of one player in frame, kept, replaced or merged by policy
  */
 
+//approx memory size
+var (
+	frameMemSize   = int(unsafe.Sizeof(Frame{}))
+	inputMemSize   = int(unsafe.Sizeof(pb.InputData{}) + unsafe.Sizeof(uintptr(0)))
+	segmentMemSize = frameMemSize * define.FrameSegmentSize
+)
+
+//segments reused between games
+var segmentPool = sync.Pool{
+	New: func() interface{} {
+		return &frameSegment{
+			frames:make([]Frame, define.FrameSegmentSize),
+		}
+	},
+}
+
+//frames of [base, base + FrameSegmentSize)
+type frameSegment struct {
+	base    uint32
+	frames  []Frame
+	memSize int //approx bytes, include inputs
+}
+
 //face info
 type LockStep struct {
-	frames     map[uint32]iface.IFrame
-	frameCount uint32
+	segments       []*frameSegment              //continuous, ordered by base
+	firstFrame     uint32                       //frames before it evicted
+	frameCount     uint32
+	memSize        int                          //approx bytes of all segments
+	maxMemory      int
+	onEvict        func(frames []*pb.FrameData) //cb for evicted frames with input, nil means drop
+	inputPolicy    string
+	inputsPerFrame int
+	merge          iface.InputMergeFunc         //for merge policy, nil means last wins
 	sync.RWMutex
 }
 
 //construct
-func NewLockStep() *LockStep {
+//maxMemory 0 means define.RoomMaxFrameMemory
+func NewLockStep(
+		maxMemory int,
+		onEvict func(frames []*pb.FrameData),
+	) *LockStep {
 	//self init
 	this := &LockStep{
-		frames:make(map[uint32]iface.IFrame),
+		segments:make([]*frameSegment, 0),
 		frameCount:0,
+		onEvict:onEvict,
 	}
+	this.SetMaxMemory(maxMemory)
+	this.SetInputPolicy("", 0, nil)
 	return this
 }
 
+//set input policy for more inputs of one player in frame
+//"" means keep, inputsPerFrame 0 means define.InputsPerFrame.
+func (f *LockStep) SetInputPolicy(
+		policy string,
+		inputsPerFrame int,
+		merge iface.InputMergeFunc,
+	) {
+	if policy == "" {
+		policy = define.InputPolicyKeep
+	}
+	if inputsPerFrame <= 0 {
+		inputsPerFrame = define.InputsPerFrame
+	}
+	f.Lock()
+	defer f.Unlock()
+	f.inputPolicy = policy
+	f.inputsPerFrame = inputsPerFrame
+	f.merge = merge
+}
+
+//set max memory, 0 means define.RoomMaxFrameMemory
+func (f *LockStep) SetMaxMemory(maxMemory int) {
+	if maxMemory <= 0 {
+		maxMemory = define.RoomMaxFrameMemory
+	}
+	f.Lock()
+	defer f.Unlock()
+	f.maxMemory = maxMemory
+}
+
 //reset
 func (f *LockStep) Reset() {
 	f.Lock()
 	defer f.Unlock()
-	f.frames = make(map[uint32]iface.IFrame)
-	f.frameCount = 0
-
-	//gc opt
-	runtime.GC()
+	for _, seg := range f.segments {
+		f.releaseSegment(seg)
+	}
+	f.segments = make([]*frameSegment, 0)
+	f.firstFrame = 0
+	f.memSize = 0
+	atomic.StoreUint32(&f.frameCount, 0)
 }
 
-//push frame data
+//push frame data into current frame
 func (f *LockStep) PushCommand(data *pb.InputData) bool {
+	return f.PushCommandAt(atomic.LoadUint32(&f.frameCount), data) == nil
+}
+
+//push frame data into open frame, current or future one
+func (f *LockStep) PushCommandAt(frameId uint32, data *pb.InputData) error {
 	//basic check
 	if data == nil {
-		return false
+		return define.ErrorOfInvalidPara
 	}
 
-	//check frame
+	//get open frame
 	f.Lock()
 	defer f.Unlock()
-	frame, ok := f.frames[f.frameCount]
-	if !ok {
-		//init new
-		frame = NewFrame(f.frameCount)
+	if frameId < atomic.LoadUint32(&f.frameCount) {
+		//closed
+		return define.ErrFrameClosed
+	}
+	seg := f.openSegment(frameId)
+	if seg == nil {
+		return define.ErrFrameEvicted
 	}
+	frame := &seg.frames[frameId - seg.base]
 
-	//check is same frame id
-	for _, v := range frame.GetData() {
+	//find inputs of same player
+	var (
+		count int
+		last  = -1
+	)
+	for i, v := range frame.GetData() {
 		if v.GetId() == data.GetId() {
-			//same data id, skipped
-			return false
+			count++
+			last = i
 		}
 	}
 
-	//add data into frame
+	//put data by policy, system inputs always kept
+	if count > 0 && data.GetRoomSeatId() != define.SystemSeatId {
+		switch f.inputPolicy {
+		case define.InputPolicyLast:
+			data.Seq = 0
+			frame.SetData(last, data)
+			return nil
+		case define.InputPolicyMerge:
+			return f.mergeData(frame, last, data)
+		default:
+			if count >= f.inputsPerFrame {
+				return define.ErrInputDuplicate
+			}
+		}
+	}
+	data.Seq = uint32(count)
 	frame.AddData(data)
-	return true
+	seg.memSize += inputMemSize
+	f.memSize += inputMemSize
+	return nil
 }
 
 //get frame count
 func (f *LockStep) GetFrameCount() uint32 {
-	return f.frameCount
+	return atomic.LoadUint32(&f.frameCount)
 }
 
-//gen tick
-func (f *LockStep) Tick() uint32 {
-	atomic.AddUint32(&f.frameCount, 1)
-	return f.frameCount
+//get first kept frame id
+func (f *LockStep) GetFirstFrame() uint32 {
+	f.RLock()
+	defer f.RUnlock()
+	return f.firstFrame
 }
 
-//get batch frame
-func (f *LockStep) GetRangeFrames(from, to uint32) []iface.IFrame {
-	//basic check
-	if from < 0 || to < 0 {
-		return nil
+//get approx memory bytes of kept frames
+func (f *LockStep) GetMemSize() int {
+	f.RLock()
+	defer f.RUnlock()
+	return f.memSize
+}
+
+//gen tick, evict old frames if over memory cap
+func (f *LockStep) Tick() uint32 {
+	f.Lock()
+	frameCount := atomic.AddUint32(&f.frameCount, 1)
+	evicted := f.evict(frameCount)
+	f.Unlock()
+
+	//cb out of lock
+	if len(evicted) > 0 && f.onEvict != nil {
+		f.onEvict(evicted)
 	}
+	return frameCount
+}
 
+//get batch frame, frames without input skipped
+func (f *LockStep) GetRangeFrames(from, to uint32) []iface.IFrame {
 	//init result
 	result := make([]iface.IFrame, 0)
 
 	//get with locker
-	f.Lock()
-	defer f.Unlock()
-	for ; from <= to && from <= f.frameCount; from++ {
-		frame, ok := f.frames[from]
-		if !ok {
+	f.RLock()
+	defer f.RUnlock()
+	frameCount := atomic.LoadUint32(&f.frameCount)
+	for ; from <= to && from <= frameCount; from++ {
+		frame := f.getFrame(from)
+		if frame == nil {
 			continue
 		}
 		result = append(result, frame)
@@ -104,19 +225,124 @@ func (f *LockStep) GetRangeFrames(from, to uint32) []iface.IFrame {
 	return result
 }
 
-//get one frame
+//get one frame, nil if no input or evicted
 func (f *LockStep) GetFrame(idx uint32) iface.IFrame {
-	//basic check
-	if idx < 0 {
+	f.RLock()
+	defer f.RUnlock()
+	frame := f.getFrame(idx)
+	if frame == nil {
 		return nil
 	}
+	return frame
+}
 
-	//get by idx with locker
-	f.Lock()
-	defer f.Unlock()
-	v, ok := f.frames[idx]
-	if !ok {
+//////////////
+//private func
+//////////////
+
+//get frame with input by index, lock outside
+func (f *LockStep) getFrame(idx uint32) *Frame {
+	if idx < f.firstFrame || len(f.segments) <= 0 || idx < f.segments[0].base {
+		return nil
+	}
+	pos := int((idx - f.segments[0].base) / define.FrameSegmentSize)
+	if pos >= len(f.segments) {
+		return nil
+	}
+	seg := f.segments[pos]
+	frame := &seg.frames[idx - seg.base]
+	if len(frame.data) <= 0 {
 		return nil
 	}
-	return v
-}
\ No newline at end of file
+	return frame
+}
+
+//merge data with old input of same player at pos, lock outside
+func (f *LockStep) mergeData(frame *Frame, pos int, data *pb.InputData) error {
+	if f.merge == nil {
+		data.Seq = 0
+		frame.SetData(pos, data)
+		return nil
+	}
+	merged := f.merge(frame.GetIdx(), frame.GetData()[pos], data)
+	if merged == nil {
+		return define.ErrInputDropped
+	}
+
+	//keep owner and order
+	merged.Id = data.GetId()
+	merged.RoomSeatId = data.GetRoomSeatId()
+	merged.Seq = 0
+	frame.SetData(pos, merged)
+	return nil
+}
+
+//get segment of frame, alloc segments if need, lock outside
+func (f *LockStep) openSegment(idx uint32) *frameSegment {
+	if idx < f.firstFrame {
+		return nil
+	}
+	for {
+		//next base
+		base := idx - idx % define.FrameSegmentSize
+		size := len(f.segments)
+		if size > 0 {
+			last := f.segments[size - 1]
+			if idx < last.base + define.FrameSegmentSize {
+				return f.segments[(idx - f.segments[0].base) / define.FrameSegmentSize]
+			}
+			base = last.base + define.FrameSegmentSize
+		}
+
+		//alloc new segment
+		seg := segmentPool.Get().(*frameSegment)
+		seg.base = base
+		seg.memSize = segmentMemSize
+		for i := range seg.frames {
+			seg.frames[i].idx = base + uint32(i)
+		}
+		f.segments = append(f.segments, seg)
+		f.memSize += seg.memSize
+	}
+}
+
+//evict oldest closed segments while over memory cap, lock outside
+//return evicted frames with input, in order.
+func (f *LockStep) evict(frameCount uint32) []*pb.FrameData {
+	var (
+		evicted []*pb.FrameData
+	)
+	for f.memSize > f.maxMemory && len(f.segments) > 0 {
+		seg := f.segments[0]
+		if seg.base + define.FrameSegmentSize > frameCount {
+			//current frame inside
+			break
+		}
+		if f.onEvict != nil {
+			for i := range seg.frames {
+				frame := &seg.frames[i]
+				if len(frame.data) <= 0 {
+					continue
+				}
+				evicted = append(evicted, &pb.FrameData{
+					FrameID:frame.idx,
+					Input:frame.data,
+				})
+			}
+		}
+		f.memSize -= seg.memSize
+		f.firstFrame = seg.base + define.FrameSegmentSize
+		f.segments = f.segments[1:]
+		f.releaseSegment(seg)
+	}
+	return evicted
+}
+
+//clear and put segment back to pool
+func (f *LockStep) releaseSegment(seg *frameSegment) {
+	for i := range seg.frames {
+		seg.frames[i].data = nil
+	}
+	seg.memSize = 0
+	segmentPool.Put(seg)
+}
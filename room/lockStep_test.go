@@ -0,0 +1,308 @@
+package room
+
+import (
+	"github.com/andyzhou/thorn/clock"
+	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/define"
+	"github.com/andyzhou/thorn/pb"
+	"testing"
+	"time"
+)
+
+//input of player with sid
+func testInput(playerId uint64, sid int32) *pb.InputData {
+	return &pb.InputData{
+		Id:playerId,
+		Sid:sid,
+		RoomSeatId:int32(playerId),
+	}
+}
+
+func TestLockStepSegments(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames []uint32 //frames pushed with one input
+		ticks  int
+		empty  []uint32 //frames without input
+		ranged int      //frames of GetRangeFrames(0, ticks)
+	}{
+		{"one segment", []uint32{0, 1, 5}, 10, []uint32{2, 9}, 3},
+		{"segment edge", []uint32{define.FrameSegmentSize - 1, define.FrameSegmentSize}, define.FrameSegmentSize + 1,
+			[]uint32{define.FrameSegmentSize + 1}, 2},
+		{"future segment", []uint32{0, define.FrameSegmentSize * 2 + 3}, define.FrameSegmentSize * 3,
+			[]uint32{define.FrameSegmentSize + 3}, 2},
+		{"not ticked", []uint32{3}, 0, []uint32{0}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ls := NewLockStep(0, nil)
+			defer ls.Reset()
+			for i, frameId := range test.frames {
+				if ls.PushCommandAt(frameId, testInput(1, int32(i))) != nil {
+					t.Fatalf("push frame %d failed", frameId)
+				}
+			}
+			for i := 0; i < test.ticks; i++ {
+				ls.Tick()
+			}
+			for i, frameId := range test.frames {
+				frame := ls.GetFrame(frameId)
+				if frame == nil || len(frame.GetData()) != 1 || frame.GetData()[0].Sid != int32(i) {
+					t.Fatalf("frame %d got %v", frameId, frame)
+				}
+				if frame.GetIdx() != frameId {
+					t.Fatalf("frame %d got idx %d", frameId, frame.GetIdx())
+				}
+			}
+			for _, frameId := range test.empty {
+				if ls.GetFrame(frameId) != nil {
+					t.Fatalf("frame %d without input got", frameId)
+				}
+			}
+			if frames := ls.GetRangeFrames(0, uint32(test.ticks)); len(frames) != test.ranged {
+				t.Fatalf("range got %d frames, want %d", len(frames), test.ranged)
+			}
+		})
+	}
+}
+
+func TestLockStepPushClosed(t *testing.T) {
+	tests := []struct {
+		name    string
+		frameId uint32
+		input   *pb.InputData
+		err     error
+		result  pb.INPUT_RESULT //ack result of err
+	}{
+		{"closed frame", 1, testInput(1, 1), define.ErrFrameClosed, pb.INPUT_RESULT_INPUT_FrameClosed},
+		{"frame of evicted segment", 0, testInput(1, 1), define.ErrFrameClosed, pb.INPUT_RESULT_INPUT_FrameClosed},
+		{"current frame", define.FrameSegmentSize, testInput(1, 1), nil, pb.INPUT_RESULT_INPUT_Ok},
+		{"nil input", define.FrameSegmentSize, nil, define.ErrorOfInvalidPara, pb.INPUT_RESULT_INPUT_InvalidPayload},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			//first segment evicted
+			ls := NewLockStep(1, nil)
+			defer ls.Reset()
+			for i := 0; i < define.FrameSegmentSize; i++ {
+				ls.Tick()
+			}
+			err := ls.PushCommandAt(test.frameId, test.input)
+			if err != test.err {
+				t.Fatalf("push err %v, want %v", err, test.err)
+			}
+			if got := inputResultOf(err); got != test.result {
+				t.Fatalf("ack result %v, want %v", got, test.result)
+			}
+		})
+	}
+}
+
+func TestLockStepPushEvicted(t *testing.T) {
+	//frame count behind evicted segments, like frames pushed ahead then evicted
+	ls := NewLockStep(0, nil)
+	defer ls.Reset()
+	ls.firstFrame = define.FrameSegmentSize
+	err := ls.PushCommandAt(1, testInput(1, 1))
+	if err != define.ErrFrameEvicted || inputResultOf(err) != pb.INPUT_RESULT_INPUT_FrameEvicted {
+		t.Fatalf("push err %v, want %v", err, define.ErrFrameEvicted)
+	}
+}
+
+func TestLockStepEvict(t *testing.T) {
+	//inputs at start of first two segments
+	second := uint32(define.FrameSegmentSize)
+	tests := []struct {
+		name      string
+		maxMemory int
+		spill     bool     //with evict cb, or drop
+		ticks     uint32
+		first     uint32   //first kept frame
+		evicted   []uint32 //frames got by evict cb
+	}{
+		{"under cap", 0, true, second * 2, 0, nil},
+		{"cap reached current segment kept", segmentMemSize, true, second - 1, 0, nil},
+		{"cap reached spill at segment edge", segmentMemSize, true, second, second, []uint32{1}},
+		{"cap reached drop at segment edge", segmentMemSize, false, second, second, nil},
+		{"cap reached spill all closed", 1, true, second * 2, second * 2, []uint32{1, second}},
+		{"cap reached drop all closed", 1, false, second * 2, second * 2, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			evicted := make([]uint32, 0)
+			var onEvict func(frames []*pb.FrameData)
+			if test.spill {
+				onEvict = func(frames []*pb.FrameData) {
+					for _, frame := range frames {
+						if len(frame.Input) != 1 {
+							t.Fatalf("evicted frame %d with %d inputs", frame.FrameID, len(frame.Input))
+						}
+						evicted = append(evicted, frame.FrameID)
+					}
+				}
+			}
+			ls := NewLockStep(test.maxMemory, onEvict)
+			defer ls.Reset()
+			for _, frameId := range []uint32{1, second} {
+				if ls.PushCommandAt(frameId, testInput(1, 1)) != nil {
+					t.Fatalf("push frame %d failed", frameId)
+				}
+			}
+			for i := uint32(0); i < test.ticks; i++ {
+				ls.Tick()
+			}
+			if ls.GetFirstFrame() != test.first {
+				t.Fatalf("first frame %d, want %d", ls.GetFirstFrame(), test.first)
+			}
+			if len(evicted) != len(test.evicted) {
+				t.Fatalf("evicted %v, want %v", evicted, test.evicted)
+			}
+			for i := range evicted {
+				if evicted[i] != test.evicted[i] {
+					t.Fatalf("evicted %v, want %v", evicted, test.evicted)
+				}
+			}
+
+			//evicted frames gone, kept frames readable
+			for _, frameId := range []uint32{1, second} {
+				if got := ls.GetFrame(frameId) != nil; got != (frameId >= test.first) {
+					t.Fatalf("frame %d kept %v, first frame %d", frameId, got, test.first)
+				}
+			}
+		})
+	}
+}
+
+//replay sink keeps frame ids
+type frameSink struct {
+	frameIds []uint32
+}
+
+func (f *frameSink) OnFrames(roomId uint64, frames []*pb.FrameData) error {
+	for _, frame := range frames {
+		f.frameIds = append(f.frameIds, frame.FrameID)
+	}
+	return nil
+}
+
+func (f *frameSink) Flush() error {
+	return nil
+}
+
+func TestFrameRetention(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		sink    bool
+		spilled int //frames pushed into sink
+	}{
+		{"spill by default", "", true, 1},
+		{"spill", define.FrameRetentionSpill, true, 1},
+		{"spill without sink drops", define.FrameRetentionSpill, false, 0},
+		{"drop", define.FrameRetentionDrop, true, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clk := clock.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			r := NewRoomWithClock(&conf.RoomConf{
+				RoomId:1,
+				Players:[]uint64{1, 2},
+				SecretKey:"k",
+				ManualTick:true,
+				MaxFrameMemory:1,
+				FrameRetention:test.policy,
+			}, clk)
+			t.Cleanup(func() {
+				r.Stop()
+				r.Wait()
+			})
+			sink := &frameSink{}
+			if test.sink {
+				r.SetReplaySink(sink)
+			}
+			p1 := connectSticky(r, 1)
+			p2 := connectSticky(r, 2)
+			readyTo(r, p1)
+			readyTo(r, p2)
+			r.Advance(1)
+
+			//first segment closed and over cap
+			sendTo(r, p1, pb.ID_MSG_Input, &pb.C2S_InputMsg{Sid:1})
+			r.Advance(define.FrameSegmentSize + 1)
+			var frameIds []uint32
+			r.query(func() {
+				frameIds = sink.frameIds
+			})
+			if len(frameIds) != test.spilled {
+				t.Fatalf("spilled frames %v, want %d", frameIds, test.spilled)
+			}
+		})
+	}
+}
+
+func TestLockStepInputPolicy(t *testing.T) {
+	//merger sums sid, drops merge with sid 0
+	sumMerge := func(frameId uint32, old, input *pb.InputData) *pb.InputData {
+		if input.Sid == 0 {
+			return nil
+		}
+		return &pb.InputData{Sid:old.Sid + input.Sid}
+	}
+	tests := []struct {
+		name           string
+		policy         string
+		inputsPerFrame int
+		merge          func(frameId uint32, old, input *pb.InputData) *pb.InputData
+		inputs         []*pb.InputData
+		errs           []error //return of each push
+		sids           []int32 //sids of frame in order
+		seqs           []uint32
+	}{
+		{"keep up to cap", "", 2, nil,
+			[]*pb.InputData{testInput(1, 1), testInput(1, 2), testInput(1, 3)},
+			[]error{nil, nil, define.ErrInputDuplicate}, []int32{1, 2}, []uint32{0, 1}},
+		{"keep default cap one", define.InputPolicyKeep, 0, nil,
+			[]*pb.InputData{testInput(1, 1), testInput(1, 2), testInput(2, 3)},
+			[]error{nil, define.ErrInputDuplicate, nil}, []int32{1, 3}, []uint32{0, 0}},
+		{"keep ordered by seat", define.InputPolicyKeep, 2, nil,
+			[]*pb.InputData{testInput(2, 1), testInput(1, 2), testInput(2, 3)},
+			[]error{nil, nil, nil}, []int32{2, 1, 3}, []uint32{0, 0, 1}},
+		{"last wins", define.InputPolicyLast, 0, nil,
+			[]*pb.InputData{testInput(1, 1), testInput(2, 2), testInput(1, 3)},
+			[]error{nil, nil, nil}, []int32{3, 2}, []uint32{0, 0}},
+		{"merge", define.InputPolicyMerge, 0, sumMerge,
+			[]*pb.InputData{testInput(1, 1), testInput(1, 2), testInput(1, 3)},
+			[]error{nil, nil, nil}, []int32{6}, []uint32{0}},
+		{"merge without merger last wins", define.InputPolicyMerge, 0, nil,
+			[]*pb.InputData{testInput(1, 1), testInput(1, 2)},
+			[]error{nil, nil}, []int32{2}, []uint32{0}},
+		{"merger returns nil drops input", define.InputPolicyMerge, 0, sumMerge,
+			[]*pb.InputData{testInput(1, 1), testInput(1, 0), testInput(1, 2)},
+			[]error{nil, define.ErrInputDropped, nil}, []int32{3}, []uint32{0}},
+		//player 0 on system seat
+		{"system input not limited", "", 1, nil,
+			[]*pb.InputData{testInput(0, 1), testInput(0, 2)},
+			[]error{nil, nil}, []int32{1, 2}, []uint32{0, 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ls := NewLockStep(0, nil)
+			defer ls.Reset()
+			ls.SetInputPolicy(test.policy, test.inputsPerFrame, test.merge)
+			for i, input := range test.inputs {
+				if err := ls.PushCommandAt(0, input); err != test.errs[i] {
+					t.Fatalf("push %d err %v, want %v", i, err, test.errs[i])
+				}
+			}
+			frame := ls.GetFrame(0)
+			if frame == nil || len(frame.GetData()) != len(test.sids) {
+				t.Fatalf("frame %v, want sids %v", frame, test.sids)
+			}
+			for i, input := range frame.GetData() {
+				if input.Sid != test.sids[i] || input.Seq != test.seqs[i] {
+					t.Fatalf("frame inputs %v, want sids %v seqs %v", frame.GetData(), test.sids, test.seqs)
+				}
+			}
+		})
+	}
+}
@@ -1,14 +1,29 @@
 package room
 
 import (
+	"github.com/andyzhou/thorn/clock"
+	"github.com/andyzhou/thorn/define"
 	"github.com/andyzhou/thorn/iface"
-	"time"
+	"github.com/andyzhou/thorn/pb"
+	"sync"
+	"sync/atomic"
 )
 
 /*
  * player face, implement of IPlayer
  */
 
+//input lateness stats
+type inputStats struct {
+	inputs        uint32
+	late          uint32
+	lateFrames    uint64 //sum of late frames
+	maxLateFrames uint32
+	recentInputs  uint32
+	recentLate    uint32
+	sync.Mutex
+}
+
 //face info
 type Player struct {
 	id                uint64 //player id
@@ -17,27 +32,60 @@ type Player struct {
 	isOnline          bool
 	loadingProgress   int32
 	lastHeartBeatTime int64
-	sendFrameCount    uint32
-	client            iface.IConn //original udp conn
+	lastInputTime     int64  //for afk check
+	afkWarned         bool   //warned in current afk period
+	timedOut          bool   //marked disconnected by heart beat timeout, conn kept
+	forfeited         bool   //forfeited, can't reconnect
+	disconnectTime    int64  //second of disconnect in reconnect grace, 0 means none
+	bot               bool   //inputs supplied by bot until reconnect
+	sendFrameCount    uint32 //next live frame to send
+	catchUpFrame      uint32 //next backlog frame to send
+	catchUpEnd        uint32 //backlog end, not included
+	ackFrameCount     uint32 //contiguous frames confirmed by client
+	ackTime           int64  //ms of last ack progress or resend, 0 means client never acked
+	inputStats        inputStats
+	inputTime         int64  //current input rate limit second
+	inputCount        int    //inputs in current second
+	rtt               int64  //smoothed round trip time, ms
+	jitter            int64  //smoothed rtt variation, ms
+	rttProbeFrame     uint32 //frame sent for rtt probe
+	rttProbeTime      int64  //send time of probe frame, ms, 0 means no probe
+	inputHold         uint32 //extra input delay frames by latency equalization
+	syncClientTime    int64  //client send time of last clock sync, client ms
+	syncRecvTime      int64  //server receive time of last clock sync, ms
+	syncSendTime      int64  //server send time of last clock sync, ms, 0 means none
+	clockOffset       int64  //smoothed server clock - client clock, ms
+	clockSynced       bool
+	client            iface.IConn   //original udp conn
+	observers         []iface.IConn //other sessions of player, mirror messages
+	clock             iface.IClock
 }
 
 //construct
-func NewPlayer(id uint64, idx int32) *Player {
+func NewPlayer(id uint64, idx int32, clk iface.IClock) *Player {
 	//self init
 	this := &Player{
 		id:id,
 		idx:idx,
+		clock:clock.Or(clk),
 	}
 	return this
 }
 
 func (f *Player) CleanUp() {
+	//flush status or close message before close
 	if f.client != nil {
-		f.client.Close()
+		closeConn(f.client)
+	}
+	for _, conn := range f.observers {
+		closeConn(conn)
 	}
+	f.observers = nil
 	f.client = nil
 	f.isOnline = false
 	f.isReady = false
+	f.timedOut = false
+	f.SetCatchUp(0, 0)
 }
 
 func (f *Player) GetConn() iface.IConn {
@@ -56,11 +104,17 @@ func (f *Player) Connect(conn iface.IConn) {
 	f.client = conn
 	f.isOnline = true
 	f.isReady = false
-	f.lastHeartBeatTime = time.Now().Unix()
+	f.lastHeartBeatTime = f.clock.Now().Unix()
+	f.lastInputTime = f.lastHeartBeatTime
+	f.afkWarned = false
+	f.timedOut = false
+	f.disconnectTime = 0
+	f.rttProbeTime = 0
+	f.SetCatchUp(0, 0)
 }
 
 func (f *Player) IsOnline() bool {
-	return f.client != nil && f.isOnline
+	return f.client != nil && f.isOnline && !f.timedOut
 }
 
 func (f *Player) IsReady() bool {
@@ -80,13 +134,76 @@ func (f *Player) SetProgress(value int32) {
 }
 
 func (f *Player) RefreshHeartbeatTime() {
-	f.lastHeartBeatTime = time.Now().Unix()
+	f.lastHeartBeatTime = f.clock.Now().Unix()
 }
 
 func (f *Player) GetLastHeartbeatTime() int64 {
 	return f.lastHeartBeatTime
 }
 
+//refresh input time, end afk period
+func (f *Player) RefreshInputTime() {
+	f.lastInputTime = f.clock.Now().Unix()
+	f.afkWarned = false
+}
+
+func (f *Player) GetLastInputTime() int64 {
+	return f.lastInputTime
+}
+
+func (f *Player) SetAfkWarned() {
+	f.afkWarned = true
+}
+
+func (f *Player) IsAfkWarned() bool {
+	return f.afkWarned
+}
+
+//mark disconnected or online again, conn kept
+func (f *Player) SetTimedOut(timedOut bool) {
+	f.timedOut = timedOut
+}
+
+func (f *Player) IsTimedOut() bool {
+	return f.client != nil && f.timedOut
+}
+
+func (f *Player) SetForfeit() {
+	f.forfeited = true
+}
+
+func (f *Player) IsForfeit() bool {
+	return f.forfeited
+}
+
+//set second of disconnect for reconnect grace, 0 means none
+func (f *Player) SetDisconnectTime(t int64) {
+	f.disconnectTime = t
+}
+
+func (f *Player) GetDisconnectTime() int64 {
+	return f.disconnectTime
+}
+
+//set seat controlled by bot or not
+func (f *Player) SetBot(bot bool) {
+	f.bot = bot
+}
+
+func (f *Player) IsBot() bool {
+	return f.bot
+}
+
+//add observer session, false if over define.MaxObservers
+func (f *Player) AddObserver(conn iface.IConn) bool {
+	f.pruneObservers()
+	if len(f.observers) >= define.MaxObservers {
+		return false
+	}
+	f.observers = append(f.observers, conn)
+	return true
+}
+
 func (f *Player) SetSendFrameCount(c uint32) {
 	f.sendFrameCount = c
 }
@@ -95,11 +212,260 @@ func (f *Player) GetSendFrameCount() uint32 {
 	return f.sendFrameCount
 }
 
-func (f *Player) SendMessage(packet iface.IPacket) {
-	if packet == nil || !f.IsOnline() {
+//set frames confirmed by client, 0 also clears ack time
+func (f *Player) SetAckFrameCount(c uint32) {
+	old := atomic.SwapUint32(&f.ackFrameCount, c)
+	if c == 0 {
+		f.ackTime = 0
+	}else if c > old || f.ackTime == 0 {
+		f.ackTime = f.clock.Now().UnixMilli()
+	}
+}
+
+//get frames confirmed by client
+func (f *Player) GetAckFrameCount() uint32 {
+	return atomic.LoadUint32(&f.ackFrameCount)
+}
+
+//set ms of ack progress, re-arm resend of un-acked frames
+func (f *Player) SetAckTime(t int64) {
+	f.ackTime = t
+}
+
+//get ms of last ack progress or resend, 0 means client never acked
+func (f *Player) GetAckTime() int64 {
+	return f.ackTime
+}
+
+//check input over rate limit or not, limit 0 means no limit
+func (f *Player) AllowInput(now int64, limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+	if now != f.inputTime {
+		f.inputTime = now
+		f.inputCount = 0
+	}
+	f.inputCount++
+	return f.inputCount <= limit
+}
+
+//record one input, lateFrames 0 means in time
+func (f *Player) RecordInput(lateFrames uint32) {
+	stats := &f.inputStats
+	stats.Lock()
+	defer stats.Unlock()
+	stats.inputs++
+	stats.recentInputs++
+	if lateFrames <= 0 {
+		return
+	}
+	stats.late++
+	stats.recentLate++
+	stats.lateFrames += uint64(lateFrames)
+	if lateFrames > stats.maxLateFrames {
+		stats.maxLateFrames = lateFrames
+	}
+}
+
+//get input stats, reset recent counter if resetRecent is true
+func (f *Player) GetInputStats(resetRecent bool) *pb.S2C_InputStatsMsg {
+	stats := &f.inputStats
+	stats.Lock()
+	defer stats.Unlock()
+	msg := &pb.S2C_InputStatsMsg{
+		Inputs:stats.inputs,
+		Late:stats.late,
+		MaxLateFrames:stats.maxLateFrames,
+		RecentInputs:stats.recentInputs,
+		RecentLate:stats.recentLate,
+	}
+	if stats.late > 0 {
+		msg.AvgLateFrames = float32(stats.lateFrames) / float32(stats.late)
+	}
+	if resetRecent {
+		stats.recentInputs = 0
+		stats.recentLate = 0
+	}
+	return msg
+}
+
+//start rtt probe with sent frame, skipped if probe pending
+//now is ms.
+func (f *Player) StartRTTProbe(frameId uint32, now int64) {
+	if f.rttProbeTime > 0 {
+		return
+	}
+	f.rttProbeFrame = frameId
+	f.rttProbeTime = now
+}
+
+//end rtt probe if ack cover probe frame, now is ms
+func (f *Player) EndRTTProbe(ackFrameCount uint32, now int64) {
+	if f.rttProbeTime <= 0 || ackFrameCount <= f.rttProbeFrame {
 		return
 	}
-	if nil != f.client.AsyncWritePacket(packet, 0) {
+	f.RecordRTT(now - f.rttProbeTime)
+	f.rttProbeTime = 0
+}
+
+//record rtt sample, ms
+func (f *Player) RecordRTT(sample int64) {
+	if sample < 0 {
+		return
+	}
+	rtt := atomic.LoadInt64(&f.rtt)
+	jitter := atomic.LoadInt64(&f.jitter)
+	if rtt <= 0 {
+		rtt = sample
+		jitter = sample / 2
+	}else{
+		delta := sample - rtt
+		if delta < 0 {
+			delta = -delta
+		}
+		jitter += (delta - jitter) / 4
+		rtt += (sample - rtt) / 8
+	}
+	atomic.StoreInt64(&f.rtt, rtt)
+	atomic.StoreInt64(&f.jitter, jitter)
+}
+
+//get smoothed rtt, ms, 0 means unknown
+func (f *Player) GetRTT() int64 {
+	return atomic.LoadInt64(&f.rtt)
+}
+
+//get smoothed rtt variation, ms
+func (f *Player) GetJitter() int64 {
+	return atomic.LoadInt64(&f.jitter)
+}
+
+//get connect quality
+func (f *Player) GetQuality() *pb.PlayerQuality {
+	quality := &pb.PlayerQuality{
+		Id:f.id,
+		Online:f.IsOnline(),
+		Rtt:f.GetRTT(),
+		Jitter:f.GetJitter(),
+	}
+	if conn := f.client; conn != nil {
+		stats := conn.GetStats()
+		quality.PacketsIn = stats.PacketsIn
+		quality.PacketsOut = stats.PacketsOut
+		quality.Pending = stats.Pending
+		quality.Segments = stats.Segments
+		quality.Retransmits = stats.Retransmits
+		quality.Parity = stats.Parity
+		quality.Recovered = stats.Recovered
+		if stats.Segments > 0 {
+			quality.Loss = float32(stats.Retransmits) * 100 / float32(stats.Segments)
+		}
+	}
+	return quality
+}
+
+//set last clock sync exchange, all times are ms
+func (f *Player) SetClockSync(clientTime, recvTime, sendTime int64) {
+	f.syncClientTime = clientTime
+	f.syncRecvTime = recvTime
+	f.syncSendTime = sendTime
+}
+
+//end clock sync exchange by client receive time of reply, ms
+//update rtt and clock offset, false if not match last exchange.
+func (f *Player) EndClockSync(sendTime, clientRecvTime int64) bool {
+	if f.syncSendTime <= 0 || sendTime != f.syncSendTime {
+		return false
+	}
+	f.syncSendTime = 0
+	rtt := (clientRecvTime - f.syncClientTime) - (sendTime - f.syncRecvTime)
+	if rtt < 0 {
+		return false
+	}
+	offset := ((f.syncRecvTime - f.syncClientTime) + (sendTime - clientRecvTime)) / 2
+	f.RecordRTT(rtt)
+
+	//smooth offset
+	current := atomic.LoadInt64(&f.clockOffset)
+	if !f.clockSynced {
+		current = offset
+		f.clockSynced = true
+	}else{
+		current += (offset - current) / 8
+	}
+	atomic.StoreInt64(&f.clockOffset, current)
+	return true
+}
+
+//get server clock - client clock, ms
+func (f *Player) GetClockOffset() int64 {
+	return atomic.LoadInt64(&f.clockOffset)
+}
+
+//set extra input delay frames
+func (f *Player) SetInputHold(frames uint32) {
+	atomic.StoreUint32(&f.inputHold, frames)
+}
+
+//get extra input delay frames
+func (f *Player) GetInputHold() uint32 {
+	return atomic.LoadUint32(&f.inputHold)
+}
+
+//set backlog frames [from, end) for catch up
+func (f *Player) SetCatchUp(from, end uint32) {
+	f.catchUpFrame = from
+	f.catchUpEnd = end
+}
+
+//get backlog frames [from, end), from >= end means no backlog
+func (f *Player) GetCatchUp() (uint32, uint32) {
+	return f.catchUpFrame, f.catchUpEnd
+}
+
+//send message, keep connect if send queue is full
+//observers get a copy, even if player is offline.
+func (f *Player) SendMessage(packet iface.IPacket) {
+	f.TrySendMessage(packet)
+}
+
+//send message, return error if not queued
+func (f *Player) TrySendMessage(packet iface.IPacket) error {
+	if packet == nil {
+		return define.ErrConnClosing
+	}
+	if len(f.observers) > 0 {
+		f.sendObservers(packet)
+	}
+	if !f.IsOnline() {
+		return define.ErrConnClosing
+	}
+	err := f.client.AsyncWritePacket(packet, 0)
+	if err != nil && err != define.ErrWriteBlocking {
 		f.client.Close()
 	}
+	return err
+}
+
+//send message to observers
+func (f *Player) sendObservers(packet iface.IPacket) {
+	for _, conn := range f.observers {
+		err := conn.AsyncWritePacket(packet, 0)
+		if err != nil && err != define.ErrWriteBlocking {
+			conn.Close()
+		}
+	}
+	f.pruneObservers()
+}
+
+//remove closed observers
+func (f *Player) pruneObservers() {
+	observers := f.observers[:0]
+	for _, conn := range f.observers {
+		if !conn.IsClosed() {
+			observers = append(observers, conn)
+		}
+	}
+	f.observers = observers
 }
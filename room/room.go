@@ -1,9 +1,12 @@
 package room
 
 import (
+	"github.com/andyzhou/thorn/clock"
 	"github.com/andyzhou/thorn/conf"
 	"github.com/andyzhou/thorn/define"
 	"github.com/andyzhou/thorn/iface"
+	"github.com/andyzhou/thorn/logger"
+	"github.com/andyzhou/thorn/pb"
 	"github.com/andyzhou/thorn/protocol"
 	"log"
 	"sync"
@@ -15,28 +18,67 @@ import (
  * room face, implement of IRoom
  */
 
+//manual tick request
+type tickReq struct {
+	ticks    int
+	doneChan chan bool
+}
+
+//system input request
+type sysInputReq struct {
+	frameId  uint32
+	input    *pb.InputData
+	result   uint32
+	err      error
+	doneChan chan bool
+}
+
+//query request, run on room process
+type queryReq struct {
+	query    func()
+	doneChan chan bool
+}
+
 //face info
 type Room struct {
-	cfg        *conf.RoomConf //room config
-	game       iface.IGame    //game instance
-	inChan     chan iface.IConn
-	outChan    chan iface.IConn
-	packetChan chan iface.IPlayerPacket
-	closeChan  chan bool
-	closeFlag  int32
-	closeOnce  sync.Once
-	wg         sync.WaitGroup
+	cfg         *conf.RoomConf //room config
+	game        iface.IGame    //game instance
+	inChan      chan iface.IConn
+	outChan     chan iface.IConn
+	packetChan  chan iface.IPlayerPacket
+	tickChan    chan *tickReq  //manual tick request
+	sysChan     chan *sysInputReq
+	queryChan   chan *queryReq //read game state from outside
+	resultSink  iface.IResultSink
+	clock       iface.IClock
+	countDown   iface.ITimer   //time limit timer
+	closeChan   chan bool
+	doneChan    chan bool      //closed after main process exit
+	closeFlag   int32
+	closeReason int32
+	closeOnce   sync.Once
+	wg          sync.WaitGroup
 }
 
 //construct
 func NewRoom(cfg *conf.RoomConf) *Room {
+	return NewRoomWithClock(cfg, nil)
+}
+
+//construct with clock, real clock if nil
+func NewRoomWithClock(cfg *conf.RoomConf, clk iface.IClock) *Room {
 	//self init
 	this := &Room{
 		cfg: cfg,
+		clock: clock.Or(clk),
 		inChan: make(chan iface.IConn, define.RoomInOutChanSize),
 		outChan: make(chan iface.IConn, define.RoomInOutChanSize),
 		packetChan: make(chan iface.IPlayerPacket, define.RoomMessageChanSize),
+		tickChan: make(chan *tickReq),
+		sysChan: make(chan *sysInputReq),
+		queryChan: make(chan *queryReq),
 		closeChan: make(chan bool, 1),
+		doneChan: make(chan bool),
 	}
 
 	//check default values
@@ -50,12 +92,22 @@ func NewRoom(cfg *conf.RoomConf) *Room {
 					cfg.Players,
 					cfg.RandomSeed,
 					this,
+					this.clock,
 				)
+	this.game.SetFrameRetention(cfg.MaxFrameMemory, cfg.FrameRetention)
+	this.game.SetCatchUpRate(this.getCatchUpPerTick(cfg.Frequency))
+	this.game.SetInputRateLimit(cfg.InputRateLimit)
+	this.game.SetInputPolicy(cfg.InputPolicy, cfg.InputsPerFrame)
+	this.game.SetTiming(cfg.Frequency, cfg.InputDelay, cfg.AdaptiveTiming)
+	this.game.SetLatencyEqualization(cfg.MaxInputHold)
+	this.game.SetSupervision(cfg.HeartbeatTimeout, cfg.TimeoutAction, cfg.AfkTimeout, cfg.AfkAction)
+	this.game.SetReconnectGrace(cfg.ReconnectGrace, cfg.GraceOutcome)
+	this.game.SetDuplicateLogin(cfg.DuplicateLogin)
 
 	//if room has time limit, setup timer func
 	if cfg.TimeLimit > 0 {
 		duration := time.Duration(cfg.TimeLimit) * time.Second
-		time.AfterFunc(duration, this.cbForCountDown)
+		this.countDown = this.clock.AfterFunc(duration, this.cbForCountDown)
 	}
 
 	//spawn main process
@@ -64,11 +116,110 @@ func NewRoom(cfg *conf.RoomConf) *Room {
 }
 
 func (f *Room) Stop() {
+	f.StopWithReason(pb.CLOSE_REASON_CLOSE_Normal)
+}
+
+func (f *Room) StopWithReason(reason pb.CLOSE_REASON) {
 	f.closeOnce.Do(func() {
+		atomic.StoreInt32(&f.closeReason, int32(reason))
 		close(f.closeChan)
 	})
 }
 
+//wait main process exit
+func (f *Room) Wait() {
+	<- f.doneChan
+}
+
+//process all pending connects and messages, then tick n times
+//only for room with manual tick, return false if room stopped.
+func (f *Room) Advance(ticks int) bool {
+	req := &tickReq{
+		ticks:ticks,
+		doneChan:make(chan bool),
+	}
+	select {
+	case f.tickChan <- req:
+	case <- f.doneChan:
+		return false
+	}
+	select {
+	case <- req.doneChan:
+	case <- f.doneChan:
+		return false
+	}
+	return true
+}
+
+//process all pending connects and messages
+func (f *Room) Sync() bool {
+	return f.Advance(0)
+}
+
+func (f *Room) SetResultSink(sink iface.IResultSink) {
+	f.resultSink = sink
+}
+
+func (f *Room) SetReplaySink(sink iface.IReplaySink) {
+	f.game.SetReplaySink(sink)
+}
+
+func (f *Room) SetInputMerger(merger iface.IInputMerger) {
+	f.game.SetInputMerger(merger)
+}
+
+func (f *Room) SetBotProvider(provider iface.IBotProvider) {
+	f.game.SetBotProvider(provider)
+}
+
+//push system input into current or future frame, delivered to all players in frame
+//frameId 0 means current frame, return frame id of input.
+func (f *Room) PushSystemInput(frameId uint32, input *pb.InputData) (uint32, error) {
+	req := &sysInputReq{
+		frameId:frameId,
+		input:input,
+		doneChan:make(chan bool),
+	}
+	select {
+	case f.sysChan <- req:
+	case <- f.doneChan:
+		return 0, define.ErrRoomNotRunning
+	}
+	select {
+	case <- req.doneChan:
+	case <- f.doneChan:
+		return 0, define.ErrRoomNotRunning
+	}
+	return req.result, req.err
+}
+
+//get connect quality of all players
+func (f *Room) GetQuality() []*pb.PlayerQuality {
+	var quality []*pb.PlayerQuality
+	f.query(func() {
+		quality = f.game.GetQuality()
+	})
+	return quality
+}
+
+//get frames not confirmed of all players
+func (f *Room) GetPlayerLags() map[uint64]uint32 {
+	var lags map[uint64]uint32
+	f.query(func() {
+		lags = f.game.GetPlayerLags()
+	})
+	return lags
+}
+
+//get input lateness stats of all players
+func (f *Room) GetInputStats() map[uint64]*pb.S2C_InputStatsMsg {
+	var stats map[uint64]*pb.S2C_InputStatsMsg
+	f.query(func() {
+		stats = f.game.GetInputStats()
+	})
+	return stats
+}
+
 func (f *Room) GetId() uint64 {
 	return f.cfg.RoomId
 }
@@ -110,6 +261,8 @@ func (f *Room) OnConnect(conn iface.IConn) bool {
 	//async send to chan
 	select {
 	case f.inChan <- conn:
+	case <- f.doneChan:
+		return false
 	}
 	return true
 }
@@ -137,11 +290,15 @@ func (f *Room) OnMessage(conn iface.IConn, packet iface.IPacket) (bRet bool) {
 	//init player packet
 	playerPacket := protocol.NewPlayerPacket()
 	playerPacket.SetId(playerId)
+	playerPacket.SetConn(conn)
 	playerPacket.SetPacket(packet)
 
 	//async send to chan
 	select {
 	case f.packetChan <- playerPacket:
+	case <- f.doneChan:
+		bRet = false
+		return
 	}
 	bRet = true
 	return
@@ -153,6 +310,7 @@ func (f *Room) OnClose(conn iface.IConn) {
 	//async send to chan
 	select {
 	case f.outChan <- conn:
+	case <- f.doneChan:
 	}
 }
 
@@ -168,8 +326,33 @@ func (f *Room) OnLeaveGame(roomId, playerId uint64) {
 	log.Printf("room %d OnLeaveGame %d\n", roomId, playerId)
 }
 
+func (f *Room) OnLeaveGameWithReason(roomId, playerId uint64, reason pb.LEAVE_REASON) {
+	log.Printf("room %d OnLeaveGame %d, reason:%v\n", roomId, playerId, reason)
+}
+
+func (f *Room) OnPlayerLag(roomId, playerId uint64, lag uint32) {
+	logger.Debugf("room %d OnPlayerLag %d, lag:%d\n", roomId, playerId, lag)
+}
+
+//report forfeit to host by result sink
+func (f *Room) OnPlayerForfeit(roomId, playerId uint64, reason pb.LEAVE_REASON) {
+	log.Printf("room %d OnPlayerForfeit %d, reason:%v\n", roomId, playerId, reason)
+	if f.resultSink != nil {
+		err := f.resultSink.OnForfeit(roomId, playerId, reason)
+		if err != nil {
+			log.Printf("room %d OnPlayerForfeit, result sink failed, err:%v\n", roomId, err)
+		}
+	}
+}
+
 func (f *Room) OneGameOver(roomId uint64) {
 	log.Printf("room %d OneGameOver\n", roomId)
+	if f.resultSink != nil {
+		err := f.resultSink.OnResult(roomId, f.game.GetResult(), f.game.GetOverReason())
+		if err != nil {
+			log.Printf("room %d OneGameOver, result sink failed, err:%v\n", roomId, err)
+		}
+	}
 	atomic.StoreInt32(&f.closeFlag, 1)
 }
 
@@ -187,25 +370,31 @@ func (f *Room) cbForCountDown() {
 func (f *Room) runMainProcess() {
 	var (
 		//ticker = time.NewTicker(define.RoomTickTimer)
-		ticker *time.Ticker
+		ticker iface.ITicker
 		conn iface.IConn
 		message iface.IPlayerPacket
-		isOk, bRet bool
+		isOk bool
 	)
 
 	//init key data
-	seconds := int64(time.Second) / int64(f.cfg.Frequency)
-	duration := time.Duration(seconds)
-	ticker = time.NewTicker(duration)
+	frequency := f.cfg.Frequency
+	ticker = f.clock.NewTicker(time.Second / time.Duration(frequency))
+	tickerChan := ticker.C()
+	if f.cfg.ManualTick {
+		//tick by Advance
+		ticker.Stop()
+		tickerChan = nil
+	}
 
 	//defer
 	defer func() {
 		//clean up
-		f.game.Close()
+		f.game.CloseWithReason(pb.CLOSE_REASON(atomic.LoadInt32(&f.closeReason)))
 		ticker.Stop()
-		close(f.inChan)
-		close(f.outChan)
-		close(f.packetChan)
+		if f.countDown != nil {
+			f.countDown.Stop()
+		}
+		close(f.doneChan)
 	}()
 
 	//loop
@@ -215,51 +404,151 @@ func (f *Room) runMainProcess() {
 			//closed
 			return
 
-		case <- ticker.C:
+		case <- tickerChan:
 			{
 				//game ticker
-				if !f.game.Tick(time.Now().Unix()) {
+				if !f.game.Tick(f.clock.Now().Unix()) {
 					break
 				}
+
+				//frequency changed by adaptive timing, keep catch up rate per second
+				if f.game.GetFrequency() != frequency {
+					frequency = f.game.GetFrequency()
+					ticker.Stop()
+					ticker = f.clock.NewTicker(time.Second / time.Duration(frequency))
+					tickerChan = ticker.C()
+					f.game.SetCatchUpRate(f.getCatchUpPerTick(frequency))
+				}
+			}
+
+		case req := <- f.tickChan:
+			{
+				//manual tick, process pending first
+				f.processPending()
+				for i := 0; i < req.ticks; i++ {
+					f.game.Tick(f.clock.Now().Unix())
+					f.processPending()
+				}
+				if f.game.GetFrequency() != frequency {
+					frequency = f.game.GetFrequency()
+					f.game.SetCatchUpRate(f.getCatchUpPerTick(frequency))
+				}
+				close(req.doneChan)
+			}
+
+		case req := <- f.sysChan:
+			{
+				//system input from server
+				req.result, req.err = f.game.PushSystemInput(req.frameId, req.input)
+				close(req.doneChan)
+			}
+
+		case req := <- f.queryChan:
+			{
+				//read game state for outside
+				req.query()
+				close(req.doneChan)
 			}
 
 		case message, isOk = <- f.packetChan:
 			if isOk {
 				//input message from player
-				f.game.ProcessMessage(message.GetId(), message.GetPacket())
+				f.game.ProcessMessageByConn(message.GetId(), message.GetConn(), message.GetPacket())
 			}
 
 		case conn, isOk = <- f.inChan:
 			if isOk {
-				//join room
-				//get player id
-				playerId, ok := conn.GetExtraData().(uint64)
-				if ok && playerId > 0 {
-					//join game
-					bRet = f.game.JoinGame(playerId, conn)
-					if !bRet {
-						conn.Close()
-					}
-				}else{
-					conn.Close()
-				}
+				f.joinGame(conn)
 			}
 
 		case conn, isOk = <- f.outChan:
 			if isOk {
-				//leave room
-				//get player id
-				playerId, ok := conn.GetExtraData().(uint64)
-				if ok && playerId > 0 {
-					//leave game
-					bRet = f.game.LeaveGame(playerId)
-					if !bRet {
-						conn.Close()
-					}
-				}else{
-					conn.Close()
-				}
+				f.leaveGame(conn)
 			}
 		}
 	}
-}
\ No newline at end of file
+}
+
+//run query on room process, avoid race with game
+//run directly if main process exited.
+func (f *Room) query(query func()) {
+	req := &queryReq{
+		query:query,
+		doneChan:make(chan bool),
+	}
+	select {
+	case f.queryChan <- req:
+		<- req.doneChan
+	case <- f.doneChan:
+		query()
+	}
+}
+
+//get backlog frames per tick at frequency
+func (f *Room) getCatchUpPerTick(frequency int) int {
+	catchUpRate := f.cfg.CatchUpRate
+	if catchUpRate <= 0 {
+		catchUpRate = define.CatchUpRate
+	}
+	return catchUpRate / frequency
+}
+
+//process pending connects and messages, join first and leave last
+func (f *Room) processPending() {
+	for {
+		select {
+		case conn := <- f.inChan:
+			f.joinGame(conn)
+			continue
+		default:
+		}
+		select {
+		case message := <- f.packetChan:
+			f.game.ProcessMessageByConn(message.GetId(), message.GetConn(), message.GetPacket())
+			continue
+		default:
+		}
+		select {
+		case conn := <- f.outChan:
+			f.leaveGame(conn)
+			continue
+		default:
+		}
+		return
+	}
+}
+
+//join room
+func (f *Room) joinGame(conn iface.IConn) {
+	//get player id
+	playerId, ok := conn.GetExtraData().(uint64)
+	if ok && playerId > 0 {
+		//join game, flush reply before close
+		if !f.game.JoinGame(playerId, conn) {
+			closeConn(conn)
+		}
+	}else{
+		conn.Close()
+	}
+}
+
+//leave room
+func (f *Room) leaveGame(conn iface.IConn) {
+	//get player id
+	playerId, ok := conn.GetExtraData().(uint64)
+	if ok && playerId > 0 {
+		//leave game, skip if conn replaced
+		if !f.game.LeaveGameByConn(playerId, conn) {
+			conn.Close()
+		}
+	}else{
+		conn.Close()
+	}
+}
+//close conn after queued packets written, not block
+func closeConn(conn iface.IConn) {
+	go func() {
+		conn.Flush(define.ConnFlushTimeout)
+		conn.Close()
+	}()
+}
@@ -0,0 +1,706 @@
+package room
+
+import (
+	"github.com/andyzhou/thorn/clock"
+	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/define"
+	"github.com/andyzhou/thorn/iface"
+	"github.com/andyzhou/thorn/network"
+	"github.com/andyzhou/thorn/pb"
+	"github.com/andyzhou/thorn/protocol"
+	"math"
+	"testing"
+	"time"
+)
+
+//conn keeps extra data on SetExtraData(nil), like old network.Conn
+//packets routed by room with conn itself, not embedded MemConn.
+type stickyConn struct {
+	*network.MemConn
+}
+
+func (f *stickyConn) SetExtraData(data interface{}) bool {
+	if data == nil {
+		return false
+	}
+	return f.MemConn.SetExtraData(data)
+}
+
+//new room with manual tick
+func newTestRoom(t *testing.T, policy string) *Room {
+	clk := clock.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := NewRoomWithClock(&conf.RoomConf{
+		RoomId:1,
+		Players:[]uint64{1, 2},
+		SecretKey:"k",
+		DuplicateLogin:policy,
+		ManualTick:true,
+	}, clk)
+	t.Cleanup(func() {
+		r.Stop()
+		r.Wait()
+	})
+	return r
+}
+
+//connect player by sticky conn
+func connectSticky(r *Room, playerId uint64) *stickyConn {
+	conn := &stickyConn{
+		MemConn:network.NewMemConn(nil, r.clock),
+	}
+	conn.SetExtraData(playerId)
+	r.OnConnect(conn)
+	r.Sync()
+	return conn
+}
+
+//send message of conn into room
+func sendTo(r *Room, conn iface.IConn, id pb.ID, msg interface{}) {
+	r.OnMessage(conn, protocol.NewPacketWithPara(uint8(id), msg))
+}
+
+//join and ready
+func readyTo(r *Room, conn iface.IConn) {
+	sendTo(r, conn, pb.ID_MSG_JoinRoom, nil)
+	sendTo(r, conn, pb.ID_MSG_Ready, nil)
+}
+
+//get sids of all inputs in frames written to conn
+func inputSids(conn *stickyConn) []int32 {
+	sids := make([]int32, 0)
+	for _, packet := range conn.TakePackets() {
+		if pb.ID(packet.GetMessageId()) != pb.ID_MSG_Frame {
+			continue
+		}
+		msg := &pb.S2C_FrameMsg{}
+		if packet.UnmarshalPB(msg) != nil {
+			continue
+		}
+		for _, frame := range msg.Frames {
+			for _, input := range frame.Input {
+				sids = append(sids, input.Sid)
+			}
+		}
+	}
+	return sids
+}
+
+//find connect reply written to conn
+func connectReply(conn *stickyConn) *pb.S2C_ConnectMsg {
+	for _, packet := range conn.TakePackets() {
+		if pb.ID(packet.GetMessageId()) == pb.ID_MSG_Connect {
+			msg := &pb.S2C_ConnectMsg{}
+			if packet.UnmarshalPB(msg) == nil {
+				return msg
+			}
+		}
+	}
+	return nil
+}
+
+//wait conn closed by async flush and close
+func waitClosed(conn iface.IConn) bool {
+	for i := 0; i < 100; i++ {
+		if conn.IsClosed() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestObserverInputDropped(t *testing.T) {
+	r := newTestRoom(t, define.DuplicateLoginObserve)
+	p1 := connectSticky(r, 1)
+	p2 := connectSticky(r, 2)
+	readyTo(r, p1)
+	readyTo(r, p2)
+	r.Advance(1)
+
+	//second session of player 1 joins as observer
+	observer := connectSticky(r, 1)
+	reply := connectReply(observer)
+	if reply == nil || !reply.Observer {
+		t.Fatalf("observer reply %v", reply)
+	}
+
+	//observer still holds player id, its input must be dropped
+	sendTo(r, observer, pb.ID_MSG_Input, &pb.C2S_InputMsg{Sid:9})
+	sendTo(r, p1, pb.ID_MSG_Input, &pb.C2S_InputMsg{Sid:1})
+	r.Advance(2)
+	sids := inputSids(p2)
+	if len(sids) != 1 || sids[0] != 1 {
+		t.Fatalf("inputs %v, want [1]", sids)
+	}
+	if observerSids := inputSids(observer); len(observerSids) != 1 || observerSids[0] != 1 {
+		t.Fatalf("observer inputs %v, want [1]", observerSids)
+	}
+}
+
+func TestKickedSessionIgnored(t *testing.T) {
+	r := newTestRoom(t, define.DuplicateLoginKick)
+	old := connectSticky(r, 1)
+	p2 := connectSticky(r, 2)
+	readyTo(r, old)
+	readyTo(r, p2)
+	r.Advance(1)
+
+	//new session kicks old one
+	current := connectSticky(r, 1)
+	readyTo(r, current)
+	r.Sync()
+
+	//old session still holds player id while flushing
+	sendTo(r, old, pb.ID_MSG_Input, &pb.C2S_InputMsg{Sid:5})
+	sendTo(r, current, pb.ID_MSG_Input, &pb.C2S_InputMsg{Sid:6})
+	r.Advance(2)
+	sids := inputSids(p2)
+	if len(sids) != 1 || sids[0] != 6 {
+		t.Fatalf("inputs %v, want [6]", sids)
+	}
+
+	//close of old session keeps new one
+	if !waitClosed(old) {
+		t.Fatal("old session not closed")
+	}
+	r.OnClose(old)
+	r.Sync()
+	for _, quality := range r.GetQuality() {
+		if quality.Id == 1 && !quality.Online {
+			t.Fatal("new session removed by close of old one")
+		}
+	}
+}
+
+func TestRejectReplyFlushed(t *testing.T) {
+	r := newTestRoom(t, define.DuplicateLoginReject)
+	connectSticky(r, 1)
+	rejected := connectSticky(r, 1)
+	if !waitClosed(rejected) {
+		t.Fatal("rejected session not closed")
+	}
+	reply := connectReply(rejected)
+	if reply == nil || reply.ErrorCode != pb.ERROR_CODE_ERR_Duplicate {
+		t.Fatalf("reject reply %v", reply)
+	}
+}
+
+func TestQueryNoRace(t *testing.T) {
+	r := newTestRoom(t, define.DuplicateLoginKick)
+	p1 := connectSticky(r, 1)
+	p2 := connectSticky(r, 2)
+	readyTo(r, p1)
+	readyTo(r, p2)
+
+	//admin reads while players reconnect and room ticks, checked by -race
+	doneChan := make(chan bool)
+	go func() {
+		defer close(doneChan)
+		for i := 0; i < 50; i++ {
+			r.GetQuality()
+			r.GetPlayerLags()
+			r.GetInputStats()
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		conn := connectSticky(r, 1)
+		readyTo(r, conn)
+		sendTo(r, conn, pb.ID_MSG_Input, &pb.C2S_InputMsg{Sid:int32(i)})
+		r.Advance(1)
+	}
+	<- doneChan
+
+	//room stopped, read directly
+	r.Stop()
+	r.Wait()
+	if quality := r.GetQuality(); len(quality) != 2 {
+		t.Fatalf("quality of %d players, want 2", len(quality))
+	}
+}
+
+func TestCatchUpRateFollowsFrequency(t *testing.T) {
+	r := newTestRoom(t, "")
+	game := r.game.(*Game)
+	rate := func() int {
+		var catchUpRate int
+		r.query(func() {
+			catchUpRate = game.catchUpRate
+		})
+		return catchUpRate
+	}
+	if rate() != define.CatchUpRate / define.RoomFrequency {
+		t.Fatalf("catch up rate %d at start", rate())
+	}
+
+	//frequency lowered as adaptive timing does
+	r.query(func() {
+		game.frequency = define.RoomFrequency / 2
+	})
+	r.Advance(1)
+	if want := define.CatchUpRate / (define.RoomFrequency / 2); rate() != want {
+		t.Fatalf("catch up rate %d, want %d", rate(), want)
+	}
+}
+
+//result sink keeps forfeits
+type forfeitSink struct {
+	forfeits map[uint64]pb.LEAVE_REASON
+}
+
+func (f *forfeitSink) OnResult(roomId uint64, result map[uint64]uint64, reason pb.CLOSE_REASON) error {
+	return nil
+}
+
+func (f *forfeitSink) OnForfeit(roomId, playerId uint64, reason pb.LEAVE_REASON) error {
+	f.forfeits[playerId] = reason
+	return nil
+}
+
+func (f *forfeitSink) Flush() error {
+	return nil
+}
+
+func TestForfeitReported(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *conf.RoomConf
+		close    bool                         //close conn of player 1, or keep it silent
+		forfeits map[uint64]pb.LEAVE_REASON
+	}{
+		{"grace forfeit", &conf.RoomConf{
+			ReconnectGrace:1,
+			GraceOutcome:define.GraceOutcomeForfeit,
+		}, true, map[uint64]pb.LEAVE_REASON{1:pb.LEAVE_REASON_LEAVE_Close}},
+		{"grace continue", &conf.RoomConf{
+			ReconnectGrace:1,
+			GraceOutcome:define.GraceOutcomeContinue,
+		}, true, map[uint64]pb.LEAVE_REASON{}},
+		{"timeout forfeit", &conf.RoomConf{
+			HeartbeatTimeout:2,
+			TimeoutAction:define.PlayerActionForfeit,
+		}, false, map[uint64]pb.LEAVE_REASON{1:pb.LEAVE_REASON_LEAVE_Timeout}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clk := clock.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			test.cfg.RoomId = 1
+			test.cfg.Players = []uint64{1, 2}
+			test.cfg.SecretKey = "k"
+			test.cfg.ManualTick = true
+			r := NewRoomWithClock(test.cfg, clk)
+			t.Cleanup(func() {
+				r.Stop()
+				r.Wait()
+			})
+			sink := &forfeitSink{forfeits:map[uint64]pb.LEAVE_REASON{}}
+			r.SetResultSink(sink)
+			p1 := connectSticky(r, 1)
+			p2 := connectSticky(r, 2)
+			readyTo(r, p1)
+			readyTo(r, p2)
+			r.Advance(1)
+			if test.close {
+				r.OnClose(p1)
+			}
+
+			//player 2 keeps heart beat until grace or timeout passed
+			for i := 0; i < 4; i++ {
+				sendTo(r, p2, pb.ID_MSG_Heartbeat, nil)
+				clk.Advance(time.Second)
+				r.Advance(1)
+			}
+			var forfeits map[uint64]pb.LEAVE_REASON
+			r.query(func() {
+				forfeits = sink.forfeits
+			})
+			if len(forfeits) != len(test.forfeits) {
+				t.Fatalf("forfeits %v, want %v", forfeits, test.forfeits)
+			}
+			for id, reason := range test.forfeits {
+				if got, ok := forfeits[id]; !ok || got != reason {
+					t.Fatalf("forfeits %v, want %v", forfeits, test.forfeits)
+				}
+			}
+		})
+	}
+}
+
+//result sink keeps close reasons of results
+type reasonSink struct {
+	reasons []pb.CLOSE_REASON
+}
+
+func (f *reasonSink) OnResult(roomId uint64, result map[uint64]uint64, reason pb.CLOSE_REASON) error {
+	f.reasons = append(f.reasons, reason)
+	return nil
+}
+
+func (f *reasonSink) OnForfeit(roomId, playerId uint64, reason pb.LEAVE_REASON) error {
+	return nil
+}
+
+func (f *reasonSink) Flush() error {
+	return nil
+}
+
+func TestShutdownPushesResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		ticks   int  //ticks after input sent
+		result  bool //players send result
+		reason  pb.CLOSE_REASON
+		reasons []pb.CLOSE_REASON
+		frames  int
+	}{
+		{"not started", 0, false, pb.CLOSE_REASON_CLOSE_Shutdown, []pb.CLOSE_REASON{pb.CLOSE_REASON_CLOSE_Shutdown}, 0},
+		{"gaming", 2, false, pb.CLOSE_REASON_CLOSE_Shutdown, []pb.CLOSE_REASON{pb.CLOSE_REASON_CLOSE_Shutdown}, 1},
+		{"gaming stopped normally", 2, false, pb.CLOSE_REASON_CLOSE_Normal, nil, 0},
+		{"over not reported", 1, true, pb.CLOSE_REASON_CLOSE_Shutdown, []pb.CLOSE_REASON{pb.CLOSE_REASON_CLOSE_Normal}, 0},
+		{"over reported", 2, true, pb.CLOSE_REASON_CLOSE_Shutdown, []pb.CLOSE_REASON{pb.CLOSE_REASON_CLOSE_Normal}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRoom(t, "")
+			results := &reasonSink{}
+			frames := &frameSink{}
+			r.SetResultSink(results)
+			r.SetReplaySink(frames)
+			p1 := connectSticky(r, 1)
+			p2 := connectSticky(r, 2)
+			readyTo(r, p1)
+			readyTo(r, p2)
+			if test.ticks > 0 {
+				r.Advance(1)
+				sendTo(r, p1, pb.ID_MSG_Input, &pb.C2S_InputMsg{Sid:1})
+			}
+			if test.result {
+				sendTo(r, p1, pb.ID_MSG_Result, &pb.C2S_ResultMsg{WinnerID:1})
+				sendTo(r, p2, pb.ID_MSG_Result, &pb.C2S_ResultMsg{WinnerID:1})
+			}
+			r.Advance(test.ticks)
+			r.StopWithReason(test.reason)
+			r.Wait()
+			if len(results.reasons) != len(test.reasons) {
+				t.Fatalf("result reasons %v, want %v", results.reasons, test.reasons)
+			}
+			for i := range test.reasons {
+				if results.reasons[i] != test.reasons[i] {
+					t.Fatalf("result reasons %v, want %v", results.reasons, test.reasons)
+				}
+			}
+			if len(frames.frameIds) != test.frames {
+				t.Fatalf("frames %v, want %d", frames.frameIds, test.frames)
+			}
+		})
+	}
+}
+
+//get frames written to conn
+func takeFrames(conn *stickyConn) []*pb.FrameData {
+	frames := make([]*pb.FrameData, 0)
+	for _, packet := range conn.TakePackets() {
+		if pb.ID(packet.GetMessageId()) != pb.ID_MSG_Frame {
+			continue
+		}
+		msg := &pb.S2C_FrameMsg{}
+		if packet.UnmarshalPB(msg) == nil {
+			frames = append(frames, msg.Frames...)
+		}
+	}
+	return frames
+}
+
+func TestAckStallResend(t *testing.T) {
+	tests := []struct {
+		name   string
+		ack    bool   //client acks frames
+		all    bool   //ack all frames sent, or stop before input frame
+		wait   time.Duration
+		resend bool
+	}{
+		{"stalled resend", true, false, 1100 * time.Millisecond, true},
+		{"stall not timed out", true, false, 500 * time.Millisecond, false},
+		{"all acked", true, true, 1100 * time.Millisecond, false},
+		{"never acked", false, false, 1100 * time.Millisecond, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRoom(t, "")
+			clk := r.clock.(*clock.FakeClock)
+			p1 := connectSticky(r, 1)
+			p2 := connectSticky(r, 2)
+			readyTo(r, p1)
+			readyTo(r, p2)
+			r.Advance(3)
+			takeFrames(p1)
+
+			//input of p2 sent to p1
+			sendTo(r, p2, pb.ID_MSG_Input, &pb.C2S_InputMsg{Sid:1})
+			r.Advance(int(define.BroadcastOffsetFrames))
+			var inputFrame, next uint32
+			for _, frame := range takeFrames(p1) {
+				if len(frame.Input) > 0 {
+					inputFrame = frame.FrameID
+				}
+				next = frame.FrameID + 1
+			}
+			if next == 0 {
+				t.Fatal("no frames sent")
+			}
+			if test.ack {
+				ack := inputFrame
+				if test.all {
+					ack = next
+				}
+				sendTo(r, p1, pb.ID_MSG_Ack, &pb.C2S_AckMsg{AckFrameCount:ack})
+			}
+			r.Sync()
+
+			//no ack progress in time, frames after ack sent again
+			clk.Advance(test.wait)
+			sendTo(r, p1, pb.ID_MSG_Heartbeat, nil)
+			r.Advance(int(define.BroadcastOffsetFrames))
+			resent := false
+			for _, frame := range takeFrames(p1) {
+				if frame.FrameID == inputFrame && len(frame.Input) > 0 {
+					resent = true
+				}
+			}
+			if resent != test.resend {
+				t.Fatalf("input frame %d resent %v, want %v", inputFrame, resent, test.resend)
+			}
+		})
+	}
+}
+
+func TestWriteFramesSplitBySize(t *testing.T) {
+	r := newTestRoom(t, "")
+	game := r.game.(*Game)
+	var packets []iface.IPacket
+	r.query(func() {
+		//3 frames, each over a third of packet
+		game.logic.SetInputPolicy(define.InputPolicyKeep, 64, nil)
+		for frameId := uint32(0); frameId < 3; frameId++ {
+			for i := 0; i < 30; i++ {
+				game.logic.PushCommandAt(frameId, &pb.InputData{
+					Id:1,
+					Sid:math.MaxInt32,
+					X:math.MaxInt32,
+					Y:math.MaxInt32,
+					RoomSeatId:1,
+				})
+			}
+		}
+		for i := 0; i < 3; i++ {
+			game.logic.Tick()
+		}
+		next := game.writeFrames(func(packet iface.IPacket) error {
+			packets = append(packets, packet)
+			return nil
+		}, 0, 3)
+		if next != 3 {
+			t.Errorf("next frame %d, want 3", next)
+		}
+	})
+	if len(packets) < 2 {
+		t.Fatalf("frames sent in %d messages, want split", len(packets))
+	}
+	inputs := 0
+	for _, packet := range packets {
+		if packet.Pack() == nil {
+			t.Fatalf("frame message of %d bytes over max", len(packet.GetData()))
+		}
+		msg := &pb.S2C_FrameMsg{}
+		if err := packet.UnmarshalPB(msg); err != nil {
+			t.Fatal(err)
+		}
+		for _, frame := range msg.Frames {
+			inputs += len(frame.Input)
+		}
+	}
+	if inputs != 90 {
+		t.Fatalf("got %d inputs, want 90", inputs)
+	}
+}
+
+//game listener with old method set only
+type baseListener struct {
+	leaves []uint64
+}
+
+func (f *baseListener) OnJoinGame(conn iface.IConn, roomId, playerId uint64) {}
+
+func (f *baseListener) OnStartGame(roomId uint64) {}
+
+func (f *baseListener) OnLeaveGame(roomId, playerId uint64) {
+	f.leaves = append(f.leaves, playerId)
+}
+
+func (f *baseListener) OneGameOver(roomId uint64) {}
+
+//game listener with all optional methods
+type fullListener struct {
+	baseListener
+	reasons  []pb.LEAVE_REASON
+	forfeits []uint64
+	lags     int
+}
+
+func (f *fullListener) OnLeaveGameWithReason(roomId, playerId uint64, reason pb.LEAVE_REASON) {
+	f.reasons = append(f.reasons, reason)
+}
+
+func (f *fullListener) OnPlayerLag(roomId, playerId uint64, lag uint32) {
+	f.lags++
+}
+
+func (f *fullListener) OnPlayerForfeit(roomId, playerId uint64, reason pb.LEAVE_REASON) {
+	f.forfeits = append(f.forfeits, playerId)
+}
+
+func TestGameListenerOptional(t *testing.T) {
+	base := &baseListener{}
+	full := &fullListener{}
+	tests := []struct {
+		name     string
+		gl       iface.IGameListener
+		leaves   []uint64
+		reasons  []pb.LEAVE_REASON
+		forfeits []uint64
+		lags     int
+	}{
+		{"old method set", base, []uint64{1, 2}, nil, nil, 0},
+		{"optional listeners", full, nil,
+			[]pb.LEAVE_REASON{pb.LEAVE_REASON_LEAVE_Close, pb.LEAVE_REASON_LEAVE_Timeout}, []uint64{2}, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clk := clock.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			game := NewGame(1, []uint64{1, 2}, 0, test.gl, clk)
+			game.JoinGame(1, network.NewMemConn(nil, clk))
+			game.JoinGame(2, network.NewMemConn(nil, clk))
+			game.LeaveGame(1)
+			p2 := game.getPlayer(2)
+			game.doAck(p2, 0)
+			game.doPlayerAction(p2, define.PlayerActionForfeit, pb.LEAVE_REASON_LEAVE_Timeout)
+
+			got := &fullListener{}
+			switch gl := test.gl.(type) {
+			case *baseListener:
+				got.leaves = gl.leaves
+			case *fullListener:
+				got = gl
+			}
+			if len(got.leaves) != len(test.leaves) || len(got.reasons) != len(test.reasons) ||
+				len(got.forfeits) != len(test.forfeits) || got.lags != test.lags {
+				t.Fatalf("got leaves %v reasons %v forfeits %v lags %d", got.leaves, got.reasons, got.forfeits, got.lags)
+			}
+			for i := range test.reasons {
+				if got.reasons[i] != test.reasons[i] {
+					t.Fatalf("reasons %v, want %v", got.reasons, test.reasons)
+				}
+			}
+		})
+	}
+}
+
+func TestPushSystemInputKeepsCaller(t *testing.T) {
+	r := newTestRoom(t, "")
+	p1 := connectSticky(r, 1)
+	p2 := connectSticky(r, 2)
+	readyTo(r, p1)
+	readyTo(r, p2)
+	r.Advance(1)
+
+	//same input pushed twice, caller copy never changed
+	input := &pb.InputData{Id:5, Sid:7, RoomSeatId:3}
+	for i := 0; i < 2; i++ {
+		frameId, err := r.PushSystemInput(0, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if input.Id != 5 || input.RoomSeatId != 3 || input.Seq != 0 {
+			t.Fatalf("caller input changed %v", input)
+		}
+		var data []*pb.InputData
+		r.query(func() {
+			data = r.game.(*Game).logic.GetFrame(frameId).GetData()
+		})
+		if len(data) != i + 1 || data[i] == input || data[i].RoomSeatId != define.SystemSeatId || data[i].Sid != 7 {
+			t.Fatalf("frame inputs %v", data)
+		}
+	}
+}
+
+//bot provider without inputs
+type idleBot struct{}
+
+func (f *idleBot) GetBotInputs(roomId uint64, roomSeatId int32, frameId uint32, history []*pb.FrameData) []*pb.InputData {
+	return nil
+}
+
+func TestBotSkipsForfeit(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   *conf.RoomConf
+		close bool //close conn of player 1, or keep it silent
+		bot   bool //seat of player 1 taken by bot
+	}{
+		{"timeout kick", &conf.RoomConf{
+			HeartbeatTimeout:2,
+			TimeoutAction:define.PlayerActionKick,
+		}, false, true},
+		{"timeout forfeit", &conf.RoomConf{
+			HeartbeatTimeout:2,
+			TimeoutAction:define.PlayerActionForfeit,
+		}, false, false},
+		{"grace continue", &conf.RoomConf{
+			ReconnectGrace:1,
+			GraceOutcome:define.GraceOutcomeContinue,
+		}, true, true},
+		{"grace forfeit", &conf.RoomConf{
+			ReconnectGrace:1,
+			GraceOutcome:define.GraceOutcomeForfeit,
+		}, true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clk := clock.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			test.cfg.RoomId = 1
+			test.cfg.Players = []uint64{1, 2, 3}
+			test.cfg.SecretKey = "k"
+			test.cfg.ManualTick = true
+			r := NewRoomWithClock(test.cfg, clk)
+			t.Cleanup(func() {
+				r.Stop()
+				r.Wait()
+			})
+			r.SetBotProvider(&idleBot{})
+			p1 := connectSticky(r, 1)
+			p2 := connectSticky(r, 2)
+			p3 := connectSticky(r, 3)
+			readyTo(r, p1)
+			readyTo(r, p2)
+			readyTo(r, p3)
+			r.Advance(1)
+			if test.close {
+				r.OnClose(p1)
+			}
+
+			//other players keep heart beat until grace or timeout passed
+			for i := 0; i < 4; i++ {
+				sendTo(r, p2, pb.ID_MSG_Heartbeat, nil)
+				sendTo(r, p3, pb.ID_MSG_Heartbeat, nil)
+				clk.Advance(time.Second)
+				r.Advance(1)
+			}
+			var bot bool
+			r.query(func() {
+				bot = r.game.(*Game).getPlayer(1).IsBot()
+			})
+			if bot != test.bot {
+				t.Fatalf("seat taken by bot %v, want %v", bot, test.bot)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package room
+
+import (
+	"bytes"
+	"github.com/andyzhou/thorn/define"
+	"github.com/andyzhou/thorn/pb"
+	"sync"
+)
+
+/*
+ * snapshot store face, implement of ISnapshotStore
+ * - clients upload state snapshot with frame id and hash
+ * - snapshot agreed when more than half voters upload same hash
+ * - keep latest agreed one and a few pending frames
+ */
+
+//candidate snapshot of one frame and hash
+type snapshotCandidate struct {
+	data   []byte
+	voters map[uint64]bool
+}
+
+//face info
+type SnapshotStore struct {
+	agreed  *pb.S2C_SnapshotMsg                       //latest agreed
+	pending map[uint32]map[uint64]*snapshotCandidate //frameId -> hash -> candidate
+	sync.RWMutex
+}
+
+//construct
+func NewSnapshotStore() *SnapshotStore {
+	//self init
+	this := &SnapshotStore{
+		pending:map[uint32]map[uint64]*snapshotCandidate{},
+	}
+	return this
+}
+
+//reset
+func (f *SnapshotStore) Reset() {
+	f.Lock()
+	defer f.Unlock()
+	f.agreed = nil
+	f.pending = map[uint32]map[uint64]*snapshotCandidate{}
+}
+
+//push snapshot of player
+//voters is count of players should vote, return true if new snapshot agreed.
+func (f *SnapshotStore) Push(
+		playerId uint64,
+		msg *pb.C2S_SnapshotMsg,
+		voters int,
+	) bool {
+	//basic check
+	if msg == nil || len(msg.Data) > define.MaxSnapshotSize {
+		return false
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	//skip older than agreed
+	if f.agreed != nil && msg.FrameID <= f.agreed.FrameID {
+		return false
+	}
+
+	//get candidate
+	hashes, ok := f.pending[msg.FrameID]
+	if !ok {
+		if len(f.pending) >= define.MaxPendingSnapshots && !f.dropOldest(msg.FrameID) {
+			return false
+		}
+		hashes = map[uint64]*snapshotCandidate{}
+		f.pending[msg.FrameID] = hashes
+	}
+	candidate, ok := hashes[msg.Hash]
+	if !ok {
+		candidate = &snapshotCandidate{
+			voters:map[uint64]bool{},
+		}
+		hashes[msg.Hash] = candidate
+	}
+
+	//check data, same hash must be same data
+	if len(msg.Data) > 0 {
+		if candidate.data == nil {
+			candidate.data = msg.Data
+		}else if !bytes.Equal(candidate.data, msg.Data) {
+			return false
+		}
+	}
+
+	//vote, one vote per player per frame
+	for _, v := range hashes {
+		if v.voters[playerId] {
+			return false
+		}
+	}
+	candidate.voters[playerId] = true
+
+	//check agreed
+	if candidate.data == nil || len(candidate.voters) * 2 <= voters {
+		return false
+	}
+	f.agreed = &pb.S2C_SnapshotMsg{
+		FrameID:msg.FrameID,
+		Hash:msg.Hash,
+		Data:candidate.data,
+	}
+
+	//drop pending not newer than agreed
+	for frameId := range f.pending {
+		if frameId <= msg.FrameID {
+			delete(f.pending, frameId)
+		}
+	}
+	return true
+}
+
+//get latest agreed snapshot, nil if none
+func (f *SnapshotStore) GetAgreed() *pb.S2C_SnapshotMsg {
+	f.RLock()
+	defer f.RUnlock()
+	return f.agreed
+}
+
+//////////////
+//private func
+//////////////
+
+//drop oldest pending frame older than frameId, lock outside
+func (f *SnapshotStore) dropOldest(frameId uint32) bool {
+	oldest := frameId
+	for v := range f.pending {
+		if v < oldest {
+			oldest = v
+		}
+	}
+	if oldest == frameId {
+		return false
+	}
+	delete(f.pending, oldest)
+	return true
+}
@@ -0,0 +1,80 @@
+package room
+
+import (
+	"github.com/andyzhou/thorn/pb"
+	"testing"
+)
+
+//one snapshot upload of player
+type testVote struct {
+	playerId uint64
+	frameId  uint32
+	hash     uint64
+	data     string
+}
+
+func TestSnapshotVote(t *testing.T) {
+	tests := []struct {
+		name    string
+		voters  int
+		votes   []testVote
+		agreed  []bool //return of each push
+		frameId uint32 //agreed frame, 0 means none
+		data    string
+	}{
+		{"majority", 3, []testVote{
+			{1, 10, 7, "a"}, {2, 10, 7, ""}, {3, 10, 8, "b"},
+		}, []bool{false, true, false}, 10, "a"},
+		{"tie of two", 2, []testVote{
+			{1, 10, 7, "a"}, {2, 10, 8, "b"},
+		}, []bool{false, false}, 0, ""},
+		{"tie of four", 4, []testVote{
+			{1, 10, 7, "a"}, {2, 10, 7, ""}, {3, 10, 8, "b"}, {4, 10, 8, ""},
+		}, []bool{false, false, false, false}, 0, ""},
+		{"half not enough", 4, []testVote{
+			{1, 10, 7, "a"}, {2, 10, 7, ""},
+		}, []bool{false, false}, 0, ""},
+		{"majority without data", 3, []testVote{
+			{1, 10, 7, ""}, {2, 10, 7, ""}, {3, 10, 7, "a"},
+		}, []bool{false, false, true}, 10, "a"},
+		{"one vote per player", 3, []testVote{
+			{1, 10, 7, "a"}, {1, 10, 7, ""}, {1, 10, 8, "b"},
+		}, []bool{false, false, false}, 0, ""},
+		{"same hash other data rejected", 3, []testVote{
+			{1, 10, 7, "a"}, {2, 10, 7, "b"},
+		}, []bool{false, false}, 0, ""},
+		{"older than agreed skipped", 1, []testVote{
+			{1, 20, 7, "a"}, {1, 10, 8, "b"},
+		}, []bool{true, false}, 20, "a"},
+		{"newer replaces agreed", 1, []testVote{
+			{1, 10, 7, "a"}, {1, 20, 8, "b"},
+		}, []bool{true, true}, 20, "b"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewSnapshotStore()
+			for i, vote := range test.votes {
+				msg := &pb.C2S_SnapshotMsg{
+					FrameID:vote.frameId,
+					Hash:vote.hash,
+				}
+				if vote.data != "" {
+					msg.Data = []byte(vote.data)
+				}
+				if got := store.Push(vote.playerId, msg, test.voters); got != test.agreed[i] {
+					t.Fatalf("push %d agreed %v, want %v", i, got, test.agreed[i])
+				}
+			}
+			agreed := store.GetAgreed()
+			if test.frameId == 0 {
+				if agreed != nil {
+					t.Fatalf("agreed %v, want none", agreed)
+				}
+				return
+			}
+			if agreed == nil || agreed.FrameID != test.frameId || string(agreed.Data) != test.data {
+				t.Fatalf("agreed %v, want frame %d data %s", agreed, test.frameId, test.data)
+			}
+		})
+	}
+}
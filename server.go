@@ -1,13 +1,17 @@
 package thorn
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/define"
 	"github.com/andyzhou/thorn/iface"
+	"github.com/andyzhou/thorn/logger"
 	"github.com/andyzhou/thorn/network"
 	"github.com/andyzhou/thorn/room"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
@@ -21,12 +25,20 @@ import (
 
 //face info
 type Server struct {
-	conf    *ServerConf
-	address string              //host:port
-	cb      iface.IConnCallBack //callback for api client
-	kcp     iface.IKcpServer
-	wg      *sync.WaitGroup
-	wgVal   int32
+	conf         *ServerConf
+	address      string              //host:port
+	cb           iface.IConnCallBack //callback for api client
+	kcp          iface.IKcpServer
+	result       iface.IResultSink   //optional
+	replay       iface.IReplaySink   //optional
+	merger       iface.IInputMerger  //optional, for merge input policy
+	bot          iface.IBotProvider  //optional, inputs for offline seats
+	fileConf     atomic.Value        //*conf.FileConf, swap on reload
+	reloadStatus ReloadStatus
+	reloadLock   sync.Mutex
+	admin        *http.Server        //admin api
+	wg           *sync.WaitGroup
+	wgVal        int32
 }
 
 //construct, step-1
@@ -49,13 +61,46 @@ func NewServer(conf *ServerConf) *Server {
 
 //stop
 func (f *Server) Stop() {
+	f.stopAdmin()
 	if f.kcp != nil {
 		f.kcp.Quit()
-		f.kcp = nil
 	}
 	f.syncGroupDone()
 }
 
+//graceful shutdown
+//stop accept and refuse new rooms, wait running games finish
+//or ctx done, notify players, wait all connects exit and flush sinks.
+func (f *Server) Shutdown(ctx context.Context) error {
+	var (
+		err error
+	)
+	if f.kcp != nil {
+		err = f.kcp.Shutdown(ctx)
+	}
+	f.stopAdmin()
+
+	//flush sinks
+	if f.result != nil {
+		if subErr := f.result.Flush(); subErr != nil {
+			log.Println("Server:Shutdown, flush result sink failed, err:", subErr)
+			if err == nil {
+				err = subErr
+			}
+		}
+	}
+	if f.replay != nil {
+		if subErr := f.replay.Flush(); subErr != nil {
+			log.Println("Server:Shutdown, flush replay sink failed, err:", subErr)
+			if err == nil {
+				err = subErr
+			}
+		}
+	}
+	f.syncGroupDone()
+	return err
+}
+
 //start, step-2
 func (f *Server) Start() {
 	if f.wgVal > 0 {
@@ -81,6 +126,28 @@ func (f *Server) SetCallback(cb iface.IConnCallBack) error {
 	return nil
 }
 
+//set game result sink, option
+func (f *Server) SetResultSink(sink iface.IResultSink) {
+	f.result = sink
+}
+
+//set frame replay sink, option
+func (f *Server) SetReplaySink(sink iface.IReplaySink) {
+	f.replay = sink
+}
+
+//set input merger for merge input policy, option
+//rooms without merger keep last input.
+func (f *Server) SetInputMerger(merger iface.IInputMerger) {
+	f.merger = merger
+}
+
+//set bot provider for seats of offline players, option
+//rooms without provider leave offline seats idle.
+func (f *Server) SetBotProvider(provider iface.IBotProvider) {
+	f.bot = provider
+}
+
 //create room, step-4
 func (f *Server) CreateRoom(cfg *conf.RoomConf) (iface.IRoom, error) {
 	//basic check
@@ -98,10 +165,44 @@ func (f *Server) CreateRoom(cfg *conf.RoomConf) (iface.IRoom, error) {
 	}
 
 	//init new room
-	roomObj = room.NewRoom(cfg)
+	f.applyRoomPolicy(cfg)
+	if err := conf.CheckFrameRetention(cfg.FrameRetention); err != nil {
+		return nil, err
+	}
+	if err := conf.CheckInputPolicy(cfg.InputPolicy); err != nil {
+		return nil, err
+	}
+	if err := conf.CheckPlayerAction(cfg.TimeoutAction); err != nil {
+		return nil, err
+	}
+	if err := conf.CheckPlayerAction(cfg.AfkAction); err != nil {
+		return nil, err
+	}
+	if err := conf.CheckGraceOutcome(cfg.GraceOutcome); err != nil {
+		return nil, err
+	}
+	if err := conf.CheckDuplicateLogin(cfg.DuplicateLogin); err != nil {
+		return nil, err
+	}
+	roomObj = room.NewRoomWithClock(cfg, f.kcp.GetClock())
+	if f.result != nil {
+		roomObj.SetResultSink(f.result)
+	}
+	if f.replay != nil {
+		roomObj.SetReplaySink(f.replay)
+	}
+	if f.merger != nil {
+		roomObj.SetInputMerger(f.merger)
+	}
+	if f.bot != nil {
+		roomObj.SetBotProvider(f.bot)
+	}
 
 	//add into manager
-	f.kcp.GetManager().AddRoom(roomObj)
+	if !f.kcp.GetManager().AddRoom(roomObj) {
+		roomObj.Stop()
+		return nil, define.ErrServerClosing
+	}
 	return roomObj, nil
 }
 
@@ -122,20 +223,38 @@ func (f *Server) SetConfig(config iface.IConfig) bool {
 	return f.kcp.SetConfig(config)
 }
 
+//set network impairment, for test
+//only work if ServerConf.Impair set, nil means pass through.
+func (f *Server) SetImpair(cfg *conf.ImpairConf) bool {
+	return f.kcp.SetImpair(cfg)
+}
+
 ///////////////
 //private func
 ///////////////
 
 //sync group done
 func (f *Server) syncGroupDone() {
-	if f.wgVal > 0 {
-		atomic.AddInt32(&f.wgVal, -1)
+	if atomic.LoadInt32(&f.wgVal) <= 0 {
+		return
 	}
+	atomic.AddInt32(&f.wgVal, -1)
 	if f.wg != nil {
 		f.wg.Done()
 	}
 }
 
+//wait udp socket passed to new process,
+//then keep serving old rooms until all finished.
+//new process relays reconnects of old rooms players back here.
+func (f *Server) waitUpgrade() {
+	<- f.kcp.Upgraded()
+	log.Println("Server:waitUpgrade, handoff done, wait old rooms finished")
+	if err := f.Shutdown(context.Background()); err != nil {
+		log.Println("Server:waitUpgrade, shutdown failed, err:", err)
+	}
+}
+
 //signal catch
 func (f *Server) signalCatch() {
 	//init signal
@@ -155,6 +274,11 @@ func (f *Server) signalCatch() {
 			case s, ok := <- sig:
 				if ok {
 					log.Printf("Get signal of %v\n", s.String())
+					if s == syscall.SIGHUP {
+						//reload conf file
+						f.Reload()
+						continue
+					}
 					f.syncGroupDone()
 					return
 				}
@@ -166,10 +290,38 @@ func (f *Server) signalCatch() {
 //inter init
 func (f *Server) interInit() {
 	//signal catch
-	f.signalCatch()
+	if !f.conf.NoSignal {
+		f.signalCatch()
+	}
 
 	//init kcp server
-	f.kcp = network.NewKcpServer(f.address, f.conf.Password, f.conf.Salt)
+	kcpConf := &conf.KcpConf{
+		Address:f.address,
+		Password:f.conf.Password,
+		Salt:f.conf.Salt,
+		Crypt:f.conf.Crypt,
+		UpgradeSock:f.conf.UpgradeSock,
+		Impair:f.conf.Impair,
+		SessionStats:f.conf.SessionStats,
+	}
+	f.kcp = network.NewKcpServerWithClock(kcpConf, f.conf.Clock)
+
+	//watch upgrade handoff
+	if f.conf.UpgradeSock != "" {
+		go f.waitUpgrade()
+	}
+
+	//load conf file
+	if f.conf.ConfFile != "" {
+		if err := f.Reload(); err != nil {
+			logger.Errorf("Server:interInit, load conf file failed, err:%v\n", err)
+		}
+	}
+
+	//start admin api
+	if f.conf.AdminAddr != "" {
+		f.startAdmin()
+	}
 
 	//set wait group value
 	atomic.StoreInt32(&f.wgVal, 0)
@@ -0,0 +1,68 @@
+package thorn
+
+import (
+	"context"
+	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/define"
+	"github.com/andyzhou/thorn/protocol"
+	"net"
+	"testing"
+	"time"
+)
+
+//new server on free local port
+func newTestServer(t *testing.T) *Server {
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP:net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := udpConn.LocalAddr().(*net.UDPAddr).Port
+	udpConn.Close()
+	return NewServer(&ServerConf{
+		Host:"127.0.0.1",
+		Port:port,
+		NoSignal:true,
+	})
+}
+
+func TestServerStopShutdownIdempotent(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []string
+	}{
+		{"shutdown then stop", []string{"shutdown", "stop"}},
+		{"stop then shutdown", []string{"stop", "shutdown"}},
+		{"stop twice", []string{"stop", "stop"}},
+		{"shutdown twice", []string{"shutdown", "shutdown", "stop"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newTestServer(t)
+			if _, err := server.CreateRoom(&conf.RoomConf{RoomId:1, Players:[]uint64{1}}); err != nil {
+				t.Fatal(err)
+			}
+			shutdowns := 0
+			for _, step := range test.steps {
+				switch step {
+				case "stop":
+					server.Stop()
+				case "shutdown":
+					ctx, cancel := context.WithCancel(context.Background())
+					cancel()
+					err := server.Shutdown(ctx)
+					if shutdowns > 0 && err != nil {
+						t.Fatalf("repeat shutdown err %v", err)
+					}
+					shutdowns++
+				}
+			}
+
+			//api works after stop, new rooms refused
+			server.GetRoom(1)
+			server.SetConfig(protocol.NewConfig(1, 1, time.Second, time.Second))
+			if _, err := server.CreateRoom(&conf.RoomConf{RoomId:2, Players:[]uint64{1}}); err != define.ErrServerClosing {
+				t.Fatalf("create room after stop err %v", err)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package thorn
 
-import "net"
+import (
+	"github.com/andyzhou/thorn/conf"
+	"github.com/andyzhou/thorn/iface"
+	"net"
+)
 
 /*
  * shared variable or struct
@@ -9,10 +13,26 @@ import "net"
 
 //server conf
 type ServerConf struct {
-	Host     string
-	Port     int
-	Password string
-	Salt     string
+	Host         string
+	Port         int
+	Password     string
+	Salt         string
+	Crypt        string           //block crypt of kcp, see define.CryptXXX, "" means aes
+	UpgradeSock  string           //unix socket path for zero downtime upgrade, linux only
+	Impair       *conf.ImpairConf //network impairment for test, nil means disabled
+	SessionStats bool             //count kcp segments, retransmits and fec per player, costs crypto of all packets
+	ConfFile     string           //json conf file, reload on SIGHUP, "" means disabled
+	AdminAddr    string           //host:port of admin api, "" means disabled
+	NoSignal     bool             //turn off built-in signal handling, for app manage signals itself
+	Clock        iface.IClock     //clock of rooms and connects, nil means real clock
+}
+
+//conf reload status
+type ReloadStatus struct {
+	Time  int64  `json:"time"` //last reload time
+	Ok    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	Count int    `json:"count"` //success reload count
 }
 
 //connect info